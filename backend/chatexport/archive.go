@@ -0,0 +1,229 @@
+package chatexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"sort"
+	"time"
+)
+
+// 数据导出的来源
+const (
+	SourceChatGPT = "chatgpt"
+	SourceClaude  = "claude"
+)
+
+var (
+	// ErrUnknownFormat 无法识别的导出文件
+	ErrUnknownFormat = errors.New("unknown_format")
+	// ErrTooLarge 压缩包中的 conversations.json 解压后超过 MaxConversationsSize
+	ErrTooLarge = errors.New("conversations_too_large")
+)
+
+// conversationsFile 导出压缩包中保存对话的文件名
+const conversationsFile = "conversations.json"
+
+// MaxConversationsSize 压缩包中 conversations.json 解压后的大小上限，防止解压炸弹耗尽内存
+const MaxConversationsSize = 1 << 30
+
+// Message 导出数据中的一条消息，Role 已转换为 user 或 assistant
+type Message struct {
+	SourceID  string
+	Parent    int // 上一条消息在 Messages 中的下标，分支起点为 -1
+	Role      string
+	Content   string
+	Model     string
+	CreatedAt time.Time
+}
+
+// Conversation 导出数据中的一个对话
+// Messages 包括所有分支（编辑后重新发送、重新生成的回复），按时间正序排列，上一条消息总在前面
+type Conversation struct {
+	SourceID  string // 对话在来源中的ID；来源中没有ID时根据内容生成
+	Title     string
+	Model     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Messages  []Message
+	Current   int // 当前分支最后一条消息的下标，没有消息时为 -1
+}
+
+// Archive 解析后的导出数据
+type Archive struct {
+	Source        string
+	Conversations []Conversation
+}
+
+// Parse 解析 ChatGPT 或 Claude 的数据导出：可以是官方导出的 zip 压缩包，也可以是其中的 conversations.json
+// source 为空时根据内容自动识别来源
+func Parse(data []byte, source string) (*Archive, error) {
+	if bytes.HasPrefix(data, []byte("PK")) {
+		var err error
+		data, err = readConversationsFile(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, ErrUnknownFormat
+	}
+	if source == "" {
+		source = detectSource(items)
+	}
+
+	archive := &Archive{Source: source}
+	var err error
+	switch source {
+	case SourceChatGPT:
+		archive.Conversations, err = parseChatGPT(items)
+	case SourceClaude:
+		archive.Conversations, err = parseClaude(items)
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range archive.Conversations {
+		conversation := &archive.Conversations[i]
+		fixTimes(conversation)
+		sortMessages(conversation)
+		if conversation.SourceID == "" {
+			conversation.SourceID = contentID(conversation)
+		}
+	}
+	return archive, nil
+}
+
+// readConversationsFile 从导出的压缩包中读取 conversations.json，文件可以在子目录中
+// 解压后超过 MaxConversationsSize 时返回 ErrTooLarge；压缩包头中记录的大小可能不可信，读取时同样限制
+func readConversationsFile(data []byte) ([]byte, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, ErrUnknownFormat
+	}
+	for _, file := range reader.File {
+		if path.Base(file.Name) != conversationsFile {
+			continue
+		}
+		if file.UncompressedSize64 > MaxConversationsSize {
+			return nil, ErrTooLarge
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		content, err := io.ReadAll(io.LimitReader(rc, MaxConversationsSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(content) > MaxConversationsSize {
+			return nil, ErrTooLarge
+		}
+		return content, nil
+	}
+	return nil, errors.New("conversations_not_found")
+}
+
+// detectSource 根据第一个对话的字段识别来源：ChatGPT 的对话有 mapping，Claude 的对话有 chat_messages
+func detectSource(items []json.RawMessage) string {
+	for _, item := range items {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(item, &fields); err != nil {
+			return ""
+		}
+		if _, ok := fields["mapping"]; ok {
+			return SourceChatGPT
+		}
+		if _, ok := fields["chat_messages"]; ok {
+			return SourceClaude
+		}
+		return ""
+	}
+	// 空的导出文件按 ChatGPT 处理，结果为没有对话
+	return SourceChatGPT
+}
+
+// fixTimes 补全缺失的时间并保证消息时间不早于上一条消息：缺失时使用上一条消息或对话的时间
+func fixTimes(conversation *Conversation) {
+	if conversation.CreatedAt.IsZero() {
+		for _, msg := range conversation.Messages {
+			if !msg.CreatedAt.IsZero() && (conversation.CreatedAt.IsZero() || msg.CreatedAt.Before(conversation.CreatedAt)) {
+				conversation.CreatedAt = msg.CreatedAt
+			}
+		}
+	}
+	if conversation.CreatedAt.IsZero() {
+		conversation.CreatedAt = time.Now()
+	}
+	latest := conversation.CreatedAt
+	for i := range conversation.Messages {
+		msg := &conversation.Messages[i]
+		previous := conversation.CreatedAt
+		if msg.Parent >= 0 {
+			previous = conversation.Messages[msg.Parent].CreatedAt
+		}
+		if msg.CreatedAt.IsZero() || msg.CreatedAt.Before(previous) {
+			msg.CreatedAt = previous
+		}
+		if msg.CreatedAt.After(latest) {
+			latest = msg.CreatedAt
+		}
+	}
+	if conversation.UpdatedAt.Before(latest) {
+		conversation.UpdatedAt = latest
+	}
+}
+
+// sortMessages 将消息按时间排序；时间相同时保持原来的顺序，因此上一条消息仍在前面
+func sortMessages(conversation *Conversation) {
+	n := len(conversation.Messages)
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return conversation.Messages[order[a]].CreatedAt.Before(conversation.Messages[order[b]].CreatedAt)
+	})
+
+	position := make([]int, n) // 原下标在排序后的位置
+	for to, from := range order {
+		position[from] = to
+	}
+	sorted := make([]Message, n)
+	for to, from := range order {
+		msg := conversation.Messages[from]
+		if msg.Parent >= 0 {
+			msg.Parent = position[msg.Parent]
+		}
+		sorted[to] = msg
+	}
+	conversation.Messages = sorted
+	if conversation.Current >= 0 {
+		conversation.Current = position[conversation.Current]
+	}
+}
+
+// contentID 来源中没有对话ID时，根据标题、创建时间和消息内容生成ID，重复导入同一份导出时保持不变
+func contentID(conversation *Conversation) string {
+	hash := sha256.New()
+	hash.Write([]byte(conversation.Title))
+	hash.Write([]byte{0})
+	hash.Write([]byte(conversation.CreatedAt.UTC().Format(time.RFC3339Nano)))
+	for _, msg := range conversation.Messages {
+		hash.Write([]byte{0})
+		hash.Write([]byte(msg.Role))
+		hash.Write([]byte{0})
+		hash.Write([]byte(msg.Content))
+	}
+	return "content:" + hex.EncodeToString(hash.Sum(nil))
+}
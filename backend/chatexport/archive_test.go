@@ -0,0 +1,105 @@
+package chatexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// chatGPTBranches 第二个问题被重新生成过一次，current_node 指向重新生成的回复
+const chatGPTBranches = `[{
+	"conversation_id": "g1",
+	"title": "量子纠缠",
+	"create_time": 1700000000,
+	"current_node": "a2b",
+	"mapping": {
+		"root": {"id": "root", "message": null, "parent": null, "children": ["sys"]},
+		"sys": {"id": "sys", "message": {"id": "sys", "author": {"role": "system"}, "content": {"content_type": "text", "parts": [""]}, "metadata": {"is_visually_hidden_from_conversation": true}}, "parent": "root", "children": ["u1"]},
+		"u1": {"id": "u1", "message": {"id": "u1", "author": {"role": "user"}, "create_time": 1700000001, "content": {"content_type": "text", "parts": ["你好"]}}, "parent": "sys", "children": ["a1"]},
+		"a1": {"id": "a1", "message": {"id": "a1", "author": {"role": "assistant"}, "create_time": 1700000002, "content": {"content_type": "text", "parts": ["你好！"]}, "metadata": {"model_slug": "gpt-4o"}}, "parent": "u1", "children": ["u2"]},
+		"u2": {"id": "u2", "message": {"id": "u2", "author": {"role": "user"}, "create_time": 1700000003, "content": {"content_type": "text", "parts": ["再详细点"]}}, "parent": "a1", "children": ["a2a", "a2b"]},
+		"a2a": {"id": "a2a", "message": {"id": "a2a", "author": {"role": "assistant"}, "create_time": 1700000005, "content": {"content_type": "text", "parts": ["旧回答"]}}, "parent": "u2", "children": []},
+		"a2b": {"id": "a2b", "message": {"id": "a2b", "author": {"role": "assistant"}, "create_time": 1700000004, "content": {"content_type": "text", "parts": ["新回答"]}}, "parent": "u2", "children": []}
+	}
+}]`
+
+func TestParseChatGPTBranches(t *testing.T) {
+	archive, err := Parse([]byte(chatGPTBranches), "")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if archive.Source != SourceChatGPT || len(archive.Conversations) != 1 {
+		t.Fatalf("unexpected archive: %+v", archive)
+	}
+	conversation := archive.Conversations[0]
+
+	// 按时间排序后 a2b 在 a2a 前面，两个回复的上一条消息都是 u2
+	want := []struct {
+		sourceID string
+		parent   string
+	}{
+		{"u1", ""}, {"a1", "u1"}, {"u2", "a1"}, {"a2b", "u2"}, {"a2a", "u2"},
+	}
+	if len(conversation.Messages) != len(want) {
+		t.Fatalf("messages = %d, want %d", len(conversation.Messages), len(want))
+	}
+	for i, w := range want {
+		msg := conversation.Messages[i]
+		parent := ""
+		if msg.Parent >= 0 {
+			if msg.Parent >= i {
+				t.Fatalf("message %s parent %d is not before it", msg.SourceID, msg.Parent)
+			}
+			parent = conversation.Messages[msg.Parent].SourceID
+		}
+		if msg.SourceID != w.sourceID || parent != w.parent {
+			t.Fatalf("message %d = %s (parent %q), want %s (parent %q)", i, msg.SourceID, parent, w.sourceID, w.parent)
+		}
+	}
+	if current := conversation.Messages[conversation.Current].SourceID; current != "a2b" {
+		t.Fatalf("current = %s, want a2b", current)
+	}
+	if model := conversation.Messages[3].Model; model != "" {
+		t.Fatalf("model without slug = %q", model)
+	}
+}
+
+func TestParseWithoutSourceID(t *testing.T) {
+	data := []byte(`[{"name": "没有ID", "created_at": "2024-01-01T00:00:00Z", "chat_messages": [
+		{"sender": "human", "text": "你好", "created_at": "2024-01-01T00:00:01Z"},
+		{"sender": "assistant", "text": "你好！", "created_at": "2024-01-01T00:00:02Z"}
+	]}]`)
+	first, err := Parse(data, "")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	second, err := Parse(data, SourceClaude)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	id := first.Conversations[0].SourceID
+	if id == "" || id != second.Conversations[0].SourceID {
+		t.Fatalf("source IDs = %q and %q, want the same non-empty ID", id, second.Conversations[0].SourceID)
+	}
+	if conversation := first.Conversations[0]; conversation.Current != 1 || conversation.Messages[1].Parent != 0 {
+		t.Fatalf("unexpected branch: %+v", conversation)
+	}
+}
+
+func TestReadConversationsFileTooLarge(t *testing.T) {
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	// 压缩包头中记录的大小超过上限，不读取内容直接拒绝
+	header := &zip.FileHeader{Name: "export/" + conversationsFile, Method: zip.Store}
+	header.UncompressedSize64 = MaxConversationsSize + 1
+	if _, err := writer.CreateRaw(header); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Parse(buf.Bytes(), ""); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("err = %v, want ErrTooLarge", err)
+	}
+}
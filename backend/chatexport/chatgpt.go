@@ -0,0 +1,221 @@
+package chatexport
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// chatGPTConversation ChatGPT 导出的 conversations.json 中的一个对话
+// 消息以树的形式保存在 mapping 中，current_node 为当前分支的最后一个节点
+type chatGPTConversation struct {
+	ID               string                 `json:"id"`
+	ConversationID   string                 `json:"conversation_id"`
+	Title            *string                `json:"title"`
+	CreateTime       *float64               `json:"create_time"`
+	UpdateTime       *float64               `json:"update_time"`
+	CurrentNode      string                 `json:"current_node"`
+	DefaultModelSlug string                 `json:"default_model_slug"`
+	Mapping          map[string]chatGPTNode `json:"mapping"`
+}
+
+type chatGPTNode struct {
+	ID       string          `json:"id"`
+	Message  *chatGPTMessage `json:"message"`
+	Parent   *string         `json:"parent"`
+	Children []string        `json:"children"`
+}
+
+type chatGPTMessage struct {
+	ID     string `json:"id"`
+	Author struct {
+		Role string `json:"role"`
+	} `json:"author"`
+	CreateTime *float64 `json:"create_time"`
+	Content    struct {
+		ContentType string            `json:"content_type"`
+		Parts       []json.RawMessage `json:"parts"`
+		Text        string            `json:"text"`
+	} `json:"content"`
+	Metadata struct {
+		ModelSlug        string `json:"model_slug"`
+		IsVisuallyHidden bool   `json:"is_visually_hidden_from_conversation"`
+	} `json:"metadata"`
+}
+
+// parseChatGPT 解析 ChatGPT 的对话：从根节点遍历 mapping 中的整棵树，编辑后重新发送和重新生成的回复作为分支导入
+// 只保留用户和助手的文本消息，系统消息、工具调用结果和隐藏的消息不导入，它们的子节点接到最近的上一条消息后面
+func parseChatGPT(items []json.RawMessage) ([]Conversation, error) {
+	conversations := make([]Conversation, 0, len(items))
+	for _, item := range items {
+		var raw chatGPTConversation
+		if err := json.Unmarshal(item, &raw); err != nil {
+			return nil, ErrUnknownFormat
+		}
+
+		conversation := Conversation{
+			SourceID:  raw.ConversationID,
+			Model:     raw.DefaultModelSlug,
+			CreatedAt: unixTime(raw.CreateTime),
+			UpdatedAt: unixTime(raw.UpdateTime),
+			Current:   -1,
+		}
+		if conversation.SourceID == "" {
+			conversation.SourceID = raw.ID
+		}
+		if raw.Title != nil {
+			conversation.Title = strings.TrimSpace(*raw.Title)
+		}
+
+		// 深度优先遍历，父节点总在子节点之前；index 记录已导入节点在 Messages 中的下标
+		type frame struct {
+			nodeID string
+			parent int
+		}
+		index := make(map[string]int)
+		visited := make(map[string]bool) // 防止异常数据中的环
+		roots := raw.roots()
+		stack := make([]frame, 0, len(raw.Mapping))
+		for i := len(roots) - 1; i >= 0; i-- {
+			stack = append(stack, frame{nodeID: roots[i], parent: -1})
+		}
+		for len(stack) > 0 {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if visited[top.nodeID] {
+				continue
+			}
+			visited[top.nodeID] = true
+			node, ok := raw.Mapping[top.nodeID]
+			if !ok {
+				continue
+			}
+
+			parent := top.parent
+			if message, ok := convertChatGPTMessage(node.Message, conversation.Model); ok {
+				message.Parent = parent
+				parent = len(conversation.Messages)
+				index[top.nodeID] = parent
+				conversation.Messages = append(conversation.Messages, message)
+			}
+			for i := len(node.Children) - 1; i >= 0; i-- {
+				stack = append(stack, frame{nodeID: node.Children[i], parent: parent})
+			}
+		}
+
+		// 当前分支：从 current_node 沿父节点向上找到第一条导入的消息
+		seen := make(map[string]bool)
+		for nodeID := raw.currentNode(); nodeID != "" && !seen[nodeID]; {
+			seen[nodeID] = true
+			if i, ok := index[nodeID]; ok {
+				conversation.Current = i
+				break
+			}
+			node, ok := raw.Mapping[nodeID]
+			if !ok || node.Parent == nil {
+				break
+			}
+			nodeID = *node.Parent
+		}
+		if conversation.Current < 0 && len(conversation.Messages) > 0 {
+			conversation.Current = len(conversation.Messages) - 1
+		}
+		conversations = append(conversations, conversation)
+	}
+	return conversations, nil
+}
+
+// convertChatGPTMessage 转换用户和助手的文本消息，其他消息返回 false
+func convertChatGPTMessage(msg *chatGPTMessage, defaultModel string) (Message, bool) {
+	if msg == nil {
+		return Message{}, false
+	}
+	role := msg.Author.Role
+	if (role != "user" && role != "assistant") || msg.Metadata.IsVisuallyHidden {
+		return Message{}, false
+	}
+	content := strings.TrimSpace(msg.text())
+	if content == "" {
+		return Message{}, false
+	}
+	message := Message{
+		SourceID:  msg.ID,
+		Role:      role,
+		Content:   content,
+		CreatedAt: unixTime(msg.CreateTime),
+	}
+	if role == "assistant" {
+		message.Model = msg.Metadata.ModelSlug
+		if message.Model == "" {
+			message.Model = defaultModel
+		}
+	}
+	return message, true
+}
+
+// roots 树的根节点：没有父节点，或父节点不在 mapping 中；按ID排序保证每次导入的顺序相同
+func (c *chatGPTConversation) roots() []string {
+	var roots []string
+	for id, node := range c.Mapping {
+		if node.Parent == nil || *node.Parent == "" {
+			roots = append(roots, id)
+			continue
+		}
+		if _, ok := c.Mapping[*node.Parent]; !ok {
+			roots = append(roots, id)
+		}
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+// currentNode 当前分支的最后一个节点；旧的导出没有 current_node 时从根节点沿最后一个子节点（最近的一次编辑或重新生成）走到叶子
+func (c *chatGPTConversation) currentNode() string {
+	if c.CurrentNode != "" {
+		return c.CurrentNode
+	}
+	var root string
+	if roots := c.roots(); len(roots) > 0 {
+		root = roots[0]
+	}
+	visited := make(map[string]bool)
+	for root != "" && !visited[root] {
+		visited[root] = true
+		node := c.Mapping[root]
+		if len(node.Children) == 0 {
+			return root
+		}
+		root = node.Children[len(node.Children)-1]
+	}
+	return root
+}
+
+// text 消息的文本内容：文本消息拼接 parts 中的字符串，图片等非文本部分忽略；代码消息使用 text
+func (m *chatGPTMessage) text() string {
+	switch m.Content.ContentType {
+	case "text", "multimodal_text":
+		var parts []string
+		for _, raw := range m.Content.Parts {
+			var part string
+			if err := json.Unmarshal(raw, &part); err == nil && part != "" {
+				parts = append(parts, part)
+			}
+		}
+		return strings.Join(parts, "\n\n")
+	case "code":
+		return m.Content.Text
+	default:
+		return ""
+	}
+}
+
+// unixTime 将 ChatGPT 导出中以秒为单位的浮点时间戳转换为时间，缺失时返回零值
+func unixTime(seconds *float64) time.Time {
+	if seconds == nil || *seconds <= 0 {
+		return time.Time{}
+	}
+	whole, frac := math.Modf(*seconds)
+	return time.Unix(int64(whole), int64(frac*1e6)*1e3)
+}
@@ -0,0 +1,107 @@
+package chatexport
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// defaultClaudeModel Claude 的导出中没有记录模型时使用的模型名称
+const defaultClaudeModel = "claude"
+
+// claudeConversation Claude 导出的 conversations.json 中的一个对话，消息按时间顺序排列
+type claudeConversation struct {
+	UUID         string          `json:"uuid"`
+	Name         string          `json:"name"`
+	Model        string          `json:"model"`
+	CreatedAt    string          `json:"created_at"`
+	UpdatedAt    string          `json:"updated_at"`
+	ChatMessages []claudeMessage `json:"chat_messages"`
+}
+
+type claudeMessage struct {
+	UUID      string `json:"uuid"`
+	Text      string `json:"text"`
+	Sender    string `json:"sender"`
+	CreatedAt string `json:"created_at"`
+	Content   []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+// parseClaude 解析 Claude 的对话，sender 为 human 的消息作为用户消息；Claude 的导出只有一条分支
+func parseClaude(items []json.RawMessage) ([]Conversation, error) {
+	conversations := make([]Conversation, 0, len(items))
+	for _, item := range items {
+		var raw claudeConversation
+		if err := json.Unmarshal(item, &raw); err != nil {
+			return nil, ErrUnknownFormat
+		}
+
+		conversation := Conversation{
+			SourceID:  raw.UUID,
+			Title:     strings.TrimSpace(raw.Name),
+			Model:     raw.Model,
+			CreatedAt: isoTime(raw.CreatedAt),
+			UpdatedAt: isoTime(raw.UpdatedAt),
+			Current:   -1,
+		}
+		if conversation.Model == "" {
+			conversation.Model = defaultClaudeModel
+		}
+
+		for _, msg := range raw.ChatMessages {
+			role := ""
+			switch msg.Sender {
+			case "human":
+				role = "user"
+			case "assistant":
+				role = "assistant"
+			default:
+				continue
+			}
+			content := strings.TrimSpace(msg.text())
+			if content == "" {
+				continue
+			}
+			message := Message{
+				SourceID:  msg.UUID,
+				Parent:    len(conversation.Messages) - 1,
+				Role:      role,
+				Content:   content,
+				CreatedAt: isoTime(msg.CreatedAt),
+			}
+			if role == "assistant" {
+				message.Model = conversation.Model
+			}
+			conversation.Messages = append(conversation.Messages, message)
+		}
+		conversation.Current = len(conversation.Messages) - 1
+		conversations = append(conversations, conversation)
+	}
+	return conversations, nil
+}
+
+// text 消息的文本内容；新版导出的 text 可能为空，内容在 content 的文本块中
+func (m *claudeMessage) text() string {
+	if strings.TrimSpace(m.Text) != "" {
+		return m.Text
+	}
+	var parts []string
+	for _, block := range m.Content {
+		if block.Type == "text" && block.Text != "" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// isoTime 解析 Claude 导出中的 ISO 8601 时间，无法解析时返回零值
+func isoTime(value string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
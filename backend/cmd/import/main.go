@@ -0,0 +1,94 @@
+// import 从命令行导入 ChatGPT 或 Claude 的数据导出，与 POST /api/import 使用相同的导入逻辑
+//
+//	go run ./cmd/import [-db grandma.db] [-source chatgpt|claude] [-dry-run] [-json] export.zip ...
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"grandma/backend/config"
+	"grandma/backend/database"
+	"grandma/backend/models"
+	"grandma/backend/modules/importer"
+	"grandma/backend/repository"
+	"log"
+	"os"
+)
+
+func main() {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	dbPath := flag.String("db", cfg.DatabasePath, "数据库文件，默认使用 DATABASE_PATH")
+	source := flag.String("source", "", "导出的来源：chatgpt 或 claude，为空时自动识别")
+	dryRun := flag.Bool("dry-run", false, "只解析和检查重复，不保存")
+	asJSON := flag.Bool("json", false, "以JSON输出导入结果")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] export.zip|conversations.json ...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := database.InitDB(*dbPath); err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	// 检测全文索引，导入的对话和文档同步写入索引
+	if err := repository.NewSearchRepository(database.DB).InitIndex(); err != nil {
+		log.Fatalf("Failed to initialize search index: %v", err)
+	}
+	service := importer.NewImporterService(repository.NewConversationRepository(database.DB))
+
+	failed := false
+	for _, path := range flag.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("Failed to read %s: %v", path, err)
+			failed = true
+			continue
+		}
+		summary, err := service.Import(data, *source, *dryRun)
+		if err != nil {
+			log.Printf("Failed to import %s: %v", path, err)
+			failed = true
+			continue
+		}
+		if summary.Failed > 0 {
+			failed = true
+		}
+		if *asJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			encoder.Encode(summary)
+			continue
+		}
+		printSummary(path, summary)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// printSummary 输出导入结果：每个对话一行，最后是汇总
+func printSummary(path string, summary *models.ImportSummary) {
+	fmt.Printf("%s (%s)\n", path, summary.Source)
+	for _, result := range summary.Results {
+		line := fmt.Sprintf("  %-9s %4d  %s", result.Status, result.Messages, result.Title)
+		if result.Error != "" {
+			line += "  error: " + result.Error
+		}
+		fmt.Println(line)
+	}
+	mode := ""
+	if summary.DryRun {
+		mode = " (dry run, nothing saved)"
+	}
+	fmt.Printf("%d conversations: %d imported, %d duplicates, %d empty, %d failed; %d messages%s\n",
+		summary.Total, summary.Imported, summary.Duplicates, summary.Empty, summary.Failed, summary.Messages, mode)
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
@@ -14,6 +15,13 @@ type Config struct {
 	AnthropicAPIKey    string
 	AnthropicBaseURL   string
 	DatabasePath       string
+	ContextTokenBudget int    // 历史上下文的token预算，为0时使用各模型的默认预算
+	SafetyEnabled      bool   // 是否启用内容安全过滤
+	SafetyAgeBand      string // 对话未设置年龄段时使用的默认年龄段
+	SafetyRulesFile    string // 自定义过滤规则的JSON文件，为空时使用内置规则
+	JobWorkers         int    // 同时执行的后台任务数
+	JobMaxAttempts     int    // 后台任务失败后默认最多执行的次数
+	TrashRetentionDays int    // 回收站中的内容保留的天数，超过后自动永久删除；为0时不自动删除
 }
 
 func LoadConfig() (*Config, error) {
@@ -28,6 +36,13 @@ func LoadConfig() (*Config, error) {
 		AnthropicAPIKey:    getEnv("ANTHROPIC_API_KEY", ""),
 		AnthropicBaseURL:   getEnv("ANTHROPIC_BASE_URL", "https://api.anthropic.com"),
 		DatabasePath:       getEnv("DATABASE_PATH", "grandma.db"),
+		ContextTokenBudget: getEnvInt("CONTEXT_TOKEN_BUDGET", 0),
+		SafetyEnabled:      getEnv("SAFETY_ENABLED", "true") == "true",
+		SafetyAgeBand:      getEnv("SAFETY_AGE_BAND", "child"),
+		SafetyRulesFile:    getEnv("SAFETY_RULES_FILE", ""),
+		JobWorkers:         getEnvInt("JOB_WORKERS", 2),
+		JobMaxAttempts:     getEnvInt("JOB_MAX_ATTEMPTS", 3),
+		TrashRetentionDays: getEnvInt("TRASH_RETENTION_DAYS", 30),
 	}, nil
 }
 
@@ -37,3 +52,12 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"grandma/backend/models"
 	"log"
+	"strings"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -23,11 +24,130 @@ func InitDB(dbPath string) error {
 		&models.Conversation{},
 		&models.Document{},
 		&models.Story{},
+		&models.ConversationSummary{},
+		&models.StoryProject{},
+		&models.StoryChapter{},
+		&models.PromptTemplate{},
+		&models.SafetyIntervention{},
+		&models.Job{},
+		&models.SearchEntry{},
+		&models.Folder{},
+		&models.ConversationTag{},
+		&models.ShareLink{},
+		&models.Revision{},
 	)
 	if err != nil {
 		return err
 	}
 
+	// 为分支功能上线前的文档补全父文档关系
+	err = migrateDocumentParents(DB)
+	if err != nil {
+		return err
+	}
+
+	// 将旧版逗号分隔的 document_ids 列迁移为文档序号
+	err = migrateDocumentSequence(DB)
+	if err != nil {
+		return err
+	}
+
 	log.Println("Database initialized successfully")
 	return nil
 }
+
+// migrateDocumentParents 将尚未建立父子关系的旧对话按创建时间串成一条分支，并设置活动分支
+// 旧数据的 parent_id 为 NULL，新文档总会写入 parent_id（分支起点为空字符串），因此只会迁移一次
+func migrateDocumentParents(db *gorm.DB) error {
+	var conversationIDs []string
+	err := db.Model(&models.Document{}).
+		Where("parent_id IS NULL").
+		Distinct().
+		Pluck("conversation_id", &conversationIDs).Error
+	if err != nil {
+		return err
+	}
+
+	for _, conversationID := range conversationIDs {
+		var documents []models.Document
+		err := db.Where("conversation_id = ?", conversationID).
+			Where("alternative_of IS NULL OR alternative_of = ''").
+			Order("created_at ASC").
+			Find(&documents).Error
+		if err != nil {
+			return err
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			parentID := ""
+			for _, doc := range documents {
+				// 候选版本与原始文档共享同一个父文档
+				err := tx.Model(&models.Document{}).
+					Where("id = ? OR alternative_of = ?", doc.ID, doc.ID).
+					Update("parent_id", parentID).Error
+				if err != nil {
+					return err
+				}
+				parentID = doc.ID
+			}
+			return tx.Model(&models.Conversation{}).
+				Where("id = ?", conversationID).
+				Update("head_document_id", parentID).Error
+		})
+		if err != nil {
+			return err
+		}
+		log.Printf("Migrated document parents for conversation %s (%d documents)", conversationID, len(documents))
+	}
+	return nil
+}
+
+// migrateDocumentSequence 按旧版 conversations.document_ids 列的顺序为文档分配序号，完成后删除该列
+// 列表中已不存在或重复的文档ID会被跳过；删除列后不会再次迁移
+func migrateDocumentSequence(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&models.Conversation{}, "document_ids") {
+		return nil
+	}
+
+	var rows []struct {
+		ID          string
+		DocumentIDs string
+	}
+	err := db.Table("conversations").
+		Select("id, document_ids").
+		Where("document_ids IS NOT NULL AND document_ids <> ''").
+		Find(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		count := 0
+		err := db.Transaction(func(tx *gorm.DB) error {
+			seen := make(map[string]bool)
+			for _, documentID := range strings.Split(row.DocumentIDs, ",") {
+				documentID = strings.TrimSpace(documentID)
+				if documentID == "" || seen[documentID] {
+					continue
+				}
+				seen[documentID] = true
+				result := tx.Model(&models.Document{}).
+					Where("id = ? AND conversation_id = ?", documentID, row.ID).
+					Update("seq", count+1)
+				if result.Error != nil {
+					return result.Error
+				}
+				if result.RowsAffected > 0 {
+					count++
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		log.Printf("Migrated document sequence for conversation %s (%d documents)", row.ID, count)
+	}
+
+	return db.Migrator().DropColumn(&models.Conversation{}, "document_ids")
+}
@@ -0,0 +1,120 @@
+package diff
+
+// 编辑操作
+const (
+	OpEqual  = "equal"  // 两边相同
+	OpInsert = "insert" // 只在新版本中
+	OpDelete = "delete" // 只在旧版本中
+)
+
+// edit 一个单位的编辑操作
+type edit struct {
+	op   string
+	text string
+}
+
+// editScript 计算把 a 变为 b 的最短编辑序列（Myers 差分算法）
+// 先去掉相同的开头和结尾；剩余部分的编辑距离超过 maxEdits 时不再搜索，整体作为删除加插入
+func editScript(a, b []string, maxEdits int) []edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]edit, 0, len(a)+len(b))
+	for _, text := range a[:prefix] {
+		edits = append(edits, edit{OpEqual, text})
+	}
+	middleA, middleB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if middle := myers(middleA, middleB, maxEdits); middle != nil {
+		edits = append(edits, middle...)
+	} else {
+		for _, text := range middleA {
+			edits = append(edits, edit{OpDelete, text})
+		}
+		for _, text := range middleB {
+			edits = append(edits, edit{OpInsert, text})
+		}
+	}
+	for _, text := range a[len(a)-suffix:] {
+		edits = append(edits, edit{OpEqual, text})
+	}
+	return edits
+}
+
+// myers 在编辑距离不超过 maxEdits 时返回最短编辑序列，否则返回 nil
+// v[k] 为第 d 步时对角线 k 上走得最远的 x，trace 保存每一步开始时 v 的 [-d, d] 部分用于回溯
+func myers(a, b []string, maxEdits int) []edit {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return []edit{}
+	}
+	limit := min(n+m, maxEdits)
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	var trace [][]int
+
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // 从对角线 k+1 向下走：插入 b 中的一个单位
+			} else {
+				x = v[offset+k-1] + 1 // 从对角线 k-1 向右走：删除 a 中的一个单位
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack 从终点沿 trace 倒推出编辑序列
+func backtrack(a, b []string, trace [][]int) []edit {
+	x, y := len(a), len(b)
+	reversed := make([]edit, 0, x+y)
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d] // v[k+d] 为第 d 步开始时对角线 k 上的 x
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && v[k-1+d] < v[k+1+d]) {
+			prevK = k + 1
+		}
+		prevX := 0
+		if d > 0 {
+			prevX = v[prevK+d]
+		}
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			reversed = append(reversed, edit{OpEqual, a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, edit{OpInsert, b[y-1]})
+			} else {
+				reversed = append(reversed, edit{OpDelete, a[x-1]})
+			}
+			x, y = prevX, prevY
+		}
+	}
+
+	edits := make([]edit, len(reversed))
+	for i, e := range reversed {
+		edits[len(reversed)-1-i] = e
+	}
+	return edits
+}
@@ -0,0 +1,183 @@
+package diff
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	maxLineEdits  = 1000 // 逐行比较时搜索的最大编辑距离
+	maxTokenEdits = 1000 // 行内逐字比较时搜索的最大编辑距离
+	minSimilarity = 0.4  // 删除和插入的两行相同部分的比例达到该值时才逐字比较
+)
+
+// Segment 行内逐字比较的一段文字
+type Segment struct {
+	Op   string `json:"op"` // equal、insert 或 delete
+	Text string `json:"text"`
+}
+
+// Line 比较结果中的一行
+type Line struct {
+	Op       string    `json:"op"`                 // equal、insert 或 delete
+	OldLine  int       `json:"old_line,omitempty"` // 在旧版本中的行号，从1开始，插入的行为空
+	NewLine  int       `json:"new_line,omitempty"` // 在新版本中的行号，从1开始，删除的行为空
+	Text     string    `json:"text"`
+	Segments []Segment `json:"segments,omitempty"` // 修改过的行的逐字比较结果：删除的行只包含相同和删除的部分，插入的行只包含相同和插入的部分
+}
+
+// Stats 修改的统计，字数按字符计算，不包括换行
+type Stats struct {
+	LinesAdded   int `json:"lines_added"`
+	LinesDeleted int `json:"lines_deleted"`
+	CharsAdded   int `json:"chars_added"`
+	CharsDeleted int `json:"chars_deleted"`
+}
+
+// Result 两段文字的比较结果
+type Result struct {
+	Lines []Line `json:"lines"`
+	Stats Stats  `json:"stats"`
+}
+
+// Compare 逐行比较两段文字；相邻的删除行和插入行内容相近时再逐字比较
+// 逐字比较时汉字和标点各自为一个单位，连续的字母和数字作为一个单词
+func Compare(oldText, newText string) *Result {
+	oldLines, newLines := splitLines(oldText), splitLines(newText)
+	edits := editScript(oldLines, newLines, maxLineEdits)
+
+	result := &Result{Lines: make([]Line, 0, len(edits))}
+	oldNo, newNo := 0, 0
+	for i := 0; i < len(edits); {
+		if edits[i].op == OpEqual {
+			oldNo++
+			newNo++
+			result.Lines = append(result.Lines, Line{Op: OpEqual, OldLine: oldNo, NewLine: newNo, Text: edits[i].text})
+			i++
+			continue
+		}
+
+		// 一段连续的修改：先列出删除的行，再列出插入的行
+		var deleted, inserted []Line
+		for ; i < len(edits) && edits[i].op != OpEqual; i++ {
+			if edits[i].op == OpDelete {
+				oldNo++
+				deleted = append(deleted, Line{Op: OpDelete, OldLine: oldNo, Text: edits[i].text})
+			} else {
+				newNo++
+				inserted = append(inserted, Line{Op: OpInsert, NewLine: newNo, Text: edits[i].text})
+			}
+		}
+		result.Stats.LinesDeleted += len(deleted)
+		result.Stats.LinesAdded += len(inserted)
+
+		// 按顺序配对删除和插入的行，内容相近的逐字比较
+		for j := range deleted {
+			if j < len(inserted) && compareLine(&deleted[j], &inserted[j], &result.Stats) {
+				continue
+			}
+			result.Stats.CharsDeleted += utf8.RuneCountInString(deleted[j].Text)
+		}
+		for j := len(deleted); j < len(inserted); j++ {
+			result.Stats.CharsAdded += utf8.RuneCountInString(inserted[j].Text)
+		}
+		result.Lines = append(result.Lines, deleted...)
+		result.Lines = append(result.Lines, inserted...)
+	}
+	return result
+}
+
+// compareLine 逐字比较修改前后的一行，内容相近时填写两行的 Segments 和字数统计并返回 true
+// 不相近时只统计插入行的字数，删除行由调用方统计
+func compareLine(deleted, inserted *Line, stats *Stats) bool {
+	edits := editScript(tokenize(deleted.Text), tokenize(inserted.Text), maxTokenEdits)
+	equal, added, removed := 0, 0, 0
+	for _, e := range edits {
+		count := utf8.RuneCountInString(e.text)
+		switch e.op {
+		case OpEqual:
+			equal += count
+		case OpInsert:
+			added += count
+		case OpDelete:
+			removed += count
+		}
+	}
+	total := equal*2 + added + removed
+	if total == 0 || float64(equal*2)/float64(total) < minSimilarity {
+		stats.CharsAdded += utf8.RuneCountInString(inserted.Text)
+		return false
+	}
+
+	for _, e := range edits {
+		if e.op != OpInsert {
+			deleted.Segments = appendSegment(deleted.Segments, e)
+		}
+		if e.op != OpDelete {
+			inserted.Segments = appendSegment(inserted.Segments, e)
+		}
+	}
+	stats.CharsAdded += added
+	stats.CharsDeleted += removed
+	return true
+}
+
+// appendSegment 追加一个单位的编辑，与上一段操作相同时合并
+func appendSegment(segments []Segment, e edit) []Segment {
+	if n := len(segments); n > 0 && segments[n-1].Op == e.op {
+		segments[n-1].Text += e.text
+		return segments
+	}
+	return append(segments, Segment{Op: e.op, Text: e.text})
+}
+
+// splitLines 按换行切分，空文字没有行
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
+
+// tokenize 将一行文字切分为逐字比较的单位：汉字、假名、谚文和标点各自为一个单位，
+// 连续的字母和数字组成一个单词，连续的空白为一个单位
+func tokenize(line string) []string {
+	var tokens []string
+	start := -1 // 当前单词或空白的开始位置
+	startKind := 0
+	for i, r := range line {
+		kind := runeKind(r)
+		if start >= 0 && (kind != startKind || kind == kindSingle) {
+			tokens = append(tokens, line[start:i])
+			start = -1
+		}
+		if start < 0 {
+			start, startKind = i, kind
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, line[start:])
+	}
+	return tokens
+}
+
+// 字符的类别
+const (
+	kindSingle = iota + 1 // 单独成为一个单位
+	kindWord              // 字母和数字
+	kindSpace             // 空白
+)
+
+func runeKind(r rune) int {
+	switch {
+	case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+		return kindSingle
+	case unicode.IsLetter(r), unicode.IsDigit(r), unicode.Is(unicode.Mn, r):
+		return kindWord
+	case unicode.IsSpace(r):
+		return kindSpace
+	default:
+		return kindSingle
+	}
+}
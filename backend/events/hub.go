@@ -0,0 +1,83 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// 事件类型
+const (
+	TypeMessageCreated      = "message_created"      // 用户消息已保存
+	TypeMessageCompleted    = "message_completed"    // 助手回复已生成完毕
+	TypeTitleUpdated        = "title_updated"        // 对话标题已生成或修改
+	TypeConversationCreated = "conversation_created" // 新对话已创建
+	TypeConversationDeleted = "conversation_deleted" // 对话已删除
+	TypeJobUpdated          = "job_updated"          // 后台任务状态或进度已更新
+)
+
+// subscriberBuffer 每个订阅者的事件缓冲区大小，缓冲区满时丢弃新事件，避免慢订阅者阻塞发布方
+const subscriberBuffer = 64
+
+// Event 服务端事件
+type Event struct {
+	Type           string      `json:"type"`
+	ConversationID string      `json:"conversation_id"`
+	DocumentID     string      `json:"document_id,omitempty"`
+	Data           interface{} `json:"data,omitempty"`
+	CreatedAt      time.Time   `json:"created_at"`
+}
+
+// Hub 进程内的事件分发中心，将对话相关事件广播给所有订阅者（如WebSocket连接）
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[int]chan Event
+	nextID      int
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[int]chan Event),
+	}
+}
+
+// Subscribe 订阅事件，返回订阅ID和事件通道
+func (h *Hub) Subscribe() (int, <-chan Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	ch := make(chan Event, subscriberBuffer)
+	h.subscribers[h.nextID] = ch
+	return h.nextID, ch
+}
+
+// Unsubscribe 取消订阅并关闭事件通道
+func (h *Hub) Unsubscribe(id int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if ch, ok := h.subscribers[id]; ok {
+		delete(h.subscribers, id)
+		close(ch)
+	}
+}
+
+// Publish 发布事件，不会阻塞
+func (h *Hub) Publish(event Event) {
+	if h == nil {
+		return
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, ch := range h.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package export
+
+import (
+	"html/template"
+	"io"
+	"time"
+)
+
+// pageFuncs 页面模板中使用的函数
+var pageFuncs = template.FuncMap{
+	"formatTime": func(t time.Time) string { return t.Format(timeLayout) },
+	"isoTime":    func(t time.Time) string { return t.Format(time.RFC3339) },
+}
+
+// styleTemplate 对话记录和故事页面共用的内联样式
+var styleTemplate = template.Must(template.New("style").Parse(`<style>
+body { max-width: 760px; margin: 2em auto; padding: 0 1em; font-family: -apple-system, "PingFang SC", "Microsoft YaHei", sans-serif; line-height: 1.7; color: #222; }
+header { border-bottom: 1px solid #ddd; margin-bottom: 1.5em; }
+header dl { display: grid; grid-template-columns: max-content 1fr; gap: 0.2em 1em; color: #666; font-size: 0.9em; }
+header dd { margin: 0; }
+article { margin: 1.2em 0; padding: 0.8em 1em; border-radius: 8px; }
+article.user { background: #f1f5fb; }
+article.assistant { background: #faf7f0; }
+article h2 { margin: 0 0 0.4em; font-size: 0.95em; color: #555; }
+article time { font-weight: normal; color: #888; margin-left: 0.5em; }
+.content { white-space: pre-wrap; word-wrap: break-word; }
+.story { font-size: 1.15em; line-height: 1.9; }
+footer { color: #999; font-size: 0.8em; margin-top: 2em; }
+</style>`))
+
+// htmlTemplate 独立的HTML页面，不依赖外部样式和脚本；消息内容保留换行，不解释为HTML
+var htmlTemplate = template.Must(template.Must(styleTemplate.Clone()).New("transcript").Funcs(pageFuncs).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+{{template "style"}}
+</head>
+<body>
+<header>
+<h1>{{.Title}}</h1>
+<dl>
+<dt>创建时间</dt><dd><time datetime="{{isoTime .CreatedAt}}">{{formatTime .CreatedAt}}</time></dd>
+<dt>更新时间</dt><dd><time datetime="{{isoTime .UpdatedAt}}">{{formatTime .UpdatedAt}}</time></dd>
+{{- if .Tags}}
+<dt>标签</dt><dd>{{range $i, $tag := .Tags}}{{if $i}}、{{end}}{{$tag}}{{end}}</dd>
+{{- end}}
+</dl>
+</header>
+<main>
+{{- range .Messages}}
+<article class="{{.Role}}" id="{{.ID}}">
+<h2>{{.Speaker}}<time datetime="{{isoTime .CreatedAt}}">{{formatTime .CreatedAt}}</time></h2>
+<div class="content">{{.Content}}</div>
+</article>
+{{- end}}
+</main>
+{{- if not .ExportedAt.IsZero}}
+<footer>导出时间：{{formatTime .ExportedAt}}</footer>
+{{- end}}
+</body>
+</html>
+`))
+
+// htmlMessage 页面中的一条消息
+type htmlMessage struct {
+	Message
+	Speaker string
+}
+
+// htmlPage 页面的数据
+type htmlPage struct {
+	*Transcript
+	Title    string
+	Messages []htmlMessage
+}
+
+// HTML 输出对话记录的独立HTML页面，所有文本都经过HTML转义
+func HTML(w io.Writer, t *Transcript) error {
+	page := htmlPage{Transcript: t, Title: title(t)}
+	for _, msg := range t.Messages {
+		page.Messages = append(page.Messages, htmlMessage{Message: msg, Speaker: speaker(msg)})
+	}
+	return htmlTemplate.Execute(w, page)
+}
@@ -0,0 +1,53 @@
+package export
+
+import (
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// defaultStoryTitle 故事没有标题时使用的标题
+const defaultStoryTitle = "未命名故事"
+
+// Story 分享的故事
+type Story struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// storyTemplate 故事的独立HTML页面，正文字号较大，保留换行
+var storyTemplate = template.Must(template.Must(styleTemplate.Clone()).New("story").Funcs(pageFuncs).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+{{template "style"}}
+</head>
+<body>
+<header>
+<h1>{{.Title}}</h1>
+<dl>
+<dt>创作时间</dt><dd><time datetime="{{isoTime .CreatedAt}}">{{formatTime .CreatedAt}}</time></dd>
+</dl>
+</header>
+<main>
+<div class="content story">{{.Content}}</div>
+</main>
+</body>
+</html>
+`))
+
+// StoryHTML 输出故事的独立HTML页面，所有文本都经过HTML转义
+func StoryHTML(w io.Writer, s *Story) error {
+	page := *s
+	page.Title = strings.TrimSpace(page.Title)
+	if page.Title == "" {
+		page.Title = defaultStoryTitle
+	}
+	return storyTemplate.Execute(w, page)
+}
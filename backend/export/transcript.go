@@ -0,0 +1,169 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 导出格式
+const (
+	FormatMarkdown = "md"
+	FormatHTML     = "html"
+	FormatJSON     = "json"
+)
+
+// Formats 支持的导出格式
+var Formats = []string{FormatMarkdown, FormatHTML, FormatJSON}
+
+// ValidFormat 是否为支持的导出格式
+func ValidFormat(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// ContentType 导出格式对应的 Content-Type
+func ContentType(format string) string {
+	switch format {
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	case FormatJSON:
+		return "application/json; charset=utf-8"
+	default:
+		return "text/markdown; charset=utf-8"
+	}
+}
+
+// timeLayout 在 Markdown 和 HTML 中显示时间的格式
+const timeLayout = "2006-01-02 15:04:05 MST"
+
+// Message 记录中的一条消息
+type Message struct {
+	ID        string    `json:"id"`
+	Role      string    `json:"role"`            // user 或 assistant
+	Model     string    `json:"model,omitempty"` // 生成回复的模型
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Transcript 导出的对话记录，消息为对话当前活动分支，按时间正序排列
+type Transcript struct {
+	Version    int       `json:"version"` // 导出格式的版本
+	ID         string    `json:"id"`
+	Title      string    `json:"title"`
+	Tags       []string  `json:"tags,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	ExportedAt time.Time `json:"exported_at"`
+	Messages   []Message `json:"messages"`
+}
+
+// TranscriptVersion 当前的导出格式版本
+const TranscriptVersion = 1
+
+// Render 按指定格式输出对话记录
+func Render(w io.Writer, t *Transcript, format string) error {
+	switch format {
+	case FormatMarkdown:
+		return Markdown(w, t)
+	case FormatHTML:
+		return HTML(w, t)
+	case FormatJSON:
+		return JSON(w, t)
+	default:
+		return fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// JSON 输出对话记录的 JSON
+func JSON(w io.Writer, t *Transcript) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(t)
+}
+
+// Markdown 输出对话记录的 Markdown：标题、对话信息，每条消息一节，标明角色、模型和时间
+func Markdown(w io.Writer, t *Transcript) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", markdownLine(title(t)))
+	fmt.Fprintf(&b, "- 对话ID：`%s`\n", t.ID)
+	fmt.Fprintf(&b, "- 创建时间：%s\n", t.CreatedAt.Format(timeLayout))
+	fmt.Fprintf(&b, "- 更新时间：%s\n", t.UpdatedAt.Format(timeLayout))
+	if len(t.Tags) > 0 {
+		fmt.Fprintf(&b, "- 标签：%s\n", markdownLine(strings.Join(t.Tags, "、")))
+	}
+	fmt.Fprintf(&b, "- 导出时间：%s\n", t.ExportedAt.Format(timeLayout))
+	for _, msg := range t.Messages {
+		fmt.Fprintf(&b, "\n---\n\n## %s · %s\n\n", markdownLine(speaker(msg)), msg.CreatedAt.Format(timeLayout))
+		content := strings.TrimRight(msg.Content, "\n")
+		if content == "" {
+			content = "*（空）*"
+		}
+		b.WriteString(content)
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// speaker 消息的角色名，助手的回复附上模型名称
+func speaker(msg Message) string {
+	switch msg.Role {
+	case "user":
+		return "用户"
+	case "assistant":
+		if msg.Model != "" {
+			return "助手（" + msg.Model + "）"
+		}
+		return "助手"
+	default:
+		return msg.Role
+	}
+}
+
+// title 对话标题，为空时使用默认标题
+func title(t *Transcript) string {
+	if strings.TrimSpace(t.Title) == "" {
+		return "未命名对话"
+	}
+	return t.Title
+}
+
+// markdownLine 将文本整理为单行，并转义行内会被解释为 Markdown 的字符
+func markdownLine(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	return markdownEscaper.Replace(text)
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`, `<`, `\<`, `#`, `\#`,
+)
+
+// unsafeFilename 文件名中不允许出现的字符
+var unsafeFilename = regexp.MustCompile(`[\\/:*?"<>|\x00-\x1f]+`)
+
+// Filename 导出文件名：日期、标题和对话ID的末尾几位，避免同名对话的文件互相覆盖
+func Filename(t *Transcript, format string) string {
+	name := strings.TrimSpace(unsafeFilename.ReplaceAllString(title(t), "_"))
+	name = strings.Trim(strings.Join(strings.Fields(name), " "), "._ ")
+	if utf8.RuneCountInString(name) > 60 {
+		name = string([]rune(name)[:60])
+	}
+	if name == "" {
+		name = "conversation"
+	}
+	suffix := t.ID
+	if len(suffix) > 8 {
+		suffix = suffix[len(suffix)-8:]
+	}
+	return fmt.Sprintf("%s-%s-%s.%s", t.CreatedAt.Format("2006-01-02"), name, suffix, format)
+}
@@ -53,7 +53,7 @@ func (h *ChatHandler) Chat(c *gin.Context) {
 
 	// 流式返回响应
 	c.Stream(func(w io.Writer) bool {
-		if err := provider.ChatStream([]models.Message{
+		if _, err := provider.ChatStream(c.Request.Context(), []models.Message{
 			{
 				Role:    "user",
 				Content: req.Message,
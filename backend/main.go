@@ -1,19 +1,37 @@
 package main
 
 import (
+	"context"
 	"grandma/backend/config"
 	"grandma/backend/database"
+	"grandma/backend/events"
 	chatHandler "grandma/backend/modules/chat"
 	chatService "grandma/backend/modules/chat"
+	"grandma/backend/modules/command"
 	conversationHandler "grandma/backend/modules/conversation"
 	conversationService "grandma/backend/modules/conversation"
 	conversationListHandler "grandma/backend/modules/conversation_list"
 	conversationListService "grandma/backend/modules/conversation_list"
 	documentHandler "grandma/backend/modules/document"
 	documentService "grandma/backend/modules/document"
+	"grandma/backend/modules/export"
+	"grandma/backend/modules/importer"
+	"grandma/backend/modules/job"
+	"grandma/backend/modules/moderation"
+	"grandma/backend/modules/organize"
+	"grandma/backend/modules/prompt"
+	"grandma/backend/modules/reading"
+	"grandma/backend/modules/revision"
+	"grandma/backend/modules/search"
+	"grandma/backend/modules/share"
 	"grandma/backend/modules/story"
+	"grandma/backend/modules/trash"
+	"grandma/backend/modules/workflow"
+	"grandma/backend/prompts"
 	"grandma/backend/repository"
+	"grandma/backend/safety"
 	"log"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -53,44 +71,140 @@ func main() {
 	conversationRepo := repository.NewConversationRepository(database.DB)
 	documentRepo := repository.NewDocumentRepository(database.DB)
 	storyRepo := repository.NewStoryRepository(database.DB)
+	summaryRepo := repository.NewSummaryRepository(database.DB)
+	storyProjectRepo := repository.NewStoryProjectRepository(database.DB)
+	promptRepo := repository.NewPromptRepository(database.DB)
+	safetyRepo := repository.NewSafetyRepository(database.DB)
+	jobRepo := repository.NewJobRepository(database.DB)
+	searchRepo := repository.NewSearchRepository(database.DB)
+	folderRepo := repository.NewFolderRepository(database.DB)
+	shareRepo := repository.NewShareRepository(database.DB)
+	revisionRepo := repository.NewRevisionRepository(database.DB)
+
+	// 初始化全文搜索索引，首次启动时从已有数据建立索引
+	if err := searchRepo.InitIndex(); err != nil {
+		log.Fatalf("Failed to initialize search index: %v", err)
+	}
+
+	// 创建事件中心，用于向WebSocket连接推送对话事件
+	eventHub := events.NewHub()
+
+	// 创建提示词模板库，服务端的提示词按名称从模板库获取
+	promptStore := prompts.NewStore(promptRepo)
+
+	// 创建内容安全过滤器，检查用户输入和模型输出
+	safetyFilter, err := safety.NewFilter(cfg.SafetyEnabled, cfg.SafetyAgeBand, cfg.SafetyRulesFile)
+	if err != nil {
+		log.Fatalf("Failed to load safety rules: %v", err)
+	}
 
 	// 创建Services
-	chatSvc := chatService.NewChatService(
+	conversationListSvc := conversationListService.NewConversationListService(
 		conversationRepo,
-		documentRepo,
-		&chatService.ChatConfig{
+		&conversationListService.TitleGenerationConfig{
 			OpenAIAPIKey:     cfg.OpenAIAPIKey,
 			OpenAIBaseURL:    cfg.OpenAIBaseURL,
 			AnthropicAPIKey:  cfg.AnthropicAPIKey,
 			AnthropicBaseURL: cfg.AnthropicBaseURL,
+			DefaultModel:     "openai", // 默认使用openai生成标题
 		},
+		eventHub,
+		promptStore,
 	)
-	conversationListSvc := conversationListService.NewConversationListService(
+	revisionSvc := revision.NewRevisionService(revisionRepo, documentRepo, storyRepo)
+	documentSvc := documentService.NewDocumentService(documentRepo, conversationRepo, revisionSvc)
+	conversationSvc := conversationService.NewConversationService(conversationRepo, documentRepo, summaryRepo, eventHub)
+	storySvc := story.NewStoryService(storyRepo, revisionSvc)
+	commandSvc := command.NewCommandService(conversationRepo, documentRepo, conversationSvc, conversationListSvc, storySvc, promptStore)
+	// 斜杠命令在聊天服务中处理，所有聊天入口都支持
+	chatSvc := chatService.NewChatService(
 		conversationRepo,
-		&conversationListService.TitleGenerationConfig{
+		documentRepo,
+		summaryRepo,
+		safetyRepo,
+		&chatService.ChatConfig{
+			OpenAIAPIKey:       cfg.OpenAIAPIKey,
+			OpenAIBaseURL:      cfg.OpenAIBaseURL,
+			AnthropicAPIKey:    cfg.AnthropicAPIKey,
+			AnthropicBaseURL:   cfg.AnthropicBaseURL,
+			ContextTokenBudget: cfg.ContextTokenBudget,
+		},
+		eventHub,
+		promptStore,
+		safetyFilter,
+		commandSvc,
+	)
+	workflowSvc := workflow.NewWorkflowService(
+		storyProjectRepo,
+		conversationRepo,
+		documentRepo,
+		chatSvc,
+		storySvc,
+		&workflow.WorkflowConfig{
 			OpenAIAPIKey:     cfg.OpenAIAPIKey,
 			OpenAIBaseURL:    cfg.OpenAIBaseURL,
 			AnthropicAPIKey:  cfg.AnthropicAPIKey,
 			AnthropicBaseURL: cfg.AnthropicBaseURL,
-			DefaultModel:     "openai", // 默认使用openai生成标题
 		},
+		eventHub,
+		promptStore,
+	)
+	promptSvc := prompt.NewPromptService(promptRepo, promptStore)
+	moderationSvc := moderation.NewModerationService(safetyRepo, conversationRepo, documentRepo, safetyFilter)
+	readingSvc := reading.NewReadingService(conversationRepo, documentRepo, storyRepo)
+	searchSvc := search.NewSearchService(searchRepo)
+	organizeSvc := organize.NewOrganizeService(conversationRepo, folderRepo)
+	exportSvc := export.NewExportService(conversationRepo, documentRepo)
+	importerSvc := importer.NewImporterService(conversationRepo)
+	shareSvc := share.NewShareService(shareRepo, conversationRepo, storyRepo, exportSvc)
+	trashSvc := trash.NewTrashService(conversationRepo, documentRepo, storyRepo, &trash.TrashConfig{
+		RetentionDays: cfg.TrashRetentionDays,
+	})
+	jobSvc := job.NewJobService(
+		jobRepo,
+		conversationRepo,
+		documentRepo,
+		storyProjectRepo,
+		chatSvc,
+		workflowSvc,
+		&job.JobConfig{
+			Workers:     cfg.JobWorkers,
+			MaxAttempts: cfg.JobMaxAttempts,
+		},
+		eventHub,
 	)
-	documentSvc := documentService.NewDocumentService(documentRepo)
-	conversationSvc := conversationService.NewConversationService(conversationRepo, documentRepo)
-	storySvc := story.NewStoryService(storyRepo)
+	// 启动后台任务的工作协程，上次运行中断的任务会重新执行
+	jobSvc.Start(context.Background())
+	// 定期永久删除回收站中超过保留期限的内容
+	trashSvc.Start(context.Background())
 
 	// 创建Handlers
-	chatHdlr := chatHandler.NewChatHandler(chatSvc)
+	chatHdlr := chatHandler.NewChatHandler(chatSvc, eventHub, strings.Split(cfg.CorsAllowedOrigins, ","))
 	conversationListHdlr := conversationListHandler.NewConversationListHandler(conversationListSvc)
 	documentHdlr := documentHandler.NewDocumentHandler(documentSvc)
 	conversationHdlr := conversationHandler.NewConversationHandler(conversationSvc)
 	storiesHdlr := story.NewStoryHandler(storySvc)
+	commandHdlr := command.NewCommandHandler(commandSvc)
+	workflowHdlr := workflow.NewWorkflowHandler(workflowSvc)
+	promptHdlr := prompt.NewPromptHandler(promptSvc)
+	moderationHdlr := moderation.NewModerationHandler(moderationSvc)
+	readingHdlr := reading.NewReadingHandler(readingSvc)
+	jobHdlr := job.NewJobHandler(jobSvc)
+	searchHdlr := search.NewSearchHandler(searchSvc)
+	organizeHdlr := organize.NewOrganizeHandler(organizeSvc)
+	exportHdlr := export.NewExportHandler(exportSvc)
+	importerHdlr := importer.NewImporterHandler(importerSvc)
+	shareHdlr := share.NewShareHandler(shareSvc)
+	revisionHdlr := revision.NewRevisionHandler(revisionSvc)
+	trashHdlr := trash.NewTrashHandler(trashSvc)
 
 	// 配置路由 - 对话模块
 	api := r.Group("/api")
 	{
 		// 聊天接口
 		api.POST("/chat", chatHdlr.Chat)
+		api.GET("/ws", chatHdlr.WebSocket)
+		api.GET("/commands", commandHdlr.GetCommands)
 
 		// 对话列表模块
 		api.GET("/conversations", conversationListHdlr.GetConversationList)
@@ -98,24 +212,111 @@ func main() {
 		api.POST("/conversations/new-with-title", conversationListHdlr.CreateNewConversationWithTitle)
 		api.POST("/conversations/generate-title", conversationListHdlr.GenerateTitle)
 
+		// 整理对话：置顶、归档、文件夹和标签
+		api.POST("/conversations/bulk", organizeHdlr.BulkUpdate)
+		api.PUT("/conversations/:id/pin", organizeHdlr.PinConversation)
+		api.PUT("/conversations/:id/archive", organizeHdlr.ArchiveConversation)
+		api.PUT("/conversations/:id/folder", organizeHdlr.MoveConversation)
+		api.PUT("/conversations/:id/tags", organizeHdlr.SetConversationTags)
+		api.GET("/folders", organizeHdlr.ListFolders)
+		api.POST("/folders", organizeHdlr.CreateFolder)
+		api.PUT("/folders/:id", organizeHdlr.RenameFolder)
+		api.DELETE("/folders/:id", organizeHdlr.DeleteFolder)
+		api.GET("/tags", organizeHdlr.ListTags)
+
+		// 导出对话：Markdown、HTML 或 JSON，批量导出为 zip 压缩包
+		api.GET("/conversations/export", exportHdlr.ExportAll)
+		api.GET("/conversations/:id/export", exportHdlr.ExportConversation)
+
+		// 导入 ChatGPT 和 Claude 的数据导出
+		api.POST("/import", importerHdlr.Import)
+
+		// 分享链接：对话和故事的只读链接，/shared/:token 不需要登录
+		api.GET("/shares", shareHdlr.ListLinks)
+		api.POST("/shares", shareHdlr.CreateLink)
+		api.DELETE("/shares/:id", shareHdlr.RevokeLink)
+		api.GET("/shared/:token", shareHdlr.GetShared)
+
 		// 对话管理模块
 		api.GET("/conversations/:id", conversationHdlr.GetConversationByID)
 		api.POST("/conversations", conversationHdlr.CreateConversation)
 		api.PUT("/conversations/:id", conversationHdlr.UpdateConversation)
 		api.PUT("/conversations/:id/title", conversationHdlr.UpdateConversationTitle)
 		api.DELETE("/conversations/:id", conversationHdlr.DeleteConversation)
+		api.POST("/conversations/:id/fork", conversationHdlr.ForkConversation)
+		api.GET("/conversations/:id/tree", conversationHdlr.GetBranchTree)
 
 		// 文档管理模块
 		api.GET("/documents", documentHdlr.GetDocumentList)
 		api.GET("/documents/ids", documentHdlr.GetDocumentIDs)
+		api.POST("/documents/batch", documentHdlr.BatchGetDocuments)
 		api.GET("/documents/:id", documentHdlr.GetDocumentByID)
 		api.PUT("/documents/:id", documentHdlr.UpdateDocument)
 		api.DELETE("/documents/:id", documentHdlr.DeleteDocument)
+		api.POST("/documents/:id/regenerate", chatHdlr.Regenerate)
+		api.POST("/documents/:id/resubmit", chatHdlr.Resubmit)
+		api.GET("/documents/:id/alternatives", documentHdlr.GetAlternatives)
+		api.PUT("/documents/:id/alternatives/active", documentHdlr.SelectAlternative)
 
 		api.GET("/stories", storiesHdlr.GetStoryList)
 		api.POST("/stories", storiesHdlr.CreateStory)
+		api.PUT("/stories/:id", storiesHdlr.UpdateStory)
 		api.DELETE("/stories/:id", storiesHdlr.DeleteStory)
 
+		// 故事创作工作流：故事前提 → 大纲 → 逐章生成
+		api.GET("/workflows", workflowHdlr.ListProjects)
+		api.POST("/workflows", workflowHdlr.CreateProject)
+		api.GET("/workflows/:id", workflowHdlr.GetProject)
+		api.DELETE("/workflows/:id", workflowHdlr.DeleteProject)
+		api.POST("/workflows/:id/outline", workflowHdlr.GenerateOutline)
+		api.PUT("/workflows/:id/outline", workflowHdlr.UpdateOutline)
+		api.POST("/workflows/:id/chapters/next", workflowHdlr.GenerateNextChapter)
+		api.POST("/workflows/:id/run", workflowHdlr.Run)
+
+		// 提示词模板库
+		api.GET("/prompts", promptHdlr.ListTemplates)
+		api.GET("/prompts/:name", promptHdlr.GetTemplate)
+		api.PUT("/prompts/:name", promptHdlr.SaveTemplate)
+		api.POST("/prompts/:name/revert", promptHdlr.RevertTemplate)
+		api.POST("/prompts/:name/render", promptHdlr.RenderTemplate)
+
+		// 内容安全过滤：规则、处理记录和对话的年龄段
+		api.GET("/safety/rules", moderationHdlr.GetRules)
+		api.GET("/safety/interventions", moderationHdlr.ListInterventions)
+		api.GET("/documents/:id/interventions", moderationHdlr.GetDocumentInterventions)
+		api.PUT("/conversations/:id/age-band", moderationHdlr.UpdateAgeBand)
+
+		// 可读性分析和目标读者年龄
+		api.GET("/documents/:id/readability", readingHdlr.AnalyzeDocument)
+		api.GET("/stories/:id/readability", readingHdlr.AnalyzeStory)
+		api.PUT("/conversations/:id/target-age", readingHdlr.UpdateTargetAge)
+
+		// 文档和故事的修订历史：查看、比较和恢复
+		api.GET("/documents/:id/revisions", revisionHdlr.ListRevisions)
+		api.GET("/documents/:id/revisions/diff", revisionHdlr.DiffRevisions)
+		api.GET("/documents/:id/revisions/:number", revisionHdlr.GetRevision)
+		api.POST("/documents/:id/revisions/:number/revert", revisionHdlr.RevertRevision)
+		api.GET("/stories/:id/revisions", revisionHdlr.ListRevisions)
+		api.GET("/stories/:id/revisions/diff", revisionHdlr.DiffRevisions)
+		api.GET("/stories/:id/revisions/:number", revisionHdlr.GetRevision)
+		api.POST("/stories/:id/revisions/:number/revert", revisionHdlr.RevertRevision)
+
+		// 后台生成任务
+		api.POST("/jobs", jobHdlr.SubmitJob)
+		api.GET("/jobs", jobHdlr.ListJobs)
+		api.GET("/jobs/:id", jobHdlr.GetJob)
+		api.POST("/jobs/:id/cancel", jobHdlr.CancelJob)
+		api.POST("/jobs/:id/retry", jobHdlr.RetryJob)
+
+		// 全文搜索
+		api.GET("/search", searchHdlr.Search)
+
+		// 回收站：删除的对话、文档和故事
+		api.GET("/trash", trashHdlr.ListTrash)
+		api.DELETE("/trash", trashHdlr.EmptyTrash)
+		api.POST("/trash/:type/:id/restore", trashHdlr.RestoreItem)
+		api.DELETE("/trash/:type/:id", trashHdlr.PurgeItem)
+
 		// 获取可用模型列表
 		api.GET("/models", func(c *gin.Context) {
 			models := []map[string]string{
@@ -126,6 +327,9 @@ func main() {
 		})
 	}
 
+	// 分享页面
+	r.GET("/shared/:token", shareHdlr.SharedPage)
+
 	// 健康检查
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
@@ -1,18 +1,58 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // Conversation 对话模型
 type Conversation struct {
-	ID          string     `json:"id" gorm:"primaryKey"`
-	Title       string     `json:"title"`                                      // 对话标题
-	DocumentIDs string     `json:"document_ids" gorm:"type:text"`              // 文档ID列表，按顺序排列，用逗号分隔
-	CreatedAt   time.Time  `json:"created_at"`                                 // 创建时间
-	UpdatedAt   time.Time  `json:"updated_at"`                                 // 更新时间
-	Documents   []Document `json:"documents" gorm:"foreignKey:ConversationID"` // 关联的文档列表
+	ID             string         `json:"id" gorm:"primaryKey"`
+	Title          string         `json:"title"`                                                    // 对话标题
+	DocumentIDs    string         `json:"document_ids" gorm:"-"`                                    // 文档ID列表，按顺序排列，用逗号分隔；由文档的序号生成，不单独保存
+	HeadDocumentID string         `json:"head_document_id"`                                         // 当前活动分支的最后一条文档ID，新消息接在其后
+	AgeBand        string         `json:"age_band"`                                                 // 内容安全过滤的年龄段：young、child、teen，为空时使用默认年龄段
+	TargetAge      int            `json:"target_age"`                                               // 目标读者年龄，用于控制生成内容的阅读难度，0表示不限制
+	Pinned         bool           `json:"pinned" gorm:"not null;default:false;index"`               // 是否置顶，置顶的对话排在列表最前
+	Archived       bool           `json:"archived" gorm:"not null;default:false;index"`             // 是否归档，归档的对话默认不在列表中显示
+	ArchivedAt     *time.Time     `json:"archived_at"`                                              // 归档时间
+	FolderID       string         `json:"folder_id" gorm:"not null;default:'';index"`               // 所在文件夹，为空表示未分类
+	Tags           []string       `json:"tags" gorm:"-"`                                            // 标签，保存在 conversation_tags 表
+	Source         string         `json:"source,omitempty" gorm:"index:idx_conversation_source"`    // 导入的来源：chatgpt、claude，在本应用中创建的对话为空
+	SourceID       string         `json:"source_id,omitempty" gorm:"index:idx_conversation_source"` // 对话在来源中的ID，重复导入时用于去重
+	CreatedAt      time.Time      `json:"created_at"`                                               // 创建时间
+	UpdatedAt      time.Time      `json:"updated_at"`                                               // 更新时间
+	DeletedAt      gorm.DeletedAt `json:"deleted_at" gorm:"index"`                                  // 移入回收站的时间，为空表示未删除
+	Documents      []Document     `json:"documents" gorm:"foreignKey:ConversationID"`               // 关联的文档列表
 }
 
 // TableName 指定表名
 func (Conversation) TableName() string {
 	return "conversations"
 }
+
+// Folder 用户创建的对话文件夹，一个对话最多属于一个文件夹
+type Folder struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"uniqueIndex"` // 文件夹名称，不能重复
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Folder) TableName() string {
+	return "folders"
+}
+
+// ConversationTag 对话的标签，一个对话可以有多个标签
+type ConversationTag struct {
+	ConversationID string    `json:"conversation_id" gorm:"primaryKey"`
+	Tag            string    `json:"tag" gorm:"primaryKey;index"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (ConversationTag) TableName() string {
+	return "conversation_tags"
+}
@@ -1,20 +1,30 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // Document 文档模型
 type Document struct {
-	ID             string    `json:"id" gorm:"primaryKey"`
-	ConversationID string    `json:"conversation_id"`           // 所属对话ID
-	Role           string    `json:"role"`                     // 角色：user 或 assistant
-	Content        string    `json:"content" gorm:"type:text"` // 文档内容
-	Model          string    `json:"model"`                    // 使用的模型
-	CreatedAt      time.Time `json:"created_at"`               // 创建时间
-	UpdatedAt      time.Time `json:"updated_at"`               // 更新时间
+	ID                  string         `json:"id" gorm:"primaryKey"`
+	ConversationID      string         `json:"conversation_id" gorm:"uniqueIndex:idx_document_seq"` // 所属对话ID
+	Role                string         `json:"role"`                                                // 角色：user 或 assistant
+	Content             string         `json:"content" gorm:"type:text"`                            // 文档内容
+	Model               string         `json:"model"`                                               // 使用的模型
+	ParentID            string         `json:"parent_id" gorm:"index"`                              // 分支中的上一条文档ID，分支起点为空
+	AlternativeOf       string         `json:"alternative_of" gorm:"index"`                         // 重新生成的候选版本所属的原始助手文档ID，原始文档为空
+	ActiveAlternativeID string         `json:"active_alternative_id"`                               // 原始助手文档当前选用的候选版本ID，为空表示使用原始内容
+	ReadingAge          int            `json:"reading_age"`                                         // 助手回复估计适合阅读的最小年龄，0表示未分析
+	ExceedsTargetAge    bool           `json:"exceeds_target_age"`                                  // 助手回复是否超过了对话目标年龄的阅读难度
+	Seq                 *int           `json:"seq" gorm:"uniqueIndex:idx_document_seq"`             // 加入对话文档列表的顺序，从1开始；候选版本等未加入列表的文档为空
+	CreatedAt           time.Time      `json:"created_at"`                                          // 创建时间
+	UpdatedAt           time.Time      `json:"updated_at"`                                          // 更新时间
+	DeletedAt           gorm.DeletedAt `json:"deleted_at" gorm:"index"`                             // 移入回收站的时间，为空表示未删除
 }
 
 // TableName 指定表名
 func (Document) TableName() string {
 	return "documents"
 }
-
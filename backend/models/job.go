@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// 后台任务类型
+const (
+	JobTypeChat     = "chat"     // 在对话中生成一条回复
+	JobTypeWorkflow = "workflow" // 执行故事创作项目剩余的全部步骤
+)
+
+// 后台任务状态
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+	JobStatusCancelled = "cancelled"
+)
+
+// Job 持久化的后台生成任务，服务重启后未完成的任务会重新执行
+type Job struct {
+	ID             string     `json:"id" gorm:"primaryKey"`
+	Type           string     `json:"type"`
+	Status         string     `json:"status" gorm:"index"`
+	ConversationID string     `json:"conversation_id" gorm:"index"`
+	ProjectID      string     `json:"project_id,omitempty"`  // 故事创作任务的项目ID
+	Payload        string     `json:"-" gorm:"type:text"`    // 任务参数（JSON）
+	Result         string     `json:"-" gorm:"type:text"`    // 任务结果（JSON）
+	BaseDocumentID string     `json:"-"`                     // 对话任务首次执行时活动分支的最后一条文档ID，用于重试时判断已保存的内容
+	DocumentID     string     `json:"document_id,omitempty"` // 对话任务生成的助手文档ID
+	Step           string     `json:"step"`                  // 当前步骤
+	Progress       int        `json:"progress"`              // 已完成的步骤数
+	Total          int        `json:"total"`                 // 总步骤数
+	GeneratedChars int        `json:"generated_chars"`       // 当前步骤已生成的字符数
+	Attempts       int        `json:"attempts"`              // 已执行的次数
+	MaxAttempts    int        `json:"max_attempts"`
+	Error          string     `json:"error,omitempty"`        // 最近一次失败的原因
+	RunAfter       time.Time  `json:"run_after" gorm:"index"` // 排队的任务在此时间之后执行（失败重试时延后）
+	StartedAt      *time.Time `json:"started_at,omitempty"`   // 最近一次开始执行的时间
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`  // 完成、失败或取消的时间
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Job) TableName() string {
+	return "jobs"
+}
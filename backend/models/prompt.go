@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// PromptTemplate 服务端提示词模板，同名模板每次修改保存为新版本，使用最新版本
+// 模板中的 {{变量名}} 在渲染时替换为对应的值
+type PromptTemplate struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"uniqueIndex:idx_prompt_version"`
+	Version     int       `json:"version" gorm:"uniqueIndex:idx_prompt_version"` // 从1开始，0表示内置默认模板
+	Content     string    `json:"content" gorm:"type:text"`
+	Description string    `json:"description"`
+	Variables   []string  `json:"variables" gorm:"-"` // 模板中引用的变量，由内容解析得到
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (PromptTemplate) TableName() string {
+	return "prompt_templates"
+}
@@ -1,10 +1,55 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+)
+
+// 聊天请求类型
+const (
+	ChatTypeMessage  = "message"  // 发送新消息（默认）
+	ChatTypeContinue = "continue" // 续写已有的助手文档
+	ChatTypeCompare  = "compare"  // 将同一条消息同时发送给多个模型对比
+)
+
 // ChatRequest 聊天请求
 type ChatRequest struct {
 	ConversationID string    `json:"conversation_id"` // 可选，如果为空则创建新对话
-	Model          string    `json:"model" binding:"required"`
-	Messages       []Message `json:"messages" binding:"required"`
+	Model          string    `json:"model"`           // 对比模式以外必填
+	Models         []string  `json:"models"`          // 对比模式下要对比的模型，第一个模型的回复作为默认选用的版本
+	Messages       []Message `json:"messages"`        // 发送新消息和对比时必填
+	Type           string    `json:"type"`            // 请求类型：message（默认）、continue 或 compare
+	DocumentID     string    `json:"document_id"`     // 续写时要续写的助手文档ID，为空时续写对话活动分支的最后一条文档
+	Stream         *bool     `json:"stream"`          // 是否流式返回，默认true；为false时返回完整的JSON结果
+}
+
+// IsStream 是否流式返回
+func (r *ChatRequest) IsStream() bool {
+	return r.Stream == nil || *r.Stream
+}
+
+// Usage token用量
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ChatResponse 聊天结果，非流式模式下直接作为响应体返回
+type ChatResponse struct {
+	ConversationID string `json:"conversation_id"`
+	UserDocumentID string `json:"user_document_id,omitempty"` // 本次保存的用户消息文档ID，续写、重新生成时为空
+	DocumentID     string `json:"document_id"`                // 助手回复的文档ID
+	Content        string `json:"content"`                    // 本次生成的内容（续写时只包含新增部分）
+	Model          string `json:"model"`                      // 请求使用的模型
+	ProviderModel  string `json:"provider_model,omitempty"`   // 服务端实际使用的模型名
+	Usage          Usage  `json:"usage"`
+	FinishReason   string `json:"finish_reason"`
+	Error          string `json:"error,omitempty"` // finish_reason 为 error 时模型服务返回的错误
+	// 请求是在服务端执行的斜杠命令时的结果，此时不生成回复，其他字段只有 conversation_id
+	Command *CommandResponse `json:"command,omitempty"`
+	// 对话设置了目标年龄时，回复的阅读难度检查结果
+	ReadingLevel *ReadingLevelCheck `json:"reading_level,omitempty"`
 }
 
 type Message struct {
@@ -12,43 +57,83 @@ type Message struct {
 	Content string `json:"content"`
 }
 
-// ConversationListRequest 对话列表请求
+// PageInfo 游标翻页信息，游标是不透明的字符串，原样传回 cursor 参数即可
+type PageInfo struct {
+	NextCursor string `json:"next_cursor,omitempty"` // 下一页的游标，列表为空时不返回
+	PrevCursor string `json:"prev_cursor,omitempty"` // 上一页的游标，列表为空时不返回
+	HasMore    bool   `json:"has_more"`              // 请求的翻页方向上是否还有更多记录
+	HasPrev    bool   `json:"has_prev"`              // 本页之前是否还有记录
+	HasNext    bool   `json:"has_next"`              // 本页之后是否还有记录
+}
+
+// ConversationListRequest 对话列表请求，筛选条件之间为 AND 关系
 type ConversationListRequest struct {
-	Page     int `json:"page" form:"page"`
-	PageSize int `json:"page_size" form:"page_size"`
+	Cursor      string   `json:"cursor" form:"cursor"`
+	PageSize    int      `json:"page_size" form:"page_size"`
+	FolderID    string   `json:"folder_id" form:"folder_id"`       // 文件夹ID，none 表示未分类的对话
+	Tags        []string `json:"tag" form:"tag"`                   // 标签，可以传多个，返回同时带有这些标签的对话
+	Archived    string   `json:"archived" form:"archived"`         // false（默认）：未归档的对话；true：已归档的对话；all：全部
+	Pinned      string   `json:"pinned" form:"pinned"`             // true：只返回置顶的对话；false：只返回未置顶的对话
+	Sort        string   `json:"sort" form:"sort"`                 // 排序字段：updated_at（默认）、created_at、title
+	Order       string   `json:"order" form:"order"`               // asc 或 desc，时间默认倒序，标题默认正序
+	PinnedFirst string   `json:"pinned_first" form:"pinned_first"` // 置顶的对话是否排在最前，默认 true
 }
 
-// ConversationListResponse 对话列表响应
+// ConversationListResponse 对话列表响应，默认置顶的对话在前，其余按更新时间倒序排列
 type ConversationListResponse struct {
 	Conversations []Conversation `json:"conversations"`
 	Total         int            `json:"total"`
-	Page          int            `json:"page"`
 	PageSize      int            `json:"page_size"`
+	PageInfo
 }
 
 // DocumentListRequest 文档列表请求
 type DocumentListRequest struct {
 	ConversationID string `json:"conversation_id" form:"conversation_id" binding:"required"`
-	BeforeID       string `json:"before_id" form:"before_id"` // 用于翻页，获取比该ID更早的文档
+	Cursor         string `json:"cursor" form:"cursor"`       // 上次返回的 prev_cursor 或 next_cursor
+	BeforeID       string `json:"before_id" form:"before_id"` // 兼容旧接口：获取比该文档更早的文档，与 cursor 同时指定时忽略
 	Limit          int    `json:"limit" form:"limit"`         // 返回数量，默认10
 }
 
 // DocumentIDsRequest 获取文档ID列表的请求
 type DocumentIDsRequest struct {
 	ConversationID string `json:"conversation_id" form:"conversation_id" binding:"required"`
-	BeforeID       string `json:"before_id" form:"before_id"` // 用于翻页，获取比该ID更早的文档ID
+	Cursor         string `json:"cursor" form:"cursor"`       // 上次返回的 prev_cursor 或 next_cursor
+	BeforeID       string `json:"before_id" form:"before_id"` // 兼容旧接口：获取比该文档更早的文档ID，与 cursor 同时指定时忽略
 	Limit          int    `json:"limit" form:"limit"`         // 返回数量，默认10
 }
 
-// DocumentIDsResponse 文档ID列表响应
+// DocumentIDsResponse 文档ID列表响应，按时间正序排列
 type DocumentIDsResponse struct {
 	DocumentIDs []string `json:"document_ids"`
+	PageInfo
 }
 
-// DocumentListResponse 文档列表响应
+// DocumentListResponse 文档列表响应，按时间正序排列
 type DocumentListResponse struct {
 	Documents []Document `json:"documents"`
 	Total     int        `json:"total"`
+	PageInfo
+}
+
+// MaxBatchDocuments 批量获取文档时一次最多请求的ID数
+const MaxBatchDocuments = 100
+
+// BatchDocumentsRequest 批量获取文档的请求
+type BatchDocumentsRequest struct {
+	IDs []string `json:"ids" binding:"required"`
+}
+
+// BatchDocumentResult 批量获取时单个ID的结果
+type BatchDocumentResult struct {
+	ID       string    `json:"id"`
+	Found    bool      `json:"found"`              // 文档不存在时为false
+	Document *Document `json:"document,omitempty"` // 文档不存在时为空
+}
+
+// BatchDocumentsResponse 批量获取文档的响应，与请求中的ID顺序一致
+type BatchDocumentsResponse struct {
+	Documents []BatchDocumentResult `json:"documents"`
 }
 
 // CreateConversationWithTitleRequest 创建对话并生成标题的请求
@@ -70,3 +155,563 @@ type StoryResponse struct {
 	Story []Story `json:"stories"`
 	Total int     `json:"total"`
 }
+
+// CompareResponse 多模型对比的结果，各模型的回复互为候选版本
+type CompareResponse struct {
+	ConversationID string         `json:"conversation_id"`
+	UserDocumentID string         `json:"user_document_id"`
+	OriginalID     string         `json:"original_id"` // 候选版本的原始文档，即第一个模型的回复
+	Results        []ChatResponse `json:"results"`     // 与请求中的模型顺序一致
+}
+
+// CompareEvent 多模型对比流式返回的事件
+type CompareEvent struct {
+	Type       string           `json:"type"` // delta：增量内容；done：单个模型生成完毕；complete：全部完成
+	Model      string           `json:"model,omitempty"`
+	DocumentID string           `json:"document_id,omitempty"`
+	Content    string           `json:"content,omitempty"`
+	Result     *ChatResponse    `json:"result,omitempty"`  // type=done 时单个模型的结果
+	Compare    *CompareResponse `json:"compare,omitempty"` // type=complete 时的完整结果
+}
+
+// CommandInfo 斜杠命令说明，用于前端自动补全
+type CommandInfo struct {
+	Name        string `json:"name"`        // 命令名，不含斜杠
+	Usage       string `json:"usage"`       // 用法示例
+	Description string `json:"description"` // 命令说明
+	Kind        string `json:"kind"`        // prompt：转换为提示词后走正常聊天流程；action：在服务端直接执行
+}
+
+// CommandListResponse 斜杠命令列表响应
+type CommandListResponse struct {
+	Commands []CommandInfo `json:"commands"`
+}
+
+// CommandResponse 服务端执行的斜杠命令的结果
+type CommandResponse struct {
+	Command        string `json:"command"`
+	ConversationID string `json:"conversation_id"`
+	Title          string `json:"title,omitempty"`    // /title 生成或设置的标题
+	StoryID        string `json:"story_id,omitempty"` // /save-story 保存的故事ID
+	DocumentID     string `json:"document_id,omitempty"`
+}
+
+// CreateStoryProjectRequest 创建故事项目的请求
+type CreateStoryProjectRequest struct {
+	Title        string `json:"title"`
+	Premise      string `json:"premise" binding:"required"` // 故事前提：主题、人物、设定等
+	Model        string `json:"model" binding:"required"`
+	ChapterCount int    `json:"chapter_count"` // 章节数，默认5
+}
+
+// UpdateOutlineRequest 手动修改故事大纲的请求
+type UpdateOutlineRequest struct {
+	Outline string `json:"outline" binding:"required"`
+}
+
+// StoryProjectListResponse 故事项目列表响应
+type StoryProjectListResponse struct {
+	Projects []StoryProject `json:"projects"`
+	Total    int            `json:"total"`
+}
+
+// WorkflowEvent 故事项目生成过程中流式返回的事件
+type WorkflowEvent struct {
+	Type       string        `json:"type"`              // step：开始生成大纲或章节；delta：增量内容；step_completed：大纲或章节生成完毕；completed：项目完成；error：出错
+	Stage      string        `json:"stage,omitempty"`   // 当前步骤所属阶段：outline 或 writing
+	Chapter    int           `json:"chapter,omitempty"` // 当前章节序号，生成大纲时为0
+	DocumentID string        `json:"document_id,omitempty"`
+	Content    string        `json:"content,omitempty"`
+	Project    *StoryProject `json:"project,omitempty"` // step_completed 和 completed 时的项目状态
+	Error      string        `json:"error,omitempty"`
+}
+
+// PromptListResponse 提示词模板列表响应，每个模板为当前使用的版本
+type PromptListResponse struct {
+	Templates []PromptTemplate `json:"templates"`
+}
+
+// PromptDetailResponse 提示词模板详情，包括全部历史版本（新版本在前，内置默认模板为版本0）
+type PromptDetailResponse struct {
+	Current  PromptTemplate   `json:"current"`
+	Versions []PromptTemplate `json:"versions"`
+}
+
+// SavePromptRequest 保存提示词模板新版本的请求
+type SavePromptRequest struct {
+	Content     string `json:"content" binding:"required"`
+	Description string `json:"description"`
+}
+
+// RevertPromptRequest 将提示词模板恢复到指定版本的请求，版本0为内置默认模板
+type RevertPromptRequest struct {
+	Version *int `json:"version" binding:"required"`
+}
+
+// RenderPromptRequest 渲染或预览提示词模板的请求
+type RenderPromptRequest struct {
+	Variables map[string]string `json:"variables"`
+	Content   string            `json:"content"` // 可选，预览尚未保存的模板内容
+	Version   *int              `json:"version"` // 可选，渲染指定版本，默认为当前版本
+}
+
+// RenderPromptResponse 提示词模板渲染结果
+type RenderPromptResponse struct {
+	Name             string   `json:"name"`
+	Version          int      `json:"version"`
+	Rendered         string   `json:"rendered"`
+	Variables        []string `json:"variables"`
+	MissingVariables []string `json:"missing_variables"` // 没有提供值的变量，在渲染结果中保留原样
+}
+
+// RegenerateRequest 重新生成助手回复的请求
+type RegenerateRequest struct {
+	Model string `json:"model"` // 可选，为空时沿用原始文档的模型
+}
+
+// SelectAlternativeRequest 选用候选版本的请求
+type SelectAlternativeRequest struct {
+	AlternativeID string `json:"alternative_id" binding:"required"` // 候选版本ID，传原始文档ID表示恢复原始内容
+}
+
+// AlternativesResponse 候选版本列表响应
+type AlternativesResponse struct {
+	OriginalID   string     `json:"original_id"`  // 原始助手文档ID
+	ActiveID     string     `json:"active_id"`    // 当前选用的版本ID
+	Alternatives []Document `json:"alternatives"` // 原始文档及其所有候选版本，按生成时间排列
+}
+
+// ForkConversationRequest 从指定文档分叉对话的请求
+type ForkConversationRequest struct {
+	DocumentID string `json:"document_id"` // 分叉点文档ID，新消息将接在其后；为空表示从对话开头开始新分支
+}
+
+// BranchNode 分支树节点
+type BranchNode struct {
+	ID        string        `json:"id"`
+	ParentID  string        `json:"parent_id"`
+	Role      string        `json:"role"`
+	Model     string        `json:"model"`
+	Preview   string        `json:"preview"` // 内容预览
+	Active    bool          `json:"active"`  // 是否位于当前活动分支上
+	CreatedAt time.Time     `json:"created_at"`
+	Children  []*BranchNode `json:"children"`
+}
+
+// BranchTreeResponse 对话分支树响应
+type BranchTreeResponse struct {
+	ConversationID string        `json:"conversation_id"`
+	HeadDocumentID string        `json:"head_document_id"`
+	Roots          []*BranchNode `json:"roots"`
+}
+
+// ResubmitRequest 编辑并重新提交用户消息的请求
+type ResubmitRequest struct {
+	Content string `json:"content" binding:"required"` // 编辑后的消息内容
+	Model   string `json:"model"`                      // 可选，为空时沿用原消息的模型
+}
+
+// UpdateAgeBandRequest 设置对话内容安全过滤年龄段的请求
+type UpdateAgeBandRequest struct {
+	AgeBand string `json:"age_band"` // young、child 或 teen，为空表示使用默认年龄段
+}
+
+// AgeBandResponse 对话年龄段响应
+type AgeBandResponse struct {
+	ConversationID string `json:"conversation_id"`
+	AgeBand        string `json:"age_band"`           // 对话设置的年龄段，为空表示使用默认年龄段
+	EffectiveBand  string `json:"effective_age_band"` // 实际使用的年龄段
+}
+
+// SafetyRuleInfo 内容安全过滤规则
+type SafetyRuleInfo struct {
+	ID       string            `json:"id"`
+	Category string            `json:"category"`
+	Keywords []string          `json:"keywords,omitempty"`
+	Patterns []string          `json:"patterns,omitempty"`
+	Actions  map[string]string `json:"actions"` // 各年龄段的处理方式
+}
+
+// SafetyRulesResponse 内容安全过滤规则列表响应
+type SafetyRulesResponse struct {
+	Enabled        bool             `json:"enabled"`
+	DefaultAgeBand string           `json:"default_age_band"`
+	Rules          []SafetyRuleInfo `json:"rules"`
+}
+
+// InterventionListRequest 内容安全处理记录查询请求
+type InterventionListRequest struct {
+	ConversationID string `json:"conversation_id" form:"conversation_id"` // 可选，为空时查询所有对话
+	Limit          int    `json:"limit" form:"limit"`                     // 默认50，最大200
+}
+
+// InterventionListResponse 内容安全处理记录列表响应
+type InterventionListResponse struct {
+	Interventions []SafetyIntervention `json:"interventions"`
+}
+
+// ReadingLevelCheck 阅读难度与目标年龄的比较结果
+type ReadingLevelCheck struct {
+	TargetAge    int            `json:"target_age"`
+	EstimatedAge int            `json:"estimated_age"`
+	Exceeds      bool           `json:"exceeds"` // 是否超过目标年龄的阅读难度
+	Issues       []ReadingIssue `json:"issues"`  // 超出的指标
+}
+
+// ReadingIssue 超过目标年龄难度上限的一项指标
+type ReadingIssue struct {
+	Metric string  `json:"metric"` // avg_sentence_length、basic_ratio 或 advanced_ratio
+	Value  float64 `json:"value"`
+	Limit  float64 `json:"limit"`
+}
+
+// ReadingLevel 目标年龄的阅读难度上限
+type ReadingLevel struct {
+	Age              int     `json:"age"`
+	MaxSentenceZh    float64 `json:"max_sentence_zh"`    // 中文平均句长上限（汉字）
+	MaxSentenceEn    float64 `json:"max_sentence_en"`    // 英文平均句长上限（单词）
+	MinBasicRatio    float64 `json:"min_basic_ratio"`    // 高频字词比例下限
+	MaxAdvancedRatio float64 `json:"max_advanced_ratio"` // 生僻字词比例上限
+}
+
+// ReadabilityReport 可读性分析结果，中文按汉字计数，英文按单词计数
+type ReadabilityReport struct {
+	Language          string         `json:"language"`            // 主要语言：zh 或 en
+	Characters        int            `json:"characters"`          // 汉字数
+	Words             int            `json:"words"`               // 英文单词数
+	Sentences         int            `json:"sentences"`           // 句子数
+	AvgSentenceLength float64        `json:"avg_sentence_length"` // 平均句长（汉字和单词数）
+	MaxSentenceLength int            `json:"max_sentence_length"` // 最长句子的长度
+	Tiers             WordTiers      `json:"tiers"`
+	BasicRatio        float64        `json:"basic_ratio"`    // 高频字词的比例
+	AdvancedRatio     float64        `json:"advanced_ratio"` // 生僻字词的比例
+	UncommonWords     []UncommonWord `json:"uncommon_words"` // 生僻字词，按出现次数排列
+	EstimatedAge      int            `json:"estimated_age"`  // 估计适合阅读的最小年龄，空文本为0
+}
+
+// WordTiers 各频率等级字词的数量
+type WordTiers struct {
+	Basic    int `json:"basic"`
+	Common   int `json:"common"`
+	Advanced int `json:"advanced"`
+}
+
+// UncommonWord 生僻字词及出现次数
+type UncommonWord struct {
+	Text  string `json:"text"`
+	Count int    `json:"count"`
+}
+
+// UpdateTargetAgeRequest 设置对话目标读者年龄的请求
+type UpdateTargetAgeRequest struct {
+	TargetAge *int `json:"target_age" binding:"required"` // 3到18岁，0表示不限制
+}
+
+// TargetAgeResponse 对话目标读者年龄响应
+type TargetAgeResponse struct {
+	ConversationID string        `json:"conversation_id"`
+	TargetAge      int           `json:"target_age"`
+	Level          *ReadingLevel `json:"level,omitempty"` // 目标年龄使用的难度上限
+}
+
+// ReadabilityRequest 可读性分析请求
+type ReadabilityRequest struct {
+	TargetAge int `json:"target_age" form:"target_age"` // 可选，为空时文档使用所属对话的目标年龄
+}
+
+// ReadabilityResponse 文档或故事的可读性分析响应
+type ReadabilityResponse struct {
+	DocumentID   string             `json:"document_id,omitempty"`
+	StoryID      string             `json:"story_id,omitempty"`
+	Report       *ReadabilityReport `json:"report"`
+	ReadingLevel *ReadingLevelCheck `json:"reading_level,omitempty"` // 指定了目标年龄时的检查结果
+}
+
+// SubmitJobRequest 提交后台任务的请求
+type SubmitJobRequest struct {
+	Type        string       `json:"type" binding:"required"` // chat 或 workflow
+	Request     *ChatRequest `json:"request"`                 // chat 任务的聊天请求（type 为 message 或 continue）
+	ProjectID   string       `json:"project_id"`              // workflow 任务的故事项目ID
+	MaxAttempts int          `json:"max_attempts"`            // 可选，失败后最多执行的次数
+}
+
+// JobListRequest 后台任务列表查询请求
+type JobListRequest struct {
+	Status string `json:"status" form:"status"` // 可选，按状态筛选
+	Limit  int    `json:"limit" form:"limit"`   // 默认50，最大200
+}
+
+// JobResponse 后台任务详情，包含任务参数和结果
+type JobResponse struct {
+	Job
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"` // chat 任务为聊天响应，workflow 任务为项目
+}
+
+// JobListResponse 后台任务列表响应
+type JobListResponse struct {
+	Jobs []Job `json:"jobs"`
+}
+
+// SearchRequest 全文搜索请求
+type SearchRequest struct {
+	Query          string `form:"q" binding:"required"`
+	Types          string `form:"types"`           // 逗号分隔的结果类型：conversation、document、story，为空时搜索全部
+	ConversationID string `form:"conversation_id"` // 只搜索该对话的标题和文档
+	Page           int    `form:"page"`
+	PageSize       int    `form:"page_size"`
+}
+
+// SearchResult 一条搜索结果，标题和片段已做HTML转义，检索词用 <mark> 标记
+type SearchResult struct {
+	Type              string    `json:"type"`                         // conversation、document 或 story
+	ID                string    `json:"id"`                           // 对话、文档或故事的ID
+	ConversationID    string    `json:"conversation_id,omitempty"`    // 所属对话
+	ConversationTitle string    `json:"conversation_title,omitempty"` // 文档所属对话的标题
+	Title             string    `json:"title,omitempty"`              // 对话或故事的标题
+	Snippet           string    `json:"snippet,omitempty"`            // 正文中检索词附近的片段
+	Score             float64   `json:"score"`                        // 相关度，越小越相关；不支持全文索引时为0
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// SearchResponse 全文搜索响应
+type SearchResponse struct {
+	Query    string         `json:"query"`
+	Mode     string         `json:"mode"` // fts：全文索引；like：不支持 FTS5 时的模糊查询
+	Results  []SearchResult `json:"results"`
+	Total    int            `json:"total"`
+	Page     int            `json:"page"`
+	PageSize int            `json:"page_size"`
+	HasMore  bool           `json:"has_more"`
+}
+
+// 回收站中的内容类型
+const (
+	TrashTypeConversation = "conversation"
+	TrashTypeDocument     = "document"
+	TrashTypeStory        = "story"
+)
+
+// TrashItem 回收站中的一项内容
+type TrashItem struct {
+	Type           string     `json:"type"` // conversation、document 或 story
+	ID             string     `json:"id"`
+	ConversationID string     `json:"conversation_id,omitempty"` // 文档所属的对话
+	Title          string     `json:"title,omitempty"`           // 对话或故事的标题
+	Preview        string     `json:"preview,omitempty"`         // 文档或故事内容的开头
+	DeletedAt      time.Time  `json:"deleted_at"`
+	PurgeAt        *time.Time `json:"purge_at,omitempty"` // 自动永久删除的时间，未设置保留期限时为空
+}
+
+// TrashListResponse 回收站列表响应，最近删除的在前
+type TrashListResponse struct {
+	Items         []TrashItem `json:"items"`
+	RetentionDays int         `json:"retention_days"` // 回收站内容保留的天数，0表示不自动删除
+}
+
+// PurgeTrashResponse 永久删除回收站内容的结果
+type PurgeTrashResponse struct {
+	Conversations int64 `json:"conversations"`
+	Documents     int64 `json:"documents"`
+	Stories       int64 `json:"stories"`
+}
+
+// MaxBulkConversations 一次批量操作最多处理的对话数
+const MaxBulkConversations = 200
+
+// 批量整理对话的操作
+const (
+	BulkActionMove      = "move"      // 移动到 folder_id 指定的文件夹，为空时移出文件夹
+	BulkActionTag       = "tag"       // 添加 tags 中的标签
+	BulkActionUntag     = "untag"     // 移除 tags 中的标签
+	BulkActionArchive   = "archive"   // 归档
+	BulkActionUnarchive = "unarchive" // 取消归档
+	BulkActionPin       = "pin"       // 置顶
+	BulkActionUnpin     = "unpin"     // 取消置顶
+)
+
+// BulkConversationRequest 批量整理对话请求
+type BulkConversationRequest struct {
+	IDs      []string `json:"ids" binding:"required"`
+	Action   string   `json:"action" binding:"required"`
+	FolderID string   `json:"folder_id"` // move 的目标文件夹
+	Tags     []string `json:"tags"`      // tag、untag 的标签
+}
+
+// BulkConversationResponse 批量整理对话的结果
+type BulkConversationResponse struct {
+	Action   string   `json:"action"`
+	Updated  int64    `json:"updated"`   // 状态发生变化的对话数；tag、untag 为添加或移除的标签数
+	NotFound []string `json:"not_found"` // 不存在或在回收站中的对话ID
+}
+
+// PinConversationRequest 置顶或取消置顶对话请求
+type PinConversationRequest struct {
+	Pinned bool `json:"pinned"`
+}
+
+// ArchiveConversationRequest 归档或取消归档对话请求
+type ArchiveConversationRequest struct {
+	Archived bool `json:"archived"`
+}
+
+// ConversationUpdateRequest 修改对话请求，只能修改标题；活动分支、年龄段、置顶、归档、文件夹等状态使用各自的接口修改
+type ConversationUpdateRequest struct {
+	Title *string `json:"title"` // 为空时不修改
+}
+
+// MoveConversationRequest 移动对话到文件夹请求，folder_id 为空表示移出文件夹
+type MoveConversationRequest struct {
+	FolderID string `json:"folder_id"`
+}
+
+// ConversationTagsRequest 设置对话标签请求，替换对话原有的标签
+type ConversationTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// FolderRequest 创建或重命名文件夹请求
+type FolderRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// FolderItem 文件夹及其中的对话数
+type FolderItem struct {
+	Folder            `gorm:"embedded"`
+	ConversationCount int64 `json:"conversation_count"` // 不包括回收站中的对话
+}
+
+// FolderListResponse 文件夹列表响应，按名称排序
+type FolderListResponse struct {
+	Folders []FolderItem `json:"folders"`
+}
+
+// TagCount 标签及使用该标签的对话数
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+// TagListResponse 标签列表响应，使用最多的标签在前
+type TagListResponse struct {
+	Tags []TagCount `json:"tags"`
+}
+
+// 导入对话的结果
+const (
+	ImportStatusImported  = "imported"  // 已导入
+	ImportStatusDuplicate = "duplicate" // 之前已导入过，跳过
+	ImportStatusEmpty     = "empty"     // 没有可导入的消息，跳过
+	ImportStatusFailed    = "failed"    // 保存失败
+)
+
+// ImportResult 一个对话的导入结果
+type ImportResult struct {
+	SourceID       string `json:"source_id"`                 // 对话在来源中的ID
+	Title          string `json:"title"`                     // 导入后的标题
+	Status         string `json:"status"`                    // imported、duplicate、empty 或 failed
+	ConversationID string `json:"conversation_id,omitempty"` // 导入后的对话ID；重复时为之前导入的对话
+	Messages       int    `json:"messages"`                  // 导入的消息数
+	Error          string `json:"error,omitempty"`
+}
+
+// ImportSummary 导入的汇总结果
+type ImportSummary struct {
+	Source     string         `json:"source"`  // chatgpt 或 claude
+	DryRun     bool           `json:"dry_run"` // 只解析不保存
+	Total      int            `json:"total"`   // 导出数据中的对话数
+	Imported   int            `json:"imported"`
+	Duplicates int            `json:"duplicates"`
+	Empty      int            `json:"empty"`
+	Failed     int            `json:"failed"`
+	Messages   int            `json:"messages"` // 导入的消息总数
+	Results    []ImportResult `json:"results"`
+}
+
+// ShareRequest 创建分享链接请求
+type ShareRequest struct {
+	TargetType string     `json:"target_type" binding:"required"` // conversation 或 story
+	TargetID   string     `json:"target_id" binding:"required"`
+	ExpiresAt  *time.Time `json:"expires_at"` // 过期时间，为空表示不过期
+}
+
+// ShareLinkItem 分享链接及其当前状态
+type ShareLinkItem struct {
+	ShareLink
+	Status string `json:"status"` // active、expired 或 revoked
+	Path   string `json:"path"`   // 分享页面的路径，JSON 格式的内容在 /api 下的同名路径
+}
+
+// ShareLinkListResponse 分享链接列表响应，最近创建的在前
+type ShareLinkListResponse struct {
+	Links []ShareLinkItem `json:"links"`
+}
+
+// DocumentUpdateRequest 修改文档请求，只能修改内容，可以说明修改人和修改原因
+type DocumentUpdateRequest struct {
+	Content *string `json:"content" binding:"required"` // 修改后的内容，可以为空字符串
+	Author  string  `json:"author"`                     // 修改人，默认为 user
+	Reason  string  `json:"reason"`                     // 修改原因
+}
+
+// StoryUpdateRequest 修改故事请求
+type StoryUpdateRequest struct {
+	Title   *string `json:"title"` // 为空时不修改标题
+	Content string  `json:"content" binding:"required"`
+	Author  string  `json:"author"` // 修改人，默认为 user
+	Reason  string  `json:"reason"` // 修改原因
+}
+
+// RevertRequest 恢复到之前的修订请求
+type RevertRequest struct {
+	Author string `json:"author"` // 修改人，默认为 user
+	Reason string `json:"reason"` // 修改原因
+}
+
+// RevisionListResponse 修订列表响应，最新的在前，不包含内容
+type RevisionListResponse struct {
+	Revisions []Revision `json:"revisions"`
+	Total     int        `json:"total"`
+}
+
+// RevisionDiffRequest 比较两个修订请求，to 默认为最新的修订，from 默认为 to 的上一个修订
+type RevisionDiffRequest struct {
+	From int `form:"from"`
+	To   int `form:"to"`
+}
+
+// RevisionDiffResponse 比较两个修订的结果
+type RevisionDiffResponse struct {
+	From int         `json:"from"`
+	To   int         `json:"to"`
+	Diff *DiffResult `json:"diff"`
+}
+
+// DiffResult 两段文字逐行比较的结果
+type DiffResult struct {
+	Lines []DiffLine `json:"lines"`
+	Stats DiffStats  `json:"stats"`
+}
+
+// DiffLine 比较结果中的一行
+type DiffLine struct {
+	Op       string        `json:"op"`                 // equal、insert 或 delete
+	OldLine  int           `json:"old_line,omitempty"` // 在旧版本中的行号，从1开始，插入的行为空
+	NewLine  int           `json:"new_line,omitempty"` // 在新版本中的行号，从1开始，删除的行为空
+	Text     string        `json:"text"`
+	Segments []DiffSegment `json:"segments,omitempty"` // 修改过的行的逐字比较结果
+}
+
+// DiffSegment 行内逐字比较的一段文字
+type DiffSegment struct {
+	Op   string `json:"op"` // equal、insert 或 delete
+	Text string `json:"text"`
+}
+
+// DiffStats 修改的统计，字数按字符计算，不包括换行
+type DiffStats struct {
+	LinesAdded   int `json:"lines_added"`
+	LinesDeleted int `json:"lines_deleted"`
+	CharsAdded   int `json:"chars_added"`
+	CharsDeleted int `json:"chars_deleted"`
+}
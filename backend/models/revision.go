@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// 修订的内容类型
+const (
+	RevisionTargetDocument = "document"
+	RevisionTargetStory    = "story"
+)
+
+// 修订的来源
+const (
+	RevisionActionOriginal = "original" // 修改前的原有内容：第一次修改前的内容，或在修订之外被更改过的内容（例如续写）
+	RevisionActionEdit     = "edit"     // 修改
+	RevisionActionRevert   = "revert"   // 恢复到之前的修订
+)
+
+// DefaultRevisionAuthor 修改时没有说明修改人时使用的修改人
+const DefaultRevisionAuthor = "user"
+
+// Revision 文档或故事的一个修订版本，保存修改后的完整内容
+// 每次修改内容增加一个修订；修改前的内容与最新的修订不同时（包括第一次修改），先保存修改前的内容
+type Revision struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	TargetType   string    `json:"target_type" gorm:"uniqueIndex:idx_revision_number"` // document 或 story
+	TargetID     string    `json:"target_id" gorm:"uniqueIndex:idx_revision_number"`
+	Number       int       `json:"number" gorm:"uniqueIndex:idx_revision_number"` // 修订号，从1开始
+	Title        string    `json:"title,omitempty"`                               // 故事的标题，文档为空
+	Content      string    `json:"content,omitempty" gorm:"type:text"`            // 修订列表中不包含内容
+	ContentHash  string    `json:"content_hash"`
+	Length       int       `json:"length"`                  // 内容的字符数
+	Action       string    `json:"action"`                  // original、edit 或 revert
+	Author       string    `json:"author"`                  // 修改人；原有内容为生成回复的模型或 user
+	Reason       string    `json:"reason"`                  // 修改原因
+	RevertedFrom *int      `json:"reverted_from,omitempty"` // 恢复时恢复到的修订号
+	CreatedAt    time.Time `json:"created_at"`              // 修改时间；原有内容为修改前的更新时间
+}
+
+// TableName 指定表名
+func (Revision) TableName() string {
+	return "revisions"
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// 内容安全检查的阶段
+const (
+	SafetyStageInput  = "input"  // 用户输入
+	SafetyStageOutput = "output" // 模型输出
+)
+
+// SafetyIntervention 内容安全过滤的处理记录，供家长查看
+// 同一文档中同一规则的多次命中合并为一条记录
+type SafetyIntervention struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	ConversationID string    `json:"conversation_id" gorm:"index"`
+	DocumentID     string    `json:"document_id" gorm:"index"` // 输入被拦截或所在消息没有保存为文档时为空
+	Stage          string    `json:"stage"`                    // input 或 output
+	AgeBand        string    `json:"age_band"`
+	RuleID         string    `json:"rule_id"`
+	Category       string    `json:"category"`
+	Action         string    `json:"action"`                   // mask、rewrite 或 block
+	Matched        string    `json:"matched"`                  // 命中的内容，多个用逗号分隔
+	Count          int       `json:"count"`                    // 命中次数
+	Content        string    `json:"content" gorm:"type:text"` // 输入被拦截或所在消息没有保存为文档时的原始输入
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (SafetyIntervention) TableName() string {
+	return "safety_interventions"
+}
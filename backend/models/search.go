@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// SearchEntry 搜索索引的条目，保存对话标题、文档和故事的可检索文本
+// 支持 FTS5 时，全文索引表 search_fts 使用与本表相同的 rowid
+type SearchEntry struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	Kind           string    `json:"kind" gorm:"uniqueIndex:idx_search_ref"`   // conversation、document 或 story
+	RefID          string    `json:"ref_id" gorm:"uniqueIndex:idx_search_ref"` // 对话、文档或故事的ID
+	ConversationID string    `json:"conversation_id" gorm:"index"`             // 所属对话，故事为空
+	Title          string    `json:"title"`
+	Body           string    `json:"body" gorm:"type:text"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (SearchEntry) TableName() string {
+	return "search_entries"
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// 分享的内容类型
+const (
+	ShareTargetConversation = "conversation"
+	ShareTargetStory        = "story"
+)
+
+// 分享链接的状态
+const (
+	ShareStatusActive  = "active"  // 可以访问
+	ShareStatusExpired = "expired" // 已过期
+	ShareStatusRevoked = "revoked" // 已撤销
+)
+
+// ShareLink 对话或故事的只读分享链接，持有令牌的人无需登录即可查看
+// 撤销后保留记录，以便查看访问次数
+type ShareLink struct {
+	ID             string     `json:"id" gorm:"primaryKey"`
+	Token          string     `json:"token" gorm:"uniqueIndex"`                  // 链接中的随机令牌
+	TargetType     string     `json:"target_type" gorm:"index:idx_share_target"` // conversation 或 story
+	TargetID       string     `json:"target_id" gorm:"index:idx_share_target"`
+	ExpiresAt      *time.Time `json:"expires_at"` // 过期时间，为空表示不过期
+	RevokedAt      *time.Time `json:"revoked_at"` // 撤销时间，为空表示未撤销
+	AccessCount    int64      `json:"access_count" gorm:"not null;default:0"`
+	LastAccessedAt *time.Time `json:"last_accessed_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (ShareLink) TableName() string {
+	return "share_links"
+}
+
+// Status 分享链接在指定时间的状态
+func (l *ShareLink) Status(now time.Time) string {
+	switch {
+	case l.RevokedAt != nil:
+		return ShareStatusRevoked
+	case l.ExpiresAt != nil && !now.Before(*l.ExpiresAt):
+		return ShareStatusExpired
+	default:
+		return ShareStatusActive
+	}
+}
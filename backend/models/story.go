@@ -1,17 +1,22 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Story struct {
-	ID          string    `json:"id" gorm:"primaryKey"`
-	DocumentID  string    `json:"document_id"`
-	Guid        string    `json:"guid"`
-	Title       string    `json:"title"`
-	Content     string    `json:"content" gorm:"type:text"`
-	ContentHash string    `json:"content_hash" gorm:"index"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Document    Document  `json:"document" gorm:"foreignKey:DocumentID"`
+	ID          string         `json:"id" gorm:"primaryKey"`
+	DocumentID  string         `json:"document_id"`
+	Guid        string         `json:"guid"`
+	Title       string         `json:"title"`
+	Content     string         `json:"content" gorm:"type:text"`
+	ContentHash string         `json:"content_hash" gorm:"index"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"` // 移入回收站的时间，为空表示未删除
+	Document    Document       `json:"document" gorm:"foreignKey:DocumentID"`
 }
 
 // TableName 指定表名
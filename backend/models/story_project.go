@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// 故事项目的阶段
+const (
+	StageOutline   = "outline"   // 已有故事前提，等待生成大纲
+	StageWriting   = "writing"   // 大纲已生成，按顺序生成章节
+	StageCompleted = "completed" // 全部章节已生成并保存为故事
+)
+
+// StoryProject 引导式故事创作项目：故事前提 → 大纲 → 逐章生成，生成内容保存在项目的对话中
+type StoryProject struct {
+	ID                string         `json:"id" gorm:"primaryKey"`
+	ConversationID    string         `json:"conversation_id" gorm:"index"`
+	Title             string         `json:"title"`
+	Premise           string         `json:"premise" gorm:"type:text"`
+	Model             string         `json:"model"`
+	Stage             string         `json:"stage"`
+	Outline           string         `json:"outline" gorm:"type:text"`
+	OutlineDocumentID string         `json:"outline_document_id"`
+	ChapterCount      int            `json:"chapter_count"`
+	StoryID           string         `json:"story_id"` // 完成后保存的故事ID
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	Chapters          []StoryChapter `json:"chapters" gorm:"foreignKey:ProjectID"`
+}
+
+// TableName 指定表名
+func (StoryProject) TableName() string {
+	return "story_projects"
+}
+
+// StoryChapter 故事项目中已生成的章节
+type StoryChapter struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ProjectID  string    `json:"project_id" gorm:"uniqueIndex:idx_story_chapter"`
+	Number     int       `json:"number" gorm:"uniqueIndex:idx_story_chapter"` // 章节序号，从1开始
+	DocumentID string    `json:"document_id"`                                 // 章节正文所在的助手文档
+	Summary    string    `json:"summary" gorm:"type:text"`                    // 章节摘要，作为后续章节的上下文
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (StoryChapter) TableName() string {
+	return "story_chapters"
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// ConversationSummary 对话滚动摘要，概括分支从起点到指定文档（含）的全部内容
+// 以被概括的最后一条文档为主键，共享前缀的分支可以复用同一份摘要
+type ConversationSummary struct {
+	DocumentID     string    `json:"document_id" gorm:"primaryKey"` // 摘要覆盖到的最后一条文档ID
+	ConversationID string    `json:"conversation_id" gorm:"index"`  // 所属对话ID
+	Content        string    `json:"content" gorm:"type:text"`      // 摘要内容
+	Model          string    `json:"model"`                         // 生成摘要使用的模型
+	CreatedAt      time.Time `json:"created_at"`                    // 创建时间
+	UpdatedAt      time.Time `json:"updated_at"`                    // 更新时间
+}
+
+// TableName 指定表名
+func (ConversationSummary) TableName() string {
+	return "conversation_summaries"
+}
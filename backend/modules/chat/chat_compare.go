@@ -0,0 +1,245 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"grandma/backend/models"
+	"grandma/backend/services"
+	"grandma/backend/utils"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const maxCompareModels = 4 // 一次对比最多同时请求的模型数
+
+// 多模型对比流式事件类型
+const (
+	compareEventDelta    = "delta"
+	compareEventDone     = "done"
+	compareEventComplete = "complete"
+)
+
+// CompareModels 将同一条用户消息同时发送给多个模型，各模型的回复保存为同一父文档下的兄弟文档：
+// 第一个模型的回复作为原始文档接入活动分支，其余作为它的候选版本，之后可通过选用候选版本的接口选择继续使用哪一个
+// emit 接收各模型交错的增量内容和完成事件，会被串行调用
+func (s *ChatService) CompareModels(ctx context.Context, req *models.ChatRequest, emit func(models.CompareEvent)) (*models.CompareResponse, error) {
+	// 先获取所有模型的provider，避免部分模型不可用时留下不完整的对比
+	providers := make([]services.ChatProvider, len(req.Models))
+	for i, model := range req.Models {
+		provider, err := services.GetProvider(
+			model,
+			s.config.OpenAIAPIKey,
+			s.config.OpenAIBaseURL,
+			s.config.AnthropicAPIKey,
+			s.config.AnthropicBaseURL,
+		)
+		if err != nil {
+			return nil, err
+		}
+		providers[i] = provider
+	}
+
+	conversation, err := s.openConversation(req)
+	if err != nil {
+		return nil, err
+	}
+	defer s.unlockConversation(conversation.ID)
+
+	// 检查用户输入，命中的内容在发送和保存之前遮盖
+	inputMatches, err := s.checkInput(conversation.ID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// 为当前消息预留token预算，各模型按各自的预算构建上下文
+	reservedTokens := 0
+	for _, msg := range req.Messages {
+		reservedTokens += services.EstimateTokens(msg.Content) + messageTokenOverhead
+	}
+	apiMessages := make([][]models.Message, len(req.Models))
+	for i, model := range req.Models {
+		apiMessages[i] = append(s.buildContext(conversation.ID, conversation.HeadDocumentID, model, reservedTokens), req.Messages...)
+	}
+
+	userDocID, err := s.saveUserMessage(req, conversation.ID, conversation.HeadDocumentID)
+	if err != nil {
+		return nil, err
+	}
+	parentID := conversation.HeadDocumentID
+	if userDocID != "" {
+		parentID = userDocID
+		s.saveInterventions(buildInterventions(conversation.ID, userDocID, models.SafetyStageInput, s.ageBand(conversation.ID), inputMatches))
+	}
+
+	// 创建各模型的助手文档（空内容）
+	documentIDs := make([]string, len(req.Models))
+	for i, model := range req.Models {
+		documentIDs[i] = utils.GenerateDocumentID()
+		doc := &models.Document{
+			ID:             documentIDs[i],
+			ConversationID: conversation.ID,
+			Role:           "assistant",
+			Content:        "",
+			Model:          model,
+			ParentID:       parentID,
+		}
+		if i > 0 {
+			doc.AlternativeOf = documentIDs[0]
+		}
+		if err := s.documentRepo.Create(doc); err != nil {
+			return nil, err
+		}
+	}
+
+	var emitMu sync.Mutex
+	safeEmit := func(event models.CompareEvent) {
+		emitMu.Lock()
+		defer emitMu.Unlock()
+		emit(event)
+	}
+
+	// 并发请求各模型，与SendMessage一致，流式响应出错时仍保留已接收的内容
+	results := make([]models.ChatResponse, len(req.Models))
+	var wg sync.WaitGroup
+	for i := range req.Models {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			model, documentID := req.Models[i], documentIDs[i]
+			writer := &compareWriter{model: model, documentID: documentID, emit: safeEmit}
+			content, result, streamErr := s.streamToDocument(ctx, providers[i], model, conversation.ID, apiMessages[i], documentID, writer)
+			if streamErr != nil {
+				fmt.Printf("[chat_service CompareModels] Model %s stream failed: %v\n", model, streamErr)
+			}
+			results[i] = *buildResponse(ctx, conversation.ID, userDocID, documentID, model, content, result, streamErr)
+			results[i].ReadingLevel = s.checkReadingLevel(conversation.ID, documentID)
+			safeEmit(models.CompareEvent{Type: compareEventDone, Model: model, DocumentID: documentID, Result: &results[i]})
+		}(i)
+	}
+	wg.Wait()
+
+	// 第一个模型的回复接入活动分支
+	if err := s.appendToBranch(conversation.ID, documentIDs[0]); err != nil {
+		return nil, err
+	}
+	for _, documentID := range documentIDs {
+		s.publishCompleted(conversation.ID, documentID)
+	}
+
+	return &models.CompareResponse{
+		ConversationID: conversation.ID,
+		UserDocumentID: userDocID,
+		OriginalID:     documentIDs[0],
+		Results:        results,
+	}, nil
+}
+
+// compareWriter 将单个模型的流式内容包装为带模型标记的增量事件
+type compareWriter struct {
+	model      string
+	documentID string
+	emit       func(models.CompareEvent)
+}
+
+func (w *compareWriter) Write(p []byte) (int, error) {
+	w.emit(models.CompareEvent{Type: compareEventDelta, Model: w.model, DocumentID: w.documentID, Content: string(p)})
+	return len(p), nil
+}
+
+// validateCompareRequest 校验多模型对比请求，未指定model时使用第一个对比模型
+func validateCompareRequest(req *models.ChatRequest) error {
+	if len(req.Models) < 2 {
+		return errors.New("at least 2 models are required for compare")
+	}
+	if len(req.Models) > maxCompareModels {
+		return fmt.Errorf("at most %d models can be compared", maxCompareModels)
+	}
+	// 别名按实际使用的服务商和模型比较，如 openai 和 gpt-4 是同一个模型
+	seen := make(map[string]string)
+	for _, model := range req.Models {
+		if model == "" {
+			return errors.New("models must be unique and non-empty")
+		}
+		key := model
+		if provider, resolved := services.ResolveModel(model); provider != "" {
+			key = provider + "/" + resolved
+		}
+		if previous, ok := seen[key]; ok {
+			if previous == model {
+				return errors.New("models must be unique and non-empty")
+			}
+			return fmt.Errorf("models %s and %s are the same model", previous, model)
+		}
+		seen[key] = model
+	}
+	if len(req.Messages) == 0 {
+		return errors.New("messages is required")
+	}
+	if req.Model == "" {
+		req.Model = req.Models[0]
+	}
+	return nil
+}
+
+// compare 处理多模型对比请求，流式模式下以SSE事件交错返回各模型的内容
+func (h *ChatHandler) compare(c *gin.Context, req *models.ChatRequest) {
+	emit := func(models.CompareEvent) {}
+	if req.IsStream() {
+		setStreamHeaders(c)
+		writer := &streamWriter{writer: c.Writer}
+		emit = func(event models.CompareEvent) {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(writer, "data: %s\n\n", data)
+		}
+	}
+
+	// 与Chat一致，使用独立的context，客户端断开连接后仍继续生成
+	response, err := h.chatService.CompareModels(context.Background(), req, emit)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+			return
+		}
+		// 同一对话已有进行中的回复
+		if errors.Is(err, ErrConversationBusy) {
+			c.JSON(http.StatusConflict, gin.H{"error": "conversation_busy"})
+			return
+		}
+		// 用户输入命中了内容安全拦截规则
+		if errors.Is(err, ErrContentBlocked) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "content_blocked"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !req.IsStream() {
+		// 所有模型都出错时返回 502，部分模型出错时各自的结果中包含错误信息
+		if allFailed(response.Results) {
+			c.JSON(http.StatusBadGateway, response)
+			return
+		}
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	emit(models.CompareEvent{Type: compareEventComplete, Compare: response})
+}
+
+// allFailed 是否所有模型的回复都出错
+func allFailed(results []models.ChatResponse) bool {
+	for _, result := range results {
+		if result.FinishReason != "error" {
+			return false
+		}
+	}
+	return len(results) > 0
+}
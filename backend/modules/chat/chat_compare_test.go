@@ -0,0 +1,30 @@
+package chat
+
+import (
+	"grandma/backend/models"
+	"testing"
+)
+
+func TestValidateCompareRequestModels(t *testing.T) {
+	cases := []struct {
+		models []string
+		valid  bool
+	}{
+		{[]string{"openai", "claude"}, true},
+		{[]string{"openai", "openai"}, false},
+		{[]string{"openai", "gpt-4"}, false},
+		{[]string{"anthropic", "claude"}, false},
+		{[]string{"openai", ""}, false},
+		{[]string{"openai"}, false},
+	}
+	for _, c := range cases {
+		req := &models.ChatRequest{Models: c.models, Messages: []models.Message{{Role: "user", Content: "你好"}}}
+		err := validateCompareRequest(req)
+		if (err == nil) != c.valid {
+			t.Errorf("validateCompareRequest(%v) = %v, want valid %v", c.models, err, c.valid)
+		}
+		if err == nil && req.Model != c.models[0] {
+			t.Errorf("model = %q, want %q", req.Model, c.models[0])
+		}
+	}
+}
@@ -1,21 +1,37 @@
 package chat
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"grandma/backend/events"
 	"grandma/backend/models"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type ChatHandler struct {
-	chatService *ChatService
+	chatService    *ChatService
+	events         *events.Hub
+	allowedOrigins map[string]bool // 除同源页面外允许建立WebSocket连接的来源
 }
 
-func NewChatHandler(chatService *ChatService) *ChatHandler {
+// NewChatHandler allowedOrigins 为允许建立WebSocket连接的来源（如 http://localhost:5173），"*" 表示不限制
+func NewChatHandler(chatService *ChatService, hub *events.Hub, allowedOrigins []string) *ChatHandler {
+	origins := make(map[string]bool)
+	for _, origin := range allowedOrigins {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			origins[origin] = true
+		}
+	}
 	return &ChatHandler{
-		chatService: chatService,
+		chatService:    chatService,
+		events:         hub,
+		allowedOrigins: origins,
 	}
 }
 
@@ -26,25 +42,180 @@ func (h *ChatHandler) Chat(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := ValidateChatRequest(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	// 设置流式响应头
-	c.Header("Content-Type", "text/event-stream")
-	c.Header("Cache-Control", "no-cache")
-	c.Header("Connection", "keep-alive")
-	c.Header("Access-Control-Allow-Origin", "*")
-	c.Header("Access-Control-Allow-Headers", "Content-Type")
+	if req.Type == models.ChatTypeCompare {
+		h.compare(c, &req)
+		return
+	}
+
+	// 非流式模式：走相同的保存流程，但不向客户端输出增量内容，结束后返回完整的JSON结果
+	var writer io.Writer = io.Discard
+	if req.IsStream() {
+		setStreamHeaders(c)
+		// 创建writer来包装响应以支持流式输出
+		writer = &streamWriter{writer: c.Writer}
+	}
+
+	// 发送消息（或续写文档）并获取响应
+	// 使用独立的context：客户端断开连接后仍继续生成，保证完整的回复被保存
+	response, err := h.chatService.Chat(context.Background(), &req, writer)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Conversation or document not found"})
+			return
+		}
+		// 同一对话已有进行中的回复
+		if errors.Is(err, ErrConversationBusy) {
+			c.JSON(http.StatusConflict, gin.H{"error": "conversation_busy"})
+			return
+		}
+		// 用户输入命中了内容安全拦截规则
+		if errors.Is(err, ErrContentBlocked) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "content_blocked"})
+			return
+		}
+		// 斜杠命令保存的故事已存在
+		if err.Error() == "duplicate_story" {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		switch err.Error() {
+		case "not_assistant_document", "document_id_required", "conversation_id_required", "no_assistant_document":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 在服务端执行的斜杠命令，流式请求同样返回JSON结果
+	if response.Command != nil {
+		c.JSON(http.StatusOK, response.Command)
+		return
+	}
+	if !req.IsStream() {
+		// 模型服务出错：已生成的部分内容已保存，与文档ID一起返回
+		if response.FinishReason == "error" {
+			c.JSON(http.StatusBadGateway, response)
+			return
+		}
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	writeMetadata(c, writer, response.ConversationID, response.DocumentID)
+}
+
+// Regenerate 重新生成助手回复，结果作为原始文档的候选版本保存
+func (h *ChatHandler) Regenerate(c *gin.Context) {
+	id := c.Param("id")
+	var req models.RegenerateRequest
+	// 请求体可选，未指定模型时沿用原始文档的模型
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	setStreamHeaders(c)
+	writer := &streamWriter{writer: c.Writer}
 
-	// 创建writer来包装响应以支持流式输出
+	response, err := h.chatService.RegenerateDocument(context.Background(), id, req.Model, writer)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+			return
+		}
+		// 同一对话已有进行中的回复
+		if errors.Is(err, ErrConversationBusy) {
+			c.JSON(http.StatusConflict, gin.H{"error": "conversation_busy"})
+			return
+		}
+		if err.Error() == "not_assistant_document" || err.Error() == "empty_context" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	writeMetadata(c, writer, response.ConversationID, response.DocumentID)
+}
+
+// Resubmit 编辑并重新提交用户消息，在新分支中流式返回助手回复
+func (h *ChatHandler) Resubmit(c *gin.Context) {
+	id := c.Param("id")
+	var req models.ResubmitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	setStreamHeaders(c)
 	writer := &streamWriter{writer: c.Writer}
 
-	// 发送消息并获取响应
-	conversationID, documentID, err := h.chatService.SendMessage(&req, writer)
+	response, err := h.chatService.ResubmitDocument(context.Background(), id, req.Content, req.Model, writer)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+			return
+		}
+		// 同一对话已有进行中的回复
+		if errors.Is(err, ErrConversationBusy) {
+			c.JSON(http.StatusConflict, gin.H{"error": "conversation_busy"})
+			return
+		}
+		// 用户输入命中了内容安全拦截规则
+		if errors.Is(err, ErrContentBlocked) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "content_blocked"})
+			return
+		}
+		if err.Error() == "not_user_document" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 在流式响应结束时，通过特殊标记返回文档ID和对话ID
+	writeMetadata(c, writer, response.ConversationID, response.DocumentID)
+}
+
+// ValidateChatRequest 校验聊天请求并补全默认的请求类型
+func ValidateChatRequest(req *models.ChatRequest) error {
+	if req.Type == "" {
+		req.Type = models.ChatTypeMessage
+	}
+	if req.Type == models.ChatTypeCompare {
+		return validateCompareRequest(req)
+	}
+	if req.Model == "" {
+		return errors.New("model is required")
+	}
+	if req.Type != models.ChatTypeMessage && req.Type != models.ChatTypeContinue {
+		return errors.New("unsupported chat type: " + req.Type)
+	}
+	if req.Type == models.ChatTypeMessage && len(req.Messages) == 0 {
+		return errors.New("messages is required")
+	}
+	return nil
+}
+
+// setStreamHeaders 设置流式响应头
+func setStreamHeaders(c *gin.Context) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("Access-Control-Allow-Origin", "*")
+	c.Header("Access-Control-Allow-Headers", "Content-Type")
+}
+
+// writeMetadata 在流式响应结束时，通过特殊标记返回文档ID和对话ID
+func writeMetadata(c *gin.Context, writer io.Writer, conversationID, documentID string) {
 	// 使用特殊的分隔符来标识这是元数据
 	metadata := fmt.Sprintf("\n\n<GRANDMA_METADATA>{\"conversation_id\":\"%s\",\"document_id\":\"%s\"}</GRANDMA_METADATA>", conversationID, documentID)
 	writer.Write([]byte(metadata))
@@ -0,0 +1,75 @@
+package chat
+
+import (
+	"grandma/backend/models"
+	"grandma/backend/prompts"
+	"grandma/backend/readability"
+	"log"
+	"strconv"
+)
+
+// targetAge 获取对话的目标读者年龄，未设置时返回0
+func (s *ChatService) targetAge(conversationID string) int {
+	conversation, err := s.conversationRepo.GetMetaByID(conversationID)
+	if err != nil {
+		return 0
+	}
+	return conversation.TargetAge
+}
+
+// applyTargetAge 对话设置了目标年龄时，在最后一条用户消息之后追加阅读难度要求，不修改传入的消息
+func (s *ChatService) applyTargetAge(conversationID string, messages []models.Message) []models.Message {
+	age := s.targetAge(conversationID)
+	if age == 0 {
+		return messages
+	}
+	level := readability.LevelFor(age)
+	instruction := s.prompts.Render(prompts.ReadabilityTargetAge, map[string]string{
+		"age":             strconv.Itoa(age),
+		"sentence_length": strconv.Itoa(int(level.MaxSentenceZh)),
+		"sentence_words":  strconv.Itoa(int(level.MaxSentenceEn)),
+	})
+
+	steered := append([]models.Message{}, messages...)
+	for i := len(steered) - 1; i >= 0; i-- {
+		if steered[i].Role == "user" {
+			steered[i].Content += "\n\n" + instruction
+			break
+		}
+	}
+	return steered
+}
+
+// checkReadingLevel 分析助手回复的阅读难度并保存到文档，对话设置了目标年龄时返回与目标年龄的比较结果
+func (s *ChatService) checkReadingLevel(conversationID, documentID string) *models.ReadingLevelCheck {
+	doc, err := s.documentRepo.GetByID(documentID)
+	if err != nil {
+		log.Printf("[chat_service checkReadingLevel] Failed to get document %s: %v", documentID, err)
+		return nil
+	}
+	report := readability.Analyze(doc.Content)
+
+	var check *models.ReadingLevelCheck
+	if age := s.targetAge(conversationID); age != 0 {
+		check = NewReadingLevelCheck(report, age)
+	}
+	if err := s.documentRepo.UpdateReadingLevel(documentID, report.EstimatedAge, check != nil && check.Exceeds); err != nil {
+		log.Printf("[chat_service checkReadingLevel] Failed to save reading level of %s: %v", documentID, err)
+	}
+	return check
+}
+
+// NewReadingLevelCheck 比较分析结果与目标年龄的阅读难度上限
+func NewReadingLevelCheck(report *readability.Report, targetAge int) *models.ReadingLevelCheck {
+	issues := readability.Check(report, readability.LevelFor(targetAge))
+	check := &models.ReadingLevelCheck{
+		TargetAge:    targetAge,
+		EstimatedAge: report.EstimatedAge,
+		Exceeds:      len(issues) > 0,
+		Issues:       make([]models.ReadingIssue, len(issues)),
+	}
+	for i, issue := range issues {
+		check.Issues[i] = models.ReadingIssue{Metric: issue.Metric, Value: issue.Value, Limit: issue.Limit}
+	}
+	return check
+}
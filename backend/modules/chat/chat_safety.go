@@ -0,0 +1,202 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"grandma/backend/models"
+	"grandma/backend/prompts"
+	"grandma/backend/safety"
+	"grandma/backend/services"
+	"io"
+	"log"
+	"strings"
+)
+
+// maxSafetyRewrites 模型输出命中需要重写的规则时最多要求模型重写的次数，超过后按拦截处理
+const maxSafetyRewrites = 1
+
+// ErrContentBlocked 用户输入命中了拦截规则
+var ErrContentBlocked = errors.New("content_blocked")
+
+// ageBand 获取对话内容安全过滤使用的年龄段
+func (s *ChatService) ageBand(conversationID string) string {
+	band := ""
+	if conversation, err := s.conversationRepo.GetMetaByID(conversationID); err == nil {
+		band = conversation.AgeBand
+	}
+	return s.safety.Band(band)
+}
+
+// checkInput 检查请求中的用户消息：命中拦截规则时记录并返回 ErrContentBlocked，
+// 其余命中的内容直接遮盖。最后一条消息会保存为文档，返回它的命中，在保存后记录到文档；
+// 其他用户消息不保存，命中时连同原始内容直接记录
+func (s *ChatService) checkInput(conversationID string, req *models.ChatRequest) ([]safety.Match, error) {
+	if !s.safety.Enabled() {
+		return nil, nil
+	}
+	band := s.ageBand(conversationID)
+
+	var lastMatches []safety.Match
+	var unsaved []models.SafetyIntervention
+	for i := range req.Messages {
+		msg := &req.Messages[i]
+		if msg.Role != "user" {
+			continue
+		}
+		matches := s.safety.Check(msg.Content, band)
+		if safety.MostSevere(matches) == safety.ActionBlock {
+			s.saveInterventions(unsavedInterventions(conversationID, band, msg.Content, matches))
+			return nil, ErrContentBlocked
+		}
+		if i == len(req.Messages)-1 {
+			lastMatches = matches
+		} else {
+			unsaved = append(unsaved, unsavedInterventions(conversationID, band, msg.Content, matches)...)
+		}
+		msg.Content = safety.Mask(msg.Content, matches)
+	}
+	s.saveInterventions(unsaved)
+	return lastMatches, nil
+}
+
+// generateSafely 流式生成时检查模型输出：需要遮盖的内容在发给客户端和保存之前遮盖；
+// 命中需要重写的规则时停止生成，从已输出的内容继续并要求模型避开相关内容；命中拦截规则时停止生成并追加提示
+func (s *ChatService) generateSafely(ctx context.Context, provider services.ChatProvider, model, conversationID, documentID string, messages []models.Message, writer io.Writer) (*services.StreamResult, error) {
+	if !s.safety.Enabled() {
+		return provider.ChatStream(ctx, messages, writer)
+	}
+	band := s.ageBand(conversationID)
+
+	var forwarded strings.Builder
+	attemptMessages := messages
+	for attempt := 0; ; attempt++ {
+		guard := s.safety.NewGuard(band)
+		genCtx, cancel := context.WithCancel(ctx)
+		guardWriter := &guardWriter{guard: guard, writer: writer, forwarded: &forwarded, cancel: cancel}
+		result, err := provider.ChatStream(genCtx, attemptMessages, guardWriter)
+		cancel()
+		guardWriter.flush()
+		s.saveInterventions(buildInterventions(conversationID, documentID, models.SafetyStageOutput, band, guard.Matches()))
+
+		halted := guard.Halted()
+		if halted == nil {
+			return result, err
+		}
+		log.Printf("[chat_service generateSafely] Document %s hit rule %s (%s)", documentID, halted.RuleID, halted.Action)
+		if result == nil {
+			result = &services.StreamResult{}
+		}
+
+		if halted.Action == safety.ActionBlock || attempt >= maxSafetyRewrites {
+			writer.Write([]byte(s.prompts.Render(prompts.SafetyBlockedNotice, nil)))
+			result.FinishReason = "content_filter"
+			return result, nil
+		}
+
+		// 要求模型从已输出的安全内容继续，避开命中的内容
+		instruction := s.prompts.Render(prompts.SafetyRewrite, map[string]string{
+			"audience": safety.Audience(band),
+			"category": halted.Category,
+		})
+		attemptMessages = safeRetryMessages(model, messages, forwarded.String(), instruction, s.prompts.Render(prompts.ChatContinuation, nil))
+	}
+}
+
+// safeRetryMessages 构建重写请求的消息：在最后一条用户消息后追加安全要求，并从已输出的内容继续
+func safeRetryMessages(model string, messages []models.Message, forwarded, instruction, continuationPrompt string) []models.Message {
+	retry := append([]models.Message{}, messages...)
+	for i := len(retry) - 1; i >= 0; i-- {
+		if retry[i].Role == "user" {
+			retry[i].Content += "\n\n" + instruction
+			break
+		}
+	}
+	if forwarded == "" {
+		return retry
+	}
+	// 续写请求以助手预填充结尾时，将已输出的内容接在预填充之后
+	if last := &retry[len(retry)-1]; last.Role == "assistant" {
+		last.Content += forwarded
+		return retry
+	}
+	return services.BuildContinuationMessages(model, retry, forwarded, continuationPrompt)
+}
+
+// saveInterventions 保存处理记录，失败时只记录日志
+func (s *ChatService) saveInterventions(interventions []models.SafetyIntervention) {
+	if err := s.safetyRepo.CreateInterventions(interventions); err != nil {
+		log.Printf("[chat_service saveInterventions] Failed to save interventions: %v", err)
+	}
+}
+
+// buildInterventions 将命中列表按规则合并为处理记录
+func buildInterventions(conversationID, documentID, stage, band string, matches []safety.Match) []models.SafetyIntervention {
+	var interventions []models.SafetyIntervention
+	index := make(map[string]int)
+	for _, match := range matches {
+		i, ok := index[match.RuleID]
+		if !ok {
+			i = len(interventions)
+			index[match.RuleID] = i
+			interventions = append(interventions, models.SafetyIntervention{
+				ConversationID: conversationID,
+				DocumentID:     documentID,
+				Stage:          stage,
+				AgeBand:        band,
+				RuleID:         match.RuleID,
+				Category:       match.Category,
+				Action:         match.Action,
+			})
+		}
+		intervention := &interventions[i]
+		intervention.Count++
+		if !strings.Contains(","+intervention.Matched+",", ","+match.Text+",") {
+			if intervention.Matched != "" {
+				intervention.Matched += ","
+			}
+			intervention.Matched += match.Text
+		}
+	}
+	return interventions
+}
+
+// unsavedInterventions 没有保存为文档的用户输入的处理记录，同时记录原始输入
+func unsavedInterventions(conversationID, band, content string, matches []safety.Match) []models.SafetyIntervention {
+	interventions := buildInterventions(conversationID, "", models.SafetyStageInput, band, matches)
+	for i := range interventions {
+		interventions[i].Content = content
+	}
+	return interventions
+}
+
+// guardWriter 在写给下游之前经过内容安全检查，命中需要停止的规则时取消生成
+type guardWriter struct {
+	guard     *safety.Guard
+	writer    io.Writer
+	forwarded *strings.Builder
+	cancel    context.CancelFunc
+}
+
+func (w *guardWriter) Write(p []byte) (int, error) {
+	if err := w.forward(w.guard.Write(string(p))); err != nil {
+		return 0, err
+	}
+	if w.guard.Halted() != nil {
+		w.cancel()
+	}
+	return len(p), nil
+}
+
+// flush 输出检查器中保留的剩余内容
+func (w *guardWriter) flush() {
+	w.forward(w.guard.Flush())
+}
+
+func (w *guardWriter) forward(text string) error {
+	if text == "" {
+		return nil
+	}
+	w.forwarded.WriteString(text)
+	_, err := w.writer.Write([]byte(text))
+	return err
+}
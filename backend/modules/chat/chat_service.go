@@ -1,84 +1,286 @@
 package chat
 
 import (
+	"context"
+	"errors"
+	"grandma/backend/events"
 	"grandma/backend/models"
+	"grandma/backend/modules/command"
+	"grandma/backend/prompts"
 	"grandma/backend/repository"
+	"grandma/backend/safety"
 	"grandma/backend/services"
 	"grandma/backend/utils"
 	"io"
+	"log"
 	"strings"
+	"sync"
 )
 
 type ChatService struct {
 	conversationRepo *repository.ConversationRepository
 	documentRepo     *repository.DocumentRepository
+	summaryRepo      *repository.SummaryRepository
+	safetyRepo       *repository.SafetyRepository
 	config           *ChatConfig
+	events           *events.Hub
+	prompts          *prompts.Store
+	safety           *safety.Filter
+	commandService   *command.CommandService
+	summarizing      sync.Map // 正在后台生成的摘要，键为摘要覆盖到的文档ID
+
+	busyMu sync.Mutex
+	busy   map[string]bool // 正在生成回复的对话ID
 }
 
+// ErrConversationBusy 对话中已有进行中的请求
+var ErrConversationBusy = errors.New("conversation_busy")
+
 type ChatConfig struct {
-	OpenAIAPIKey     string
-	OpenAIBaseURL    string
-	AnthropicAPIKey  string
-	AnthropicBaseURL string
+	OpenAIAPIKey       string
+	OpenAIBaseURL      string
+	AnthropicAPIKey    string
+	AnthropicBaseURL   string
+	ContextTokenBudget int // 历史上下文的token预算，为0时使用各模型的默认预算
 }
 
-func NewChatService(conversationRepo *repository.ConversationRepository, documentRepo *repository.DocumentRepository, config *ChatConfig) *ChatService {
+func NewChatService(conversationRepo *repository.ConversationRepository, documentRepo *repository.DocumentRepository, summaryRepo *repository.SummaryRepository, safetyRepo *repository.SafetyRepository, config *ChatConfig, hub *events.Hub, promptStore *prompts.Store, safetyFilter *safety.Filter, commandService *command.CommandService) *ChatService {
 	return &ChatService{
 		conversationRepo: conversationRepo,
 		documentRepo:     documentRepo,
+		summaryRepo:      summaryRepo,
+		safetyRepo:       safetyRepo,
 		config:           config,
+		events:           hub,
+		prompts:          promptStore,
+		safety:           safetyFilter,
+		commandService:   commandService,
+		busy:             make(map[string]bool),
+	}
+}
+
+// lockConversation 标记对话正在生成回复。同一对话同时只允许一个请求读取历史并写入文档，
+// 对话已有进行中的请求时返回 ErrConversationBusy，由调用方在请求结束后调用 unlockConversation
+func (s *ChatService) lockConversation(conversationID string) error {
+	s.busyMu.Lock()
+	defer s.busyMu.Unlock()
+	if s.busy[conversationID] {
+		return ErrConversationBusy
 	}
+	s.busy[conversationID] = true
+	return nil
+}
+
+// unlockConversation 释放对话
+func (s *ChatService) unlockConversation(conversationID string) {
+	s.busyMu.Lock()
+	defer s.busyMu.Unlock()
+	delete(s.busy, conversationID)
 }
 
-// SendMessage 发送消息并获取流式响应
-func (s *ChatService) SendMessage(req *models.ChatRequest, writer io.Writer) (string, string, error) {
-	var conversationID string
-	var conversation *models.Conversation
-	var err error
+// Chat 先处理请求中的斜杠命令，再根据请求类型发送新消息或续写已有文档
+func (s *ChatService) Chat(ctx context.Context, req *models.ChatRequest, writer io.Writer) (*models.ChatResponse, error) {
+	// 斜杠命令：提示词类命令改写请求后继续聊天，服务端执行的命令直接返回结果，不生成回复
+	result, err := s.commandService.Apply(req)
+	if err != nil {
+		return nil, err
+	}
+	if result != nil {
+		return &models.ChatResponse{ConversationID: result.ConversationID, Command: result}, nil
+	}
 
+	if req.Type == models.ChatTypeContinue {
+		return s.ContinueDocument(ctx, req, writer)
+	}
+	return s.SendMessage(ctx, req, writer)
+}
+
+// SendMessage 发送消息并获取流式响应，返回本次聊天的结果
+func (s *ChatService) SendMessage(ctx context.Context, req *models.ChatRequest, writer io.Writer) (*models.ChatResponse, error) {
+	conversation, err := s.openConversation(req)
+	if err != nil {
+		return nil, err
+	}
+	defer s.unlockConversation(conversation.ID)
+
+	// 新消息接在当前活动分支的最后一条文档之后
+	return s.sendOnBranch(ctx, req, conversation.ID, conversation.HeadDocumentID, writer)
+}
+
+// openConversation 获取请求指定的对话并加锁，未指定对话ID时创建新对话
+// 调用方在请求结束后需要调用 unlockConversation
+func (s *ChatService) openConversation(req *models.ChatRequest) (*models.Conversation, error) {
 	// 如果没有提供对话ID，创建新对话
 	if req.ConversationID == "" {
-		conversationID = utils.GenerateConversationID()
-		conversation = &models.Conversation{
-			ID:          conversationID,
-			Title:       s.generateTitle(req.Messages),
-			DocumentIDs: "",
+		id := utils.GenerateConversationID()
+		// 新对话在创建前加锁，避免其他请求在首条消息保存前写入
+		if err := s.lockConversation(id); err != nil {
+			return nil, err
 		}
-		err = s.conversationRepo.Create(conversation)
+		conversation, err := s.createConversation(id, req.Messages)
 		if err != nil {
-			return "", "", err
+			s.unlockConversation(id)
+			return nil, err
 		}
-	} else {
-		conversationID = req.ConversationID
-		conversation, err = s.conversationRepo.GetByID(conversationID)
+		return conversation, nil
+	}
+
+	// 加锁后再读取活动分支，保证并发发送时不会接在同一条文档之后
+	if err := s.lockConversation(req.ConversationID); err != nil {
+		return nil, err
+	}
+	conversation, err := s.conversationRepo.GetByID(req.ConversationID)
+	if err != nil {
+		s.unlockConversation(req.ConversationID)
+		return nil, err
+	}
+	return conversation, nil
+}
+
+// CreateConversation 创建新对话，以消息中的第一条用户消息作为标题
+func (s *ChatService) CreateConversation(messages []models.Message) (*models.Conversation, error) {
+	return s.createConversation(utils.GenerateConversationID(), messages)
+}
+
+func (s *ChatService) createConversation(id string, messages []models.Message) (*models.Conversation, error) {
+	conversation := &models.Conversation{
+		ID:    id,
+		Title: s.generateTitle(messages),
+	}
+	if err := s.conversationRepo.Create(conversation); err != nil {
+		return nil, err
+	}
+	s.events.Publish(events.Event{
+		Type:           events.TypeConversationCreated,
+		ConversationID: conversation.ID,
+		Data:           map[string]string{"title": conversation.Title},
+	})
+	return conversation, nil
+}
+
+// ContinueDocument 续写已有的助手文档：模型从文档末尾继续生成，只流式返回新增内容，并追加到同一文档
+// 未指定文档ID时续写对话活动分支的最后一条文档；文档已选用候选版本时续写该候选版本
+func (s *ChatService) ContinueDocument(ctx context.Context, req *models.ChatRequest, writer io.Writer) (*models.ChatResponse, error) {
+	documentID := req.DocumentID
+	if documentID == "" {
+		if req.ConversationID == "" {
+			return nil, errors.New("document_id_required")
+		}
+		conversation, err := s.conversationRepo.GetByID(req.ConversationID)
 		if err != nil {
-			return "", "", err
+			return nil, err
 		}
+		documentID = conversation.HeadDocumentID
+	}
+	if documentID == "" {
+		return nil, errors.New("not_assistant_document")
 	}
 
-	// 构建API调用的消息数组
-	var apiMessages []models.Message
-
-	// 如果提供了对话ID，从数据库加载历史消息
-	// 优化：只加载最近的对话上下文，而不是全部历史
-	if req.ConversationID != "" {
-		// 获取对话的历史文档（只获取最近的20条，避免上下文过长）
-		historyDocs, err := s.documentRepo.GetLatestDocumentsByConversationID(conversationID, 20)
-		if err == nil && len(historyDocs) > 0 {
-			// 反转顺序，使其按时间正序排列（最新的在最后）
-			for i, j := 0, len(historyDocs)-1; i < j; i, j = i+1, j-1 {
-				historyDocs[i], historyDocs[j] = historyDocs[j], historyDocs[i]
-			}
-			// 将历史文档转换为消息格式
-			for _, doc := range historyDocs {
-				apiMessages = append(apiMessages, models.Message{
-					Role:    doc.Role,
-					Content: doc.Content,
-				})
-			}
+	doc, err := s.documentRepo.GetByID(documentID)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Role != "assistant" {
+		return nil, errors.New("not_assistant_document")
+	}
+	if err := s.lockConversation(doc.ConversationID); err != nil {
+		return nil, err
+	}
+	defer s.unlockConversation(doc.ConversationID)
+	if doc.ActiveAlternativeID != "" {
+		if alternative, err := s.documentRepo.GetByID(doc.ActiveAlternativeID); err == nil {
+			doc = alternative
 		}
 	}
 
+	provider, err := services.GetProvider(
+		req.Model,
+		s.config.OpenAIAPIKey,
+		s.config.OpenAIBaseURL,
+		s.config.AnthropicAPIKey,
+		s.config.AnthropicBaseURL,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// 上下文为文档之前的分支历史，已有内容作为续写的起点
+	reservedTokens := services.EstimateTokens(doc.Content) + messageTokenOverhead*2
+	history := s.buildContext(doc.ConversationID, doc.ParentID, req.Model, reservedTokens)
+	apiMessages := services.BuildContinuationMessages(req.Model, history, doc.Content, s.prompts.Render(prompts.ChatContinuation, nil))
+
+	// 新增内容追加到同一文档，与SendMessage一致，流式响应出错时仍保留已接收的内容
+	content, result, streamErr := s.streamToDocument(ctx, provider, req.Model, doc.ConversationID, apiMessages, doc.ID, writer)
+	s.publishCompleted(doc.ConversationID, doc.ID)
+
+	response := buildResponse(ctx, doc.ConversationID, "", doc.ID, req.Model, content, result, streamErr)
+	response.ReadingLevel = s.checkReadingLevel(doc.ConversationID, doc.ID)
+	return response, nil
+}
+
+// ResubmitDocument 编辑并重新提交用户消息：保留原消息，以编辑后的内容在原消息的父文档下开启新分支，
+// 并通过正常的聊天流程流式返回新的助手回复。model 为空时沿用原消息的模型
+func (s *ChatService) ResubmitDocument(ctx context.Context, documentID, content, model string, writer io.Writer) (*models.ChatResponse, error) {
+	doc, err := s.documentRepo.GetByID(documentID)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Role != "user" {
+		return nil, errors.New("not_user_document")
+	}
+	if err := s.lockConversation(doc.ConversationID); err != nil {
+		return nil, err
+	}
+	defer s.unlockConversation(doc.ConversationID)
+	if model == "" {
+		model = doc.Model
+	}
+
+	req := &models.ChatRequest{
+		ConversationID: doc.ConversationID,
+		Model:          model,
+		Messages: []models.Message{
+			{Role: "user", Content: content},
+		},
+	}
+	return s.sendOnBranch(ctx, req, doc.ConversationID, doc.ParentID, writer)
+}
+
+// sendOnBranch 将请求中的消息接在parentID之后保存并流式获取助手回复，parentID为空表示从对话开头开始
+func (s *ChatService) sendOnBranch(ctx context.Context, req *models.ChatRequest, conversationID, parentID string, writer io.Writer) (*models.ChatResponse, error) {
+	// 为当前消息预留token预算
+	reservedTokens := 0
+	for _, msg := range req.Messages {
+		reservedTokens += services.EstimateTokens(msg.Content) + messageTokenOverhead
+	}
+
+	// 沿分支从数据库加载历史消息，按模型的token预算截取，过早的历史以摘要代替
+	history := s.buildContext(conversationID, parentID, req.Model, reservedTokens)
+	return s.sendWithHistory(ctx, req, conversationID, parentID, history, writer)
+}
+
+// SendWithContext 以调用方构建的上下文代替分支历史发送消息，消息和回复仍接在对话活动分支之后保存
+// 用于工作流等需要自行组织上下文的场景
+func (s *ChatService) SendWithContext(ctx context.Context, req *models.ChatRequest, history []models.Message, writer io.Writer) (*models.ChatResponse, error) {
+	conversation, err := s.openConversation(req)
+	if err != nil {
+		return nil, err
+	}
+	defer s.unlockConversation(conversation.ID)
+
+	return s.sendWithHistory(ctx, req, conversation.ID, conversation.HeadDocumentID, history, writer)
+}
+
+// sendWithHistory 将请求中的消息接在parentID之后保存，并以history加上请求中的消息作为上下文流式获取助手回复
+func (s *ChatService) sendWithHistory(ctx context.Context, req *models.ChatRequest, conversationID, parentID string, history []models.Message, writer io.Writer) (*models.ChatResponse, error) {
+	// 检查用户输入，命中的内容在发送和保存之前遮盖
+	inputMatches, err := s.checkInput(conversationID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	apiMessages := history
+
 	// 添加当前用户消息
 	for _, msg := range req.Messages {
 		apiMessages = append(apiMessages, models.Message{
@@ -88,28 +290,13 @@ func (s *ChatService) SendMessage(req *models.ChatRequest, writer io.Writer) (st
 	}
 
 	// 保存最后一条用户消息（必须存在）
-	var userDocID string
-	if len(req.Messages) > 0 {
-		lastMsg := req.Messages[len(req.Messages)-1]
-		if lastMsg.Role == "user" {
-			userDocID = utils.GenerateDocumentID()
-			userDoc := &models.Document{
-				ID:             userDocID,
-				ConversationID: conversationID,
-				Role:           "user",
-				Content:        lastMsg.Content,
-				Model:          req.Model,
-			}
-			err = s.documentRepo.Create(userDoc)
-			if err != nil {
-				return "", "", err
-			}
-			// 添加用户文档ID到对话的文档ID列表
-			err = s.conversationRepo.AppendDocumentID(conversationID, userDocID)
-			if err != nil {
-				return "", "", err
-			}
-		}
+	userDocID, err := s.saveUserMessage(req, conversationID, parentID)
+	if err != nil {
+		return nil, err
+	}
+	if userDocID != "" {
+		parentID = userDocID
+		s.saveInterventions(buildInterventions(conversationID, userDocID, models.SafetyStageInput, s.ageBand(conversationID), inputMatches))
 	}
 
 	// 调用大模型API获取流式响应
@@ -121,7 +308,7 @@ func (s *ChatService) SendMessage(req *models.ChatRequest, writer io.Writer) (st
 		s.config.AnthropicBaseURL,
 	)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
 	// 首先创建助手文档（空内容）
@@ -132,27 +319,205 @@ func (s *ChatService) SendMessage(req *models.ChatRequest, writer io.Writer) (st
 		Role:           "assistant",
 		Content:        "",
 		Model:          req.Model,
+		ParentID:       parentID,
 	}
 	err = s.documentRepo.Create(assistantDoc)
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
+	// 流式返回并逐步更新文档
+	content, result, err := s.streamToDocument(ctx, provider, req.Model, conversationID, apiMessages, assistantDocID, writer)
+	response := buildResponse(ctx, conversationID, userDocID, assistantDocID, req.Model, content, result, err)
+	response.ReadingLevel = s.checkReadingLevel(conversationID, assistantDocID)
+
+	// 如果流式响应过程中出现错误（可能是客户端断开连接），
+	// 仍然保存已接收的内容，并继续添加文档ID到对话列表
+	// 这样用户可以切换回对话时看到部分内容
+	// 注意：即使流式响应失败，也要继续处理，确保已保存的内容可以被访问
+	_ = err // 忽略流式响应错误，继续保存已接收的内容
+
+	// 添加助手文档ID到对话的文档ID列表（即使流式响应失败也要添加）
+	errAppend := s.appendToBranch(conversationID, assistantDocID)
+	if errAppend != nil {
+		// 如果添加文档ID失败，记录错误
+		// 但继续执行，确保已保存的内容可以被访问
+		_ = errAppend
+	}
+	s.publishCompleted(conversationID, assistantDocID)
+
+	// 无论流式响应是否成功，都返回成功
+	// 这样即使客户端断开连接，已接收的内容也会被保存并可以被访问
+	return response, nil
+}
+
+// saveUserMessage 将请求中的最后一条用户消息保存为parentID的子文档，并移动活动分支
+// 最后一条消息不是用户消息时不保存，返回空的文档ID
+func (s *ChatService) saveUserMessage(req *models.ChatRequest, conversationID, parentID string) (string, error) {
+	if len(req.Messages) == 0 {
+		return "", nil
+	}
+	lastMsg := req.Messages[len(req.Messages)-1]
+	if lastMsg.Role != "user" {
+		return "", nil
+	}
+
+	userDocID := utils.GenerateDocumentID()
+	userDoc := &models.Document{
+		ID:             userDocID,
+		ConversationID: conversationID,
+		Role:           "user",
+		Content:        lastMsg.Content,
+		Model:          req.Model,
+		ParentID:       parentID,
+	}
+	err := s.documentRepo.Create(userDoc)
+	if err != nil {
+		return "", err
+	}
+	// 添加用户文档ID到对话的文档ID列表，并移动活动分支
+	err = s.appendToBranch(conversationID, userDocID)
+	if err != nil {
+		return "", err
+	}
+	s.events.Publish(events.Event{
+		Type:           events.TypeMessageCreated,
+		ConversationID: conversationID,
+		DocumentID:     userDocID,
+	})
+	return userDocID, nil
+}
+
+// RegenerateDocument 使用相同的上下文重新生成助手回复，结果保存为原始文档的候选版本并设为当前选用版本
+// model 为空时沿用原始文档的模型，返回对话ID和新候选版本的文档ID
+func (s *ChatService) RegenerateDocument(ctx context.Context, documentID, model string, writer io.Writer) (*models.ChatResponse, error) {
+	doc, err := s.documentRepo.GetByID(documentID)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Role != "assistant" {
+		return nil, errors.New("not_assistant_document")
+	}
+	if err := s.lockConversation(doc.ConversationID); err != nil {
+		return nil, err
+	}
+	defer s.unlockConversation(doc.ConversationID)
+
+	// 对候选版本重新生成时，以其原始文档为准
+	original := doc
+	if doc.AlternativeOf != "" {
+		original, err = s.documentRepo.GetByID(doc.AlternativeOf)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if model == "" {
+		model = original.Model
+	}
+
+	// 还原生成原始文档时的上下文：原始文档所在分支中位于其之前的历史
+	apiMessages := s.buildContext(original.ConversationID, original.ParentID, model, 0)
+	if len(apiMessages) == 0 {
+		return nil, errors.New("empty_context")
+	}
+
+	provider, err := services.GetProvider(
+		model,
+		s.config.OpenAIAPIKey,
+		s.config.OpenAIBaseURL,
+		s.config.AnthropicAPIKey,
+		s.config.AnthropicBaseURL,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	alternativeID := utils.GenerateDocumentID()
+	alternative := &models.Document{
+		ID:             alternativeID,
+		ConversationID: original.ConversationID,
+		Role:           "assistant",
+		Content:        "",
+		Model:          model,
+		ParentID:       original.ParentID,
+		AlternativeOf:  original.ID,
+	}
+	err = s.documentRepo.Create(alternative)
+	if err != nil {
+		return nil, err
+	}
+
+	// 与SendMessage一致，流式响应出错时仍保留已接收的内容
+	content, result, streamErr := s.streamToDocument(ctx, provider, model, original.ConversationID, apiMessages, alternativeID, writer)
+
+	err = s.documentRepo.SetActiveAlternative(original.ID, alternativeID)
+	if err != nil {
+		return nil, err
+	}
+	s.publishCompleted(original.ConversationID, alternativeID)
+
+	response := buildResponse(ctx, original.ConversationID, "", alternativeID, model, content, result, streamErr)
+	response.ReadingLevel = s.checkReadingLevel(original.ConversationID, alternativeID)
+	return response, nil
+}
+
+// appendToBranch 将文档ID添加到对话的文档ID列表，并将其设为活动分支的最后一条文档
+func (s *ChatService) appendToBranch(conversationID, documentID string) error {
+	if err := s.conversationRepo.AppendDocumentID(conversationID, documentID); err != nil {
+		return err
+	}
+	return s.conversationRepo.UpdateHeadDocumentID(conversationID, documentID)
+}
+
+// publishCompleted 发布助手回复生成完毕的事件
+func (s *ChatService) publishCompleted(conversationID, documentID string) {
+	s.events.Publish(events.Event{
+		Type:           events.TypeMessageCompleted,
+		ConversationID: conversationID,
+		DocumentID:     documentID,
+	})
+}
+
+// historyToMessages 将按分支倒序的历史文档转换为按时间正序的消息，
+// 已选用候选版本的助手文档使用候选版本的内容
+func (s *ChatService) historyToMessages(historyDocs []models.Document) []models.Message {
+	messages := make([]models.Message, 0, len(historyDocs))
+	for i := len(historyDocs) - 1; i >= 0; i-- {
+		doc := historyDocs[i]
+		content := doc.Content
+		if doc.ActiveAlternativeID != "" {
+			if alternative, err := s.documentRepo.GetByID(doc.ActiveAlternativeID); err == nil {
+				content = alternative.Content
+			}
+		}
+		messages = append(messages, models.Message{
+			Role:    doc.Role,
+			Content: content,
+		})
+	}
+	return messages
+}
+
+// streamToDocument 调用大模型流式接口，将响应写给客户端的同时逐步保存到文档，返回本次生成的完整内容
+// 输出经过对话年龄段的内容安全检查，对话设置了目标年龄时要求模型使用相应难度的语言
+func (s *ChatService) streamToDocument(ctx context.Context, provider services.ChatProvider, model, conversationID string, messages []models.Message, documentID string, writer io.Writer) (string, *services.StreamResult, error) {
+	messages = s.applyTargetAge(conversationID, messages)
+
 	// 创建流式响应收集器，在流式返回时逐步更新文档
 	responseCollector := &responseCollector{
 		writer:       writer,
 		content:      "",
 		documentRepo: s.documentRepo,
-		documentID:   assistantDocID,
+		documentID:   documentID,
 		updateBuffer: "",
 		bufferSize:   0,
 	}
-	err = provider.ChatStream(apiMessages, responseCollector)
+	result, err := s.generateSafely(ctx, provider, model, conversationID, documentID, messages, responseCollector)
 
 	// 无论流式响应是否成功，都要保存剩余的缓冲区内容
 	// 这样即使客户端断开连接，已接收的内容也会被保存
 	if responseCollector.updateBuffer != "" {
-		appendErr := s.documentRepo.AppendContent(assistantDocID, responseCollector.updateBuffer)
+		appendErr := s.documentRepo.AppendContent(documentID, responseCollector.updateBuffer)
 		if appendErr != nil {
 			// 如果保存失败，记录错误但不影响主流程
 			// 因为如果流式响应成功，后续会继续保存
@@ -161,24 +526,34 @@ func (s *ChatService) SendMessage(req *models.ChatRequest, writer io.Writer) (st
 			}
 		}
 	}
-
-	// 如果流式响应过程中出现错误（可能是客户端断开连接），
-	// 仍然保存已接收的内容，并继续添加文档ID到对话列表
-	// 这样用户可以切换回对话时看到部分内容
-	// 注意：即使流式响应失败，也要继续处理，确保已保存的内容可以被访问
-	_ = err // 忽略流式响应错误，继续保存已接收的内容
-
-	// 添加助手文档ID到对话的文档ID列表（即使流式响应失败也要添加）
-	errAppend := s.conversationRepo.AppendDocumentID(conversationID, assistantDocID)
-	if errAppend != nil {
-		// 如果添加文档ID失败，记录错误
-		// 但继续执行，确保已保存的内容可以被访问
-		_ = errAppend
+	// 生成结束后更新搜索索引，流式追加内容时不更新，避免每次追加都重新索引整个文档
+	if indexErr := s.documentRepo.Reindex(documentID); indexErr != nil {
+		log.Printf("[chat_service streamToDocument] Failed to index document %s: %v", documentID, indexErr)
 	}
+	return responseCollector.content, result, err
+}
 
-	// 无论流式响应是否成功，都返回成功
-	// 这样即使客户端断开连接，已接收的内容也会被保存并可以被访问
-	return conversationID, assistantDocID, nil
+// buildResponse 根据流式响应的结果构建聊天结果，流式响应被取消或出错时结束原因分别为 cancelled、error，出错时同时返回错误信息
+func buildResponse(ctx context.Context, conversationID, userDocID, documentID, model, content string, result *services.StreamResult, streamErr error) *models.ChatResponse {
+	response := &models.ChatResponse{
+		ConversationID: conversationID,
+		UserDocumentID: userDocID,
+		DocumentID:     documentID,
+		Content:        content,
+		Model:          model,
+	}
+	if result != nil {
+		response.ProviderModel = result.Model
+		response.Usage = result.Usage
+		response.FinishReason = result.FinishReason
+	}
+	if ctx.Err() != nil {
+		response.FinishReason = "cancelled"
+	} else if streamErr != nil {
+		response.FinishReason = "error"
+		response.Error = streamErr.Error()
+	}
+	return response
 }
 
 // generateTitle 从消息内容生成对话标题
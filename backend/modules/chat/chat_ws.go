@@ -0,0 +1,249 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"grandma/backend/events"
+	"grandma/backend/models"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// WebSocket消息类型
+const (
+	// 客户端发送
+	wsTypeChat        = "chat"        // 发送聊天请求（与 /api/chat 的请求体相同）
+	wsTypeCancel      = "cancel"      // 取消进行中的聊天请求
+	wsTypeSubscribe   = "subscribe"   // 订阅对话事件，conversation_id 为空表示订阅所有对话
+	wsTypeUnsubscribe = "unsubscribe" // 取消订阅对话事件
+	wsTypePing        = "ping"
+
+	// 服务端发送
+	wsTypeDelta     = "delta"     // 流式增量内容
+	wsTypeDone      = "done"      // 聊天请求完成
+	wsTypeCancelled = "cancelled" // 聊天请求已取消，已生成的内容已保存
+	wsTypeError     = "error"
+	wsTypeEvent     = "event" // 服务端事件（标题生成、其他标签页的消息等）
+	wsTypePong      = "pong"
+)
+
+// wsMessage WebSocket消息，客户端和服务端使用同一结构
+type wsMessage struct {
+	Type           string                  `json:"type"`
+	RequestID      string                  `json:"request_id,omitempty"` // 由客户端指定，用于关联同一聊天请求的所有消息
+	ConversationID string                  `json:"conversation_id,omitempty"`
+	DocumentID     string                  `json:"document_id,omitempty"`
+	Chat           *models.ChatRequest     `json:"chat,omitempty"`    // type=chat 时的聊天请求
+	Content        string                  `json:"content,omitempty"` // type=delta 时的增量内容
+	Event          *events.Event           `json:"event,omitempty"`   // type=event 时的事件
+	Command        *models.CommandResponse `json:"command,omitempty"` // type=done 时在服务端执行的斜杠命令的结果
+	Error          string                  `json:"error,omitempty"`
+}
+
+// WebSocket 在一个长连接上复用聊天请求、取消和对话事件
+func (h *ChatHandler) WebSocket(c *gin.Context) {
+	server := websocket.Server{
+		// WebSocket 没有预检请求，任何网页都可以发起连接，只接受同源页面和配置允许的来源
+		Handshake: func(config *websocket.Config, req *http.Request) error {
+			if !h.allowOrigin(req) {
+				fmt.Printf("[chat_ws WebSocket] Rejected origin %q\n", req.Header.Get("Origin"))
+				return errors.New("origin_not_allowed")
+			}
+			return nil
+		},
+		Handler: func(conn *websocket.Conn) {
+			session := &wsSession{
+				conn:          conn,
+				chatService:   h.chatService,
+				cancels:       make(map[string]context.CancelFunc),
+				subscriptions: make(map[string]bool),
+			}
+			session.run(h.events)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// allowOrigin 检查WebSocket连接的来源：没有 Origin 的非浏览器客户端、与请求的 Host 相同的页面和配置允许的来源可以连接
+func (h *ChatHandler) allowOrigin(req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" || h.allowedOrigins["*"] || h.allowedOrigins[strings.TrimRight(origin, "/")] {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, req.Host)
+}
+
+// wsSession 单个WebSocket连接的会话状态
+type wsSession struct {
+	conn        *websocket.Conn
+	chatService *ChatService
+	sendMu      sync.Mutex
+
+	mu            sync.Mutex
+	cancels       map[string]context.CancelFunc // 进行中的聊天请求
+	subscriptions map[string]bool               // 已订阅的对话ID
+	subscribeAll  bool                          // 是否订阅所有对话
+}
+
+func (s *wsSession) run(hub *events.Hub) {
+	subscriptionID, eventCh := hub.Subscribe()
+	defer hub.Unsubscribe(subscriptionID)
+
+	// 转发订阅的对话事件
+	go func() {
+		for event := range eventCh {
+			if s.wants(event.ConversationID) {
+				event := event
+				s.send(wsMessage{Type: wsTypeEvent, ConversationID: event.ConversationID, Event: &event})
+			}
+		}
+	}()
+
+	for {
+		var msg wsMessage
+		if err := websocket.JSON.Receive(s.conn, &msg); err != nil {
+			// 连接断开时不取消进行中的请求，与SSE一致，生成会继续完成并保存
+			return
+		}
+
+		switch msg.Type {
+		case wsTypeChat:
+			s.startChat(msg)
+		case wsTypeCancel:
+			s.cancel(msg.RequestID)
+		case wsTypeSubscribe:
+			s.subscribe(msg.ConversationID, true)
+		case wsTypeUnsubscribe:
+			s.subscribe(msg.ConversationID, false)
+		case wsTypePing:
+			s.send(wsMessage{Type: wsTypePong, RequestID: msg.RequestID})
+		default:
+			s.send(wsMessage{Type: wsTypeError, RequestID: msg.RequestID, Error: "unsupported message type: " + msg.Type})
+		}
+	}
+}
+
+// startChat 在后台执行聊天请求，通过delta消息流式返回内容
+func (s *wsSession) startChat(msg wsMessage) {
+	if msg.RequestID == "" {
+		s.send(wsMessage{Type: wsTypeError, Error: "request_id is required"})
+		return
+	}
+	if msg.Chat == nil {
+		s.send(wsMessage{Type: wsTypeError, RequestID: msg.RequestID, Error: "chat is required"})
+		return
+	}
+	req := msg.Chat
+	if err := ValidateChatRequest(req); err != nil {
+		s.send(wsMessage{Type: wsTypeError, RequestID: msg.RequestID, Error: err.Error()})
+		return
+	}
+
+	if req.Type == models.ChatTypeCompare {
+		s.send(wsMessage{Type: wsTypeError, RequestID: msg.RequestID, Error: "compare is only supported by POST /api/chat"})
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	if _, exists := s.cancels[msg.RequestID]; exists {
+		s.mu.Unlock()
+		cancel()
+		s.send(wsMessage{Type: wsTypeError, RequestID: msg.RequestID, Error: "duplicate request_id"})
+		return
+	}
+	s.cancels[msg.RequestID] = cancel
+	s.mu.Unlock()
+
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			delete(s.cancels, msg.RequestID)
+			s.mu.Unlock()
+			cancel()
+		}()
+
+		writer := &wsStreamWriter{session: s, requestID: msg.RequestID}
+		response, err := s.chatService.Chat(ctx, req, writer)
+		if err != nil {
+			s.send(wsMessage{Type: wsTypeError, RequestID: msg.RequestID, Error: err.Error()})
+			return
+		}
+
+		// 自动订阅本连接发起的对话
+		s.subscribe(response.ConversationID, true)
+
+		resultType := wsTypeDone
+		if ctx.Err() != nil {
+			resultType = wsTypeCancelled
+		}
+		s.send(wsMessage{
+			Type:           resultType,
+			RequestID:      msg.RequestID,
+			ConversationID: response.ConversationID,
+			DocumentID:     response.DocumentID,
+			Command:        response.Command,
+		})
+	}()
+}
+
+// cancel 取消进行中的聊天请求
+func (s *wsSession) cancel(requestID string) {
+	s.mu.Lock()
+	cancel, ok := s.cancels[requestID]
+	s.mu.Unlock()
+	if !ok {
+		s.send(wsMessage{Type: wsTypeError, RequestID: requestID, Error: "request not found"})
+		return
+	}
+	cancel()
+}
+
+// subscribe 订阅或取消订阅对话事件
+func (s *wsSession) subscribe(conversationID string, subscribed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if conversationID == "" {
+		s.subscribeAll = subscribed
+		return
+	}
+	if subscribed {
+		s.subscriptions[conversationID] = true
+	} else {
+		delete(s.subscriptions, conversationID)
+	}
+}
+
+// wants 判断是否需要向本连接转发该对话的事件
+func (s *wsSession) wants(conversationID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.subscribeAll || s.subscriptions[conversationID]
+}
+
+// send 发送消息，多个goroutine共享同一连接，需要加锁
+func (s *wsSession) send(msg wsMessage) error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	return websocket.JSON.Send(s.conn, msg)
+}
+
+// wsStreamWriter 将流式响应内容包装为delta消息发送
+type wsStreamWriter struct {
+	session   *wsSession
+	requestID string
+}
+
+func (w *wsStreamWriter) Write(p []byte) (int, error) {
+	if err := w.session.send(wsMessage{Type: wsTypeDelta, RequestID: w.requestID, Content: string(p)}); err != nil {
+		return 0, fmt.Errorf("websocket send failed: %w", err)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,37 @@
+package chat
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebSocketAllowOrigin(t *testing.T) {
+	handler := NewChatHandler(nil, nil, []string{"http://localhost:5173", " http://app.example.com/ "})
+	cases := []struct {
+		origin string
+		allow  bool
+	}{
+		{"", true},
+		{"http://grandma.local:8080", true}, // 同源
+		{"http://localhost:5173", true},
+		{"http://app.example.com", true},
+		{"http://evil.example.com", false},
+		{"http://grandma.local:9090", false},
+		{"null", false},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "http://grandma.local:8080/api/ws", nil)
+		if c.origin != "" {
+			req.Header.Set("Origin", c.origin)
+		}
+		if got := handler.allowOrigin(req); got != c.allow {
+			t.Errorf("allowOrigin(%q) = %v, want %v", c.origin, got, c.allow)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "http://grandma.local:8080/api/ws", nil)
+	req.Header.Set("Origin", "http://evil.example.com")
+	if !NewChatHandler(nil, nil, []string{"*"}).allowOrigin(req) {
+		t.Error("wildcard does not allow other origins")
+	}
+}
@@ -0,0 +1,188 @@
+package chat
+
+import (
+	"fmt"
+	"grandma/backend/models"
+	"grandma/backend/prompts"
+	"grandma/backend/services"
+	"log"
+	"strings"
+)
+
+const (
+	maxHistoryDocuments  = 200  // 构建上下文时沿分支最多加载的文档数
+	messageTokenOverhead = 4    // 每条消息格式开销的token估算
+	summaryReserveTokens = 2000 // 注入摘要时为摘要预留的token数
+	summaryChunkTokens   = 6000 // 生成滚动摘要时每次送入模型的最大新增内容
+)
+
+// buildContext 沿分支构建发送给大模型的历史消息：从最新到最旧估算每条消息的token数，
+// 在模型预算内尽可能多地保留原文；更早的历史被丢弃时注入缓存的滚动摘要，并在后台更新摘要，
+// 还没有摘要时注入说明，告诉模型更早的内容已省略
+// reservedTokens 为本次请求中新消息占用的token数
+func (s *ChatService) buildContext(conversationID, parentID, model string, reservedTokens int) []models.Message {
+	if parentID == "" {
+		return nil
+	}
+
+	historyDocs, err := s.documentRepo.GetBranch(parentID, maxHistoryDocuments)
+	if err != nil || len(historyDocs) == 0 {
+		return nil
+	}
+
+	// 按时间正序排列，与消息一一对应
+	messages := s.historyToMessages(historyDocs)
+	documentIDs := make([]string, len(historyDocs))
+	for i, doc := range historyDocs {
+		documentIDs[len(historyDocs)-1-i] = doc.ID
+	}
+
+	budget := services.GetContextBudget(model)
+	if s.config.ContextTokenBudget > 0 {
+		budget = s.config.ContextTokenBudget
+	}
+	budget -= reservedTokens
+	costs := make([]int, len(messages))
+	total := 0
+	for i, msg := range messages {
+		costs[i] = services.EstimateTokens(msg.Content) + messageTokenOverhead
+		total += costs[i]
+	}
+
+	// 分支完整加载且全部放得下时，直接使用全部历史
+	branchComplete := len(historyDocs) < maxHistoryDocuments || historyDocs[len(historyDocs)-1].ParentID == ""
+	if branchComplete && total <= budget {
+		return messages
+	}
+
+	// 从最新到最旧填充预算，为摘要预留空间
+	keep := len(messages)
+	used := 0
+	for keep > 0 && used+costs[keep-1] <= budget-summaryReserveTokens {
+		used += costs[keep-1]
+		keep--
+	}
+	// 加载的文档都放得下，但分支超过 maxHistoryDocuments 没有完整加载，更早的历史同样被丢弃：
+	// 把最早的一轮对话作为被丢弃的历史，由滚动摘要覆盖，之后的请求可以沿用摘要
+	if keep == 0 {
+		keep = 1
+	}
+	// 保留的历史以用户消息开头，保证消息角色交替
+	for keep < len(messages) && messages[keep].Role != "user" {
+		keep++
+	}
+
+	// 分支上覆盖范围最大的摘要可能在加载的文档之前（分支超过 maxHistoryDocuments 时），沿全部祖先文档查找
+	lastDroppedID := documentIDs[keep-1]
+	summary, err := s.summaryRepo.GetLatestOnBranch(lastDroppedID)
+	if err != nil {
+		log.Printf("[chat_service buildContext] Failed to load summary: %v", err)
+	}
+
+	// 摘要尚未覆盖全部被丢弃的历史时，在后台从摘要之后继续概括，供之后的请求使用
+	if summary == nil || summary.DocumentID != lastDroppedID {
+		s.refreshSummary(conversationID, model, summary, lastDroppedID)
+	}
+
+	result := make([]models.Message, 0, len(messages)-keep+2)
+	if summary != nil {
+		result = append(result,
+			models.Message{Role: "user", Content: s.prompts.Render(prompts.ChatSummaryContext, map[string]string{"summary": summary.Content})},
+			models.Message{Role: "assistant", Content: s.prompts.Render(prompts.ChatSummaryReply, nil)},
+		)
+	} else {
+		// 摘要在后台生成，本次请求只能说明更早的内容已省略
+		log.Printf("[chat_service buildContext] Conversation %s: %d earlier messages omitted without summary", conversationID, keep)
+		result = append(result,
+			models.Message{Role: "user", Content: s.prompts.Render(prompts.ChatTruncatedContext, nil)},
+			models.Message{Role: "assistant", Content: s.prompts.Render(prompts.ChatTruncatedReply, nil)},
+		)
+	}
+	return append(result, messages[keep:]...)
+}
+
+// refreshSummary 在后台基于已有摘要 base 概括其后直到 documentID（含）的历史，生成滚动摘要
+// base 为空时从分支起点开始；按块逐步概括并缓存每一块的结果，之后的请求以最新的一块为基础继续
+func (s *ChatService) refreshSummary(conversationID, model string, base *models.ConversationSummary, documentID string) {
+	// 同一摘要只生成一次
+	if _, loaded := s.summarizing.LoadOrStore(documentID, struct{}{}); loaded {
+		return
+	}
+
+	go func() {
+		defer s.summarizing.Delete(documentID)
+
+		summary := "（无）"
+		baseID := ""
+		if base != nil {
+			summary = base.Content
+			baseID = base.DocumentID
+		}
+		docs, err := s.documentRepo.GetBranchSince(documentID, baseID)
+		if err != nil {
+			log.Printf("[chat_service refreshSummary] Failed to load history: %v", err)
+			return
+		}
+		if len(docs) == 0 {
+			return
+		}
+		// 按时间正序排列，与消息一一对应
+		messages := s.historyToMessages(docs)
+		documentIDs := make([]string, len(docs))
+		for i, doc := range docs {
+			documentIDs[len(docs)-1-i] = doc.ID
+		}
+
+		provider, err := services.GetProvider(
+			model,
+			s.config.OpenAIAPIKey,
+			s.config.OpenAIBaseURL,
+			s.config.AnthropicAPIKey,
+			s.config.AnthropicBaseURL,
+		)
+		if err != nil {
+			log.Printf("[chat_service refreshSummary] Failed to get provider: %v", err)
+			return
+		}
+
+		var chunk strings.Builder
+		chunkTokens := 0
+		for i, msg := range messages {
+			role := "用户"
+			if msg.Role == "assistant" {
+				role = "助手"
+			}
+			fmt.Fprintf(&chunk, "%s：%s\n\n", role, msg.Content)
+			chunkTokens += services.EstimateTokens(msg.Content) + messageTokenOverhead
+
+			if chunkTokens < summaryChunkTokens && i < len(messages)-1 {
+				continue
+			}
+
+			prompt := s.prompts.Render(prompts.ChatSummaryGeneration, map[string]string{
+				"summary":      summary,
+				"conversation": chunk.String(),
+			})
+			summary, err = provider.Chat([]models.Message{{Role: "user", Content: prompt}})
+			if err != nil {
+				log.Printf("[chat_service refreshSummary] Failed to generate summary: %v", err)
+				return
+			}
+			summary = strings.TrimSpace(summary)
+
+			err = s.summaryRepo.Save(&models.ConversationSummary{
+				DocumentID:     documentIDs[i],
+				ConversationID: conversationID,
+				Content:        summary,
+				Model:          model,
+			})
+			if err != nil {
+				log.Printf("[chat_service refreshSummary] Failed to save summary: %v", err)
+				return
+			}
+
+			chunk.Reset()
+			chunkTokens = 0
+		}
+	}()
+}
@@ -0,0 +1,204 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"grandma/backend/database"
+	"grandma/backend/events"
+	"grandma/backend/models"
+	"grandma/backend/prompts"
+	"grandma/backend/repository"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newContextTestService 使用临时数据库创建聊天服务，并创建一条有 count 条文档的分支（用户和助手交替），返回最后一条文档的ID
+func newContextTestService(t *testing.T, count int) (*ChatService, string) {
+	t.Helper()
+	if err := database.InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	conversationRepo := repository.NewConversationRepository(database.DB)
+	documentRepo := repository.NewDocumentRepository(database.DB)
+	if err := conversationRepo.Create(&models.Conversation{ID: "conv_1", Title: "很长的对话"}); err != nil {
+		t.Fatal(err)
+	}
+	service := NewChatService(
+		conversationRepo,
+		documentRepo,
+		repository.NewSummaryRepository(database.DB),
+		repository.NewSafetyRepository(database.DB),
+		&ChatConfig{ContextTokenBudget: 1 << 20},
+		events.NewHub(),
+		prompts.NewStore(repository.NewPromptRepository(database.DB)),
+		nil,
+		nil,
+	)
+	return service, appendTestDocuments(t, service, "", 0, count)
+}
+
+// appendTestDocuments 在 parentID 之后追加编号从 from 开始的 count 条文档，返回最后一条文档的ID
+func appendTestDocuments(t *testing.T, service *ChatService, parentID string, from, count int) string {
+	t.Helper()
+	for i := from; i < from+count; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		doc := &models.Document{
+			ID:             fmt.Sprintf("doc_%03d", i),
+			ConversationID: "conv_1",
+			Role:           role,
+			Content:        fmt.Sprintf("第%d条", i),
+			ParentID:       parentID,
+		}
+		if err := service.documentRepo.Create(doc); err != nil {
+			t.Fatal(err)
+		}
+		parentID = doc.ID
+	}
+	return parentID
+}
+
+func TestBuildContextCompleteBranch(t *testing.T) {
+	service, headID := newContextTestService(t, 10)
+	messages := service.buildContext("conv_1", headID, "openai", 0)
+	if len(messages) != 10 || messages[0].Content != "第0条" {
+		t.Fatalf("got %d messages starting with %+v", len(messages), messages[0])
+	}
+}
+
+func TestBuildContextMarksTruncatedHistory(t *testing.T) {
+	// 分支超过 maxHistoryDocuments，加载的部分都放得下预算
+	service, headID := newContextTestService(t, maxHistoryDocuments+11)
+	messages := service.buildContext("conv_1", headID, "openai", 0)
+
+	truncated := service.prompts.Render(prompts.ChatTruncatedContext, nil)
+	if len(messages) < 3 || messages[0].Role != "user" || messages[0].Content != truncated || messages[1].Role != "assistant" {
+		t.Fatalf("history does not start with the truncation notice: %+v", messages[:2])
+	}
+	// 说明之后的历史以用户消息开头，角色交替，并保留到最后一条文档
+	for i := 2; i < len(messages); i++ {
+		want := "user"
+		if i%2 == 1 {
+			want = "assistant"
+		}
+		if messages[i].Role != want {
+			t.Fatalf("message %d role = %s, want %s", i, messages[i].Role, want)
+		}
+	}
+	if last := messages[len(messages)-1]; last.Content != fmt.Sprintf("第%d条", maxHistoryDocuments+10) {
+		t.Fatalf("last message = %+v", last)
+	}
+}
+
+func TestBuildContextUsesCachedSummary(t *testing.T) {
+	service, headID := newContextTestService(t, maxHistoryDocuments+11)
+	// 被丢弃的最早一轮对话已有摘要
+	branch, err := service.documentRepo.GetBranch(headID, maxHistoryDocuments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldest := branch[len(branch)-1]
+	if err := service.summaryRepo.Save(&models.ConversationSummary{DocumentID: oldest.ID, ConversationID: "conv_1", Content: "小龙学会了飞"}); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := service.buildContext("conv_1", headID, "openai", 0)
+	want := service.prompts.Render(prompts.ChatSummaryContext, map[string]string{"summary": "小龙学会了飞"})
+	if messages[0].Content != want || messages[2].Role != "user" {
+		t.Fatalf("history does not start with the summary: %+v", messages[:3])
+	}
+}
+
+// summaryServer 模拟 OpenAI 兼容接口生成摘要，第 n 次请求返回“摘要n”，并记录收到的提示词
+type summaryServer struct {
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (m *summaryServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Messages []models.Message `json:"messages"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Messages) == 0 {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	m.mu.Lock()
+	m.prompts = append(m.prompts, req.Messages[len(req.Messages)-1].Content)
+	n := len(m.prompts)
+	m.mu.Unlock()
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"choices": []map[string]interface{}{{"message": map[string]string{"content": fmt.Sprintf("摘要%d", n)}}},
+	})
+}
+
+// waitForSummary 等待后台生成覆盖到指定文档的摘要
+func waitForSummary(t *testing.T, service *ChatService, documentID string) *models.ConversationSummary {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		summary, err := service.summaryRepo.GetLatestOnBranch(documentID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if summary != nil && summary.DocumentID == documentID {
+			return summary
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no summary for %s", documentID)
+	return nil
+}
+
+func TestBuildContextChainsSummaries(t *testing.T) {
+	server := &summaryServer{}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	count := maxHistoryDocuments + 11
+	service, headID := newContextTestService(t, count)
+	service.config.OpenAIAPIKey = "test"
+	service.config.OpenAIBaseURL = httpServer.URL
+
+	// 第一次从分支起点概括到加载窗口之前的最后一条文档
+	messages := service.buildContext("conv_1", headID, "openai", 0)
+	truncated := service.prompts.Render(prompts.ChatTruncatedContext, nil)
+	if messages[0].Content != truncated {
+		t.Fatalf("first context = %+v", messages[0])
+	}
+	first := waitForSummary(t, service, "doc_011")
+	if first.Content != "摘要1" || !strings.Contains(server.prompts[0], "第0条") || !strings.Contains(server.prompts[0], "第11条") {
+		t.Fatalf("first summary %+v from prompt %q", first, server.prompts[0])
+	}
+
+	// 之后每次发送，加载窗口后移一轮，以窗口之前的摘要为基础只概括新移出窗口的文档
+	for round := 1; round <= 3; round++ {
+		headID = appendTestDocuments(t, service, headID, count, 2)
+		count += 2
+
+		messages = service.buildContext("conv_1", headID, "openai", 0)
+		want := service.prompts.Render(prompts.ChatSummaryContext, map[string]string{"summary": fmt.Sprintf("摘要%d", round)})
+		if messages[0].Content != want {
+			t.Fatalf("round %d: context starts with %q, want %q", round, messages[0].Content, want)
+		}
+
+		lastDropped := fmt.Sprintf("doc_%03d", 11+2*round)
+		summary := waitForSummary(t, service, lastDropped)
+		prompt := server.prompts[round]
+		if summary.Content != fmt.Sprintf("摘要%d", round+1) || !strings.Contains(prompt, fmt.Sprintf("摘要%d", round)) ||
+			strings.Contains(prompt, fmt.Sprintf("第%d条", 9+2*round)) || !strings.Contains(prompt, fmt.Sprintf("第%d条", 10+2*round)) ||
+			!strings.Contains(prompt, fmt.Sprintf("第%d条", 11+2*round)) {
+			t.Fatalf("round %d: summary %+v from prompt %q", round, summary, prompt)
+		}
+	}
+	if len(server.prompts) != 4 {
+		t.Fatalf("summary requests = %d, want 4", len(server.prompts))
+	}
+}
@@ -0,0 +1,23 @@
+package command
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CommandHandler 斜杠命令列表；命令本身在聊天服务中处理，所有聊天入口都支持
+type CommandHandler struct {
+	service *CommandService
+}
+
+func NewCommandHandler(service *CommandService) *CommandHandler {
+	return &CommandHandler{
+		service: service,
+	}
+}
+
+// GetCommands 获取斜杠命令列表
+func (h *CommandHandler) GetCommands(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.ListCommands())
+}
@@ -0,0 +1,218 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"grandma/backend/models"
+	"grandma/backend/modules/conversation"
+	"grandma/backend/modules/conversation_list"
+	"grandma/backend/modules/story"
+	"grandma/backend/prompts"
+	"grandma/backend/repository"
+	"strings"
+)
+
+// 命令类型
+const (
+	KindPrompt = "prompt" // 转换为提示词后走正常聊天流程
+	KindAction = "action" // 在服务端直接执行
+)
+
+const titleContextDocuments = 20 // 重新生成标题时最多参考的分支文档数
+
+// commands 支持的斜杠命令
+var commands = []models.CommandInfo{
+	{Name: "outline", Usage: "/outline [补充要求]", Description: "根据目前的故事内容整理后续情节大纲", Kind: KindPrompt},
+	{Name: "continue", Usage: "/continue", Description: "从上一条回复的末尾继续写", Kind: KindPrompt},
+	{Name: "rewrite", Usage: "/rewrite [要求，如 shorter]", Description: "按要求改写上一条回复", Kind: KindPrompt},
+	{Name: "title", Usage: "/title [新标题]", Description: "重新生成对话标题，带参数时直接设为该标题", Kind: KindAction},
+	{Name: "save-story", Usage: "/save-story [标题]", Description: "将上一条回复保存为故事", Kind: KindAction},
+}
+
+// rewriteAliases 常用的英文改写要求
+var rewriteAliases = map[string]string{
+	"shorter": "更简短",
+	"longer":  "更详细",
+	"simpler": "用更简单易懂的语言",
+	"vivid":   "更生动形象",
+}
+
+type CommandService struct {
+	conversationRepo        *repository.ConversationRepository
+	documentRepo            *repository.DocumentRepository
+	conversationService     *conversation.ConversationService
+	conversationListService *conversation_list.ConversationListService
+	storyService            *story.StoryService
+	prompts                 *prompts.Store
+}
+
+func NewCommandService(conversationRepo *repository.ConversationRepository, documentRepo *repository.DocumentRepository, conversationService *conversation.ConversationService, conversationListService *conversation_list.ConversationListService, storyService *story.StoryService, promptStore *prompts.Store) *CommandService {
+	return &CommandService{
+		conversationRepo:        conversationRepo,
+		documentRepo:            documentRepo,
+		conversationService:     conversationService,
+		conversationListService: conversationListService,
+		storyService:            storyService,
+		prompts:                 promptStore,
+	}
+}
+
+// ListCommands 获取支持的斜杠命令
+func (s *CommandService) ListCommands() *models.CommandListResponse {
+	return &models.CommandListResponse{Commands: commands}
+}
+
+// Apply 处理聊天请求中的斜杠命令，所有聊天入口（HTTP、WebSocket、后台任务）在聊天之前调用
+// 提示词类命令直接改写请求并返回nil，请求继续走正常聊天流程；服务端命令执行后返回结果；不是命令时不做处理
+func (s *CommandService) Apply(req *models.ChatRequest) (*models.CommandResponse, error) {
+	command, args := s.Parse(req)
+	if command == nil {
+		return nil, nil
+	}
+	fmt.Printf("[command_service Apply] Command: /%s, args: %q\n", command.Name, args)
+
+	if command.Kind == KindPrompt {
+		return nil, s.ApplyPrompt(req, command, args)
+	}
+	return s.ExecuteAction(req, command, args)
+}
+
+// Parse 识别聊天请求最后一条用户消息中的斜杠命令，返回命令和参数；不是已知命令时返回nil
+func (s *CommandService) Parse(req *models.ChatRequest) (*models.CommandInfo, string) {
+	if req.Type != "" && req.Type != models.ChatTypeMessage {
+		return nil, ""
+	}
+	if len(req.Messages) == 0 {
+		return nil, ""
+	}
+	lastMsg := req.Messages[len(req.Messages)-1]
+	text := strings.TrimSpace(lastMsg.Content)
+	if lastMsg.Role != "user" || !strings.HasPrefix(text, "/") {
+		return nil, ""
+	}
+
+	name, args, _ := strings.Cut(text[1:], " ")
+	for i := range commands {
+		if commands[i].Name == name {
+			return &commands[i], strings.TrimSpace(args)
+		}
+	}
+	// 未知命令按普通消息处理
+	return nil, ""
+}
+
+// ApplyPrompt 将提示词类命令转换为对应的聊天请求
+func (s *CommandService) ApplyPrompt(req *models.ChatRequest, command *models.CommandInfo, args string) error {
+	last := &req.Messages[len(req.Messages)-1]
+	switch command.Name {
+	case "outline":
+		last.Content = strings.TrimSpace(s.prompts.Render(prompts.CommandOutline, map[string]string{"requirements": args}))
+	case "continue":
+		// 续写对话活动分支的最后一条助手文档
+		if req.ConversationID == "" {
+			return errors.New("conversation_id_required")
+		}
+		req.Type = models.ChatTypeContinue
+		req.Messages = nil
+	case "rewrite":
+		if alias, ok := rewriteAliases[strings.ToLower(args)]; ok {
+			args = alias
+		}
+		if args == "" {
+			args = "使表达更流畅"
+		}
+		if req.ConversationID == "" {
+			return errors.New("conversation_id_required")
+		}
+		last.Content = s.prompts.Render(prompts.CommandRewrite, map[string]string{"instruction": args})
+	default:
+		return errors.New("unsupported_command")
+	}
+	return nil
+}
+
+// ExecuteAction 执行服务端命令
+func (s *CommandService) ExecuteAction(req *models.ChatRequest, command *models.CommandInfo, args string) (*models.CommandResponse, error) {
+	if req.ConversationID == "" {
+		return nil, errors.New("conversation_id_required")
+	}
+	conv, err := s.conversationRepo.GetByID(req.ConversationID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.CommandResponse{
+		Command:        command.Name,
+		ConversationID: conv.ID,
+	}
+	switch command.Name {
+	case "title":
+		if args != "" {
+			err = s.conversationService.UpdateConversationTitle(conv.ID, args)
+			response.Title = args
+		} else {
+			response.Title, err = s.conversationListService.RetitleConversation(conv.ID, s.userInputs(conv.HeadDocumentID))
+		}
+		if err != nil {
+			return nil, err
+		}
+	case "save-story":
+		doc, err := s.latestAssistantDocument(conv.HeadDocumentID)
+		if err != nil {
+			return nil, err
+		}
+		title := args
+		if title == "" {
+			title = conv.Title
+		}
+		saved, err := s.storyService.CreateStory("", doc.ID, title, doc.Content, "")
+		if err != nil {
+			return nil, err
+		}
+		response.StoryID = saved.ID
+		response.DocumentID = doc.ID
+		response.Title = title
+	default:
+		return nil, errors.New("unsupported_command")
+	}
+	return response, nil
+}
+
+// userInputs 获取活动分支上的用户输入，按时间正序
+func (s *CommandService) userInputs(headDocumentID string) []string {
+	if headDocumentID == "" {
+		return nil
+	}
+	docs, err := s.documentRepo.GetBranch(headDocumentID, titleContextDocuments)
+	if err != nil {
+		return nil
+	}
+	var inputs []string
+	for i := len(docs) - 1; i >= 0; i-- {
+		if docs[i].Role == "user" {
+			inputs = append(inputs, docs[i].Content)
+		}
+	}
+	return inputs
+}
+
+// latestAssistantDocument 获取活动分支上最后一条助手文档，已选用候选版本时返回候选版本
+func (s *CommandService) latestAssistantDocument(headDocumentID string) (*models.Document, error) {
+	if headDocumentID == "" {
+		return nil, errors.New("no_assistant_document")
+	}
+	docs, err := s.documentRepo.GetBranch(headDocumentID, 2)
+	if err != nil {
+		return nil, err
+	}
+	for _, doc := range docs {
+		if doc.Role != "assistant" {
+			continue
+		}
+		if doc.ActiveAlternativeID != "" {
+			return s.documentRepo.GetByID(doc.ActiveAlternativeID)
+		}
+		return &doc, nil
+	}
+	return nil, errors.New("no_assistant_document")
+}
@@ -1,11 +1,13 @@
 package conversation
 
 import (
+	"errors"
 	"fmt"
 	"grandma/backend/models"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type ConversationHandler struct {
@@ -60,15 +62,18 @@ func (h *ConversationHandler) CreateConversation(c *gin.Context) {
 func (h *ConversationHandler) UpdateConversation(c *gin.Context) {
 	fmt.Println("[conversation_handler UpdateConversation] Start")
 	id := c.Param("id")
-	var conv models.Conversation
-	if err := c.ShouldBindJSON(&conv); err != nil {
+	var req models.ConversationUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	conv.ID = id
-	err := h.service.UpdateConversation(&conv)
+	err := h.service.UpdateConversation(id, &req)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -103,9 +108,57 @@ func (h *ConversationHandler) DeleteConversation(c *gin.Context) {
 	id := c.Param("id")
 	err := h.service.DeleteConversation(id)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Conversation deleted successfully"})
 }
+
+// ForkConversation 从指定文档分叉对话
+func (h *ConversationHandler) ForkConversation(c *gin.Context) {
+	fmt.Println("[conversation_handler ForkConversation] Start")
+	id := c.Param("id")
+	var req models.ForkConversationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conv, err := h.service.ForkConversation(id, req.DocumentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Conversation or document not found"})
+			return
+		}
+		if err.Error() == "invalid_fork_document" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, conv)
+}
+
+// GetBranchTree 获取对话的分支树
+func (h *ConversationHandler) GetBranchTree(c *gin.Context) {
+	fmt.Println("[conversation_handler GetBranchTree] Start")
+	id := c.Param("id")
+	tree, err := h.service.GetBranchTree(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tree)
+}
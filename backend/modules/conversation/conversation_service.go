@@ -1,20 +1,27 @@
 package conversation
 
 import (
+	"errors"
+	"grandma/backend/events"
 	"grandma/backend/models"
 	"grandma/backend/repository"
 	"grandma/backend/utils"
+	"strings"
 )
 
 type ConversationService struct {
 	conversationRepo *repository.ConversationRepository
 	documentRepo     *repository.DocumentRepository
+	summaryRepo      *repository.SummaryRepository
+	events           *events.Hub
 }
 
-func NewConversationService(conversationRepo *repository.ConversationRepository, documentRepo *repository.DocumentRepository) *ConversationService {
+func NewConversationService(conversationRepo *repository.ConversationRepository, documentRepo *repository.DocumentRepository, summaryRepo *repository.SummaryRepository, hub *events.Hub) *ConversationService {
 	return &ConversationService{
 		conversationRepo: conversationRepo,
 		documentRepo:     documentRepo,
+		summaryRepo:      summaryRepo,
+		events:           hub,
 	}
 }
 
@@ -36,24 +43,132 @@ func (s *ConversationService) CreateConversation(title string) (*models.Conversa
 	return conversation, nil
 }
 
-// UpdateConversation 更新对话
-func (s *ConversationService) UpdateConversation(conversation *models.Conversation) error {
-	return s.conversationRepo.Update(conversation)
+// UpdateConversation 更新对话，只修改请求中给出的标题
+func (s *ConversationService) UpdateConversation(id string, req *models.ConversationUpdateRequest) error {
+	if req.Title == nil {
+		_, err := s.conversationRepo.GetMetaByID(id)
+		return err
+	}
+	if err := s.conversationRepo.Update(id, *req.Title); err != nil {
+		return err
+	}
+	s.events.Publish(events.Event{
+		Type:           events.TypeTitleUpdated,
+		ConversationID: id,
+		Data:           map[string]string{"title": *req.Title},
+	})
+	return nil
 }
 
 // UpdateConversationTitle 更新对话标题
 func (s *ConversationService) UpdateConversationTitle(id, title string) error {
-	return s.conversationRepo.UpdateTitle(id, title)
+	err := s.conversationRepo.UpdateTitle(id, title)
+	if err != nil {
+		return err
+	}
+	s.events.Publish(events.Event{
+		Type:           events.TypeTitleUpdated,
+		ConversationID: id,
+		Data:           map[string]string{"title": title},
+	})
+	return nil
 }
 
-// DeleteConversation 删除对话（包括关联的文档）
+// DeleteConversation 将对话（包括关联的文档）移入回收站，摘要在永久删除时一起删除
 func (s *ConversationService) DeleteConversation(id string) error {
-	// 先删除关联的文档
-	err := s.documentRepo.DeleteByConversationID(id)
+	err := s.conversationRepo.Delete(id)
 	if err != nil {
 		return err
 	}
-	// 再删除对话
-	return s.conversationRepo.Delete(id)
+	s.events.Publish(events.Event{
+		Type:           events.TypeConversationDeleted,
+		ConversationID: id,
+	})
+	return nil
+}
+
+// ForkConversation 从指定文档分叉对话：将活动分支切换到该文档，之后的新消息将形成新的分支
+// documentID 为空表示从对话开头开始新分支
+func (s *ConversationService) ForkConversation(id, documentID string) (*models.Conversation, error) {
+	if _, err := s.conversationRepo.GetByID(id); err != nil {
+		return nil, err
+	}
+
+	if documentID != "" {
+		doc, err := s.documentRepo.GetByID(documentID)
+		if err != nil {
+			return nil, err
+		}
+		if doc.ConversationID != id || doc.AlternativeOf != "" {
+			return nil, errors.New("invalid_fork_document")
+		}
+	}
+
+	if err := s.conversationRepo.UpdateHeadDocumentID(id, documentID); err != nil {
+		return nil, err
+	}
+	return s.conversationRepo.GetByID(id)
 }
 
+// GetBranchTree 获取对话的分支树
+func (s *ConversationService) GetBranchTree(id string) (*models.BranchTreeResponse, error) {
+	conversation, err := s.conversationRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	documents, err := s.documentRepo.GetByConversationID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[string]*models.BranchNode, len(documents))
+	for _, doc := range documents {
+		nodes[doc.ID] = &models.BranchNode{
+			ID:        doc.ID,
+			ParentID:  doc.ParentID,
+			Role:      doc.Role,
+			Model:     doc.Model,
+			Preview:   preview(doc.Content),
+			CreatedAt: doc.CreatedAt,
+			Children:  []*models.BranchNode{},
+		}
+	}
+
+	// 标记活动分支上的节点
+	for nodeID := conversation.HeadDocumentID; nodeID != ""; {
+		node, ok := nodes[nodeID]
+		if !ok || node.Active {
+			break
+		}
+		node.Active = true
+		nodeID = node.ParentID
+	}
+
+	// 按创建时间顺序组装树，父文档不存在（如已删除）的节点作为根节点
+	roots := []*models.BranchNode{}
+	for _, doc := range documents {
+		node := nodes[doc.ID]
+		if parent, ok := nodes[doc.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	return &models.BranchTreeResponse{
+		ConversationID: id,
+		HeadDocumentID: conversation.HeadDocumentID,
+		Roots:          roots,
+	}, nil
+}
+
+// preview 截取文档内容的前若干个字符作为预览
+func preview(content string) string {
+	const previewLength = 50
+	runes := []rune(strings.TrimSpace(content))
+	if len(runes) > previewLength {
+		return string(runes[:previewLength]) + "..."
+	}
+	return string(runes)
+}
@@ -1,9 +1,10 @@
 package conversation_list
 
 import (
+	"errors"
 	"grandma/backend/models"
+	"grandma/backend/repository"
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,13 +19,21 @@ func NewConversationListHandler(service *ConversationListService) *ConversationL
 	}
 }
 
-// GetConversationList 获取对话列表
+// GetConversationList 获取对话列表，支持按文件夹、标签、归档和置顶筛选以及排序
+// 使用上次返回的 next_cursor 或 prev_cursor 翻页，翻页时需要传入相同的筛选和排序参数
 func (h *ConversationListHandler) GetConversationList(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	var req models.ConversationListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	response, err := h.service.GetConversationList(page, pageSize)
+	response, err := h.service.GetConversationList(&req)
 	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) || err.Error() == "invalid_filter" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -1,16 +1,23 @@
 package conversation_list
 
 import (
+	"errors"
+	"grandma/backend/events"
 	"grandma/backend/models"
+	"grandma/backend/prompts"
 	"grandma/backend/repository"
 	"grandma/backend/services"
 	"grandma/backend/utils"
 	"strings"
 )
 
+const maxPageSize = 100 // 每页对话数的上限
+
 type ConversationListService struct {
 	conversationRepo *repository.ConversationRepository
 	config           *TitleGenerationConfig
+	events           *events.Hub
+	prompts          *prompts.Store
 }
 
 type TitleGenerationConfig struct {
@@ -21,23 +28,31 @@ type TitleGenerationConfig struct {
 	DefaultModel     string // 默认使用哪个模型生成标题
 }
 
-func NewConversationListService(conversationRepo *repository.ConversationRepository, config *TitleGenerationConfig) *ConversationListService {
+func NewConversationListService(conversationRepo *repository.ConversationRepository, config *TitleGenerationConfig, hub *events.Hub, promptStore *prompts.Store) *ConversationListService {
 	return &ConversationListService{
 		conversationRepo: conversationRepo,
 		config:           config,
+		events:           hub,
+		prompts:          promptStore,
 	}
 }
 
-// GetConversationList 获取对话列表
-func (s *ConversationListService) GetConversationList(page, pageSize int) (*models.ConversationListResponse, error) {
-	if page <= 0 {
-		page = 1
-	}
+// GetConversationList 按筛选条件和游标获取对话列表
+func (s *ConversationListService) GetConversationList(req *models.ConversationListRequest) (*models.ConversationListResponse, error) {
+	pageSize := req.PageSize
 	if pageSize <= 0 {
 		pageSize = 20
 	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
 
-	conversations, total, err := s.conversationRepo.List(page, pageSize)
+	filter, err := parseFilter(req)
+	if err != nil {
+		return nil, err
+	}
+
+	conversations, total, page, err := s.conversationRepo.List(*filter, req.Cursor, pageSize)
 	if err != nil {
 		return nil, err
 	}
@@ -45,18 +60,85 @@ func (s *ConversationListService) GetConversationList(page, pageSize int) (*mode
 	return &models.ConversationListResponse{
 		Conversations: conversations,
 		Total:         int(total),
-		Page:          page,
 		PageSize:      pageSize,
+		PageInfo:      *page,
 	}, nil
 }
 
+// parseFilter 将列表请求的参数转换为筛选条件，参数值不合法时返回 invalid_filter
+func parseFilter(req *models.ConversationListRequest) (*repository.ConversationFilter, error) {
+	filter := &repository.ConversationFilter{PinnedFirst: true}
+
+	switch req.FolderID {
+	case "":
+	case "none":
+		unfiled := ""
+		filter.FolderID = &unfiled
+	default:
+		folderID := req.FolderID
+		filter.FolderID = &folderID
+	}
+
+	for _, tag := range req.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			filter.Tags = append(filter.Tags, tag)
+		}
+	}
+
+	switch req.Archived {
+	case "", "false":
+		archived := false
+		filter.Archived = &archived
+	case "true":
+		archived := true
+		filter.Archived = &archived
+	case "all":
+	default:
+		return nil, errors.New("invalid_filter")
+	}
+
+	switch req.Pinned {
+	case "":
+	case "true", "false":
+		pinned := req.Pinned == "true"
+		filter.Pinned = &pinned
+	default:
+		return nil, errors.New("invalid_filter")
+	}
+
+	switch req.Sort {
+	case "", repository.ConversationSortUpdated, repository.ConversationSortCreated:
+	case repository.ConversationSortTitle:
+		filter.Ascending = true
+	default:
+		return nil, errors.New("invalid_filter")
+	}
+	filter.Sort = req.Sort
+
+	switch req.Order {
+	case "":
+	case "asc", "desc":
+		filter.Ascending = req.Order == "asc"
+	default:
+		return nil, errors.New("invalid_filter")
+	}
+
+	switch req.PinnedFirst {
+	case "", "true":
+	case "false":
+		filter.PinnedFirst = false
+	default:
+		return nil, errors.New("invalid_filter")
+	}
+	return filter, nil
+}
+
 // CreateNewConversation 创建新对话
 func (s *ConversationListService) CreateNewConversation() (*models.Conversation, error) {
 	conversationID := utils.GenerateConversationID()
 	conversation := &models.Conversation{
-		ID:          conversationID,
-		Title:       "新对话",
-		DocumentIDs: "",
+		ID:    conversationID,
+		Title: "新对话",
 	}
 	err := s.conversationRepo.Create(conversation)
 	if err != nil {
@@ -79,14 +161,18 @@ func (s *ConversationListService) CreateNewConversationWithTitle(userInputs []st
 	}
 
 	conversation := &models.Conversation{
-		ID:          conversationID,
-		Title:       title,
-		DocumentIDs: "",
+		ID:    conversationID,
+		Title: title,
 	}
 	err := s.conversationRepo.Create(conversation)
 	if err != nil {
 		return nil, err
 	}
+	s.events.Publish(events.Event{
+		Type:           events.TypeConversationCreated,
+		ConversationID: conversationID,
+		Data:           map[string]string{"title": title},
+	})
 	return conversation, nil
 }
 
@@ -98,7 +184,7 @@ func (s *ConversationListService) generateTitle(userInputs []string) (string, er
 
 	// 构建提示词
 	userMessagesText := strings.Join(userInputs, "\n")
-	prompt := "请根据以下用户输入，生成一个简洁的对话标题（不超过20个字，不要包含标点符号）：\n\n" + userMessagesText
+	prompt := s.prompts.Render(prompts.ConversationTitle, map[string]string{"user_inputs": userMessagesText})
 
 	// 构建消息
 	messages := []models.Message{
@@ -148,6 +234,24 @@ func (s *ConversationListService) generateTitle(userInputs []string) (string, er
 	return title, nil
 }
 
+// RetitleConversation 根据用户输入为已有对话重新生成标题并保存
+func (s *ConversationListService) RetitleConversation(id string, userInputs []string) (string, error) {
+	title, err := s.generateTitle(userInputs)
+	if err != nil {
+		return "", err
+	}
+	err = s.conversationRepo.UpdateTitle(id, title)
+	if err != nil {
+		return "", err
+	}
+	s.events.Publish(events.Event{
+		Type:           events.TypeTitleUpdated,
+		ConversationID: id,
+		Data:           map[string]string{"title": title},
+	})
+	return title, nil
+}
+
 // GenerateTitleForConversation 为对话生成标题（公开方法，用于智能命名接口）
 func (s *ConversationListService) GenerateTitleForConversation(userInputs []string) (string, error) {
 	return s.generateTitle(userInputs)
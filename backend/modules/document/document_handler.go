@@ -1,11 +1,14 @@
 package document
 
 import (
+	"errors"
 	"fmt"
 	"grandma/backend/models"
+	"grandma/backend/repository"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type DocumentHandler struct {
@@ -18,7 +21,7 @@ func NewDocumentHandler(service *DocumentService) *DocumentHandler {
 	}
 }
 
-// GetDocumentList 获取文档列表（支持翻页）
+// GetDocumentList 获取文档列表，使用上次返回的 prev_cursor 或 next_cursor 翻页
 func (h *DocumentHandler) GetDocumentList(c *gin.Context) {
 	fmt.Println("[document_handler GetDocumentList] Start")
 	var req models.DocumentListRequest
@@ -28,14 +31,9 @@ func (h *DocumentHandler) GetDocumentList(c *gin.Context) {
 		return
 	}
 
-	limit := req.Limit
-	if limit <= 0 {
-		limit = 10 // 默认10个
-	}
-
-	response, err := h.service.GetDocumentList(req.ConversationID, req.BeforeID, limit)
+	response, err := h.service.GetDocumentList(req.ConversationID, req.Cursor, req.BeforeID, req.Limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writePageError(c, err)
 		return
 	}
 
@@ -55,22 +53,50 @@ func (h *DocumentHandler) GetDocumentByID(c *gin.Context) {
 	c.JSON(http.StatusOK, doc)
 }
 
+// BatchGetDocuments 批量获取文档，一次最多 models.MaxBatchDocuments 个ID
+func (h *DocumentHandler) BatchGetDocuments(c *gin.Context) {
+	var req models.BatchDocumentsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.service.GetDocumentsByIDs(req.IDs)
+	if err != nil {
+		if err.Error() == "empty_ids" || err.Error() == "too_many_ids" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "max_ids": models.MaxBatchDocuments})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
 // UpdateDocument 更新文档
 func (h *DocumentHandler) UpdateDocument(c *gin.Context) {
 	fmt.Println("[document_handler UpdateDocument] Start")
-	var doc models.Document
-	if err := c.ShouldBindJSON(&doc); err != nil {
+	var req models.DocumentUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	err := h.service.UpdateDocument(&doc)
+	revision, err := h.service.UpdateDocument(c.Param("id"), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		case err.Error() == "invalid_author", err.Error() == "invalid_reason":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Document updated successfully"})
+	// 内容没有变化时不记录修订，revision 为空
+	c.JSON(http.StatusOK, gin.H{"message": "Document updated successfully", "revision": revision})
 }
 
 // DeleteDocument 删除文档
@@ -79,6 +105,10 @@ func (h *DocumentHandler) DeleteDocument(c *gin.Context) {
 	id := c.Param("id")
 	err := h.service.DeleteDocument(id)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -86,7 +116,7 @@ func (h *DocumentHandler) DeleteDocument(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Document deleted successfully"})
 }
 
-// GetDocumentIDs 获取文档ID列表（用于翻页）
+// GetDocumentIDs 获取文档ID列表，使用上次返回的 prev_cursor 或 next_cursor 翻页
 func (h *DocumentHandler) GetDocumentIDs(c *gin.Context) {
 	var req models.DocumentIDsRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
@@ -94,18 +124,66 @@ func (h *DocumentHandler) GetDocumentIDs(c *gin.Context) {
 		return
 	}
 
-	limit := req.Limit
-	if limit <= 0 {
-		limit = 10
+	response, err := h.service.GetDocumentIDs(req.ConversationID, req.Cursor, req.BeforeID, req.Limit)
+	if err != nil {
+		writePageError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// writePageError 返回翻页接口的错误，游标或 before_id 无效时返回400
+func writePageError(c *gin.Context, err error) {
+	if errors.Is(err, repository.ErrInvalidCursor) || err.Error() == "invalid_before_id" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// GetAlternatives 获取助手文档的候选版本列表
+func (h *DocumentHandler) GetAlternatives(c *gin.Context) {
+	id := c.Param("id")
+	response, err := h.service.GetAlternatives(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+			return
+		}
+		if err.Error() == "not_assistant_document" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// SelectAlternative 选用候选版本
+func (h *DocumentHandler) SelectAlternative(c *gin.Context) {
+	id := c.Param("id")
+	var req models.SelectAlternativeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	documentIDs, err := h.service.GetDocumentIDsByConversationID(req.ConversationID, req.BeforeID, limit)
+	err := h.service.SelectAlternative(id, req.AlternativeID)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+			return
+		}
+		if err.Error() == "not_assistant_document" || err.Error() == "alternative_mismatch" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, models.DocumentIDsResponse{
-		DocumentIDs: documentIDs,
-	})
+	c.JSON(http.StatusOK, gin.H{"message": "Alternative selected successfully"})
 }
@@ -1,50 +1,44 @@
 package document
 
 import (
+	"errors"
 	"grandma/backend/models"
+	"grandma/backend/modules/revision"
 	"grandma/backend/repository"
 	"grandma/backend/utils"
+
+	"gorm.io/gorm"
 )
 
 type DocumentService struct {
-	documentRepo *repository.DocumentRepository
+	documentRepo     *repository.DocumentRepository
+	conversationRepo *repository.ConversationRepository
+	revisionSvc      *revision.RevisionService
 }
 
-func NewDocumentService(documentRepo *repository.DocumentRepository) *DocumentService {
+func NewDocumentService(documentRepo *repository.DocumentRepository, conversationRepo *repository.ConversationRepository, revisionSvc *revision.RevisionService) *DocumentService {
 	return &DocumentService{
-		documentRepo: documentRepo,
+		documentRepo:     documentRepo,
+		conversationRepo: conversationRepo,
+		revisionSvc:      revisionSvc,
 	}
 }
 
-// GetDocumentList 获取文档列表（支持翻页）
-func (s *DocumentService) GetDocumentList(conversationID string, beforeID string, limit int) (*models.DocumentListResponse, error) {
-	// 如果没有指定limit，默认为10
-	if limit <= 0 {
-		limit = 10
+// GetDocumentList 按游标获取文档列表，未指定游标时返回最新的文档
+func (s *DocumentService) GetDocumentList(conversationID, cursor, beforeID string, limit int) (*models.DocumentListResponse, error) {
+	pageCursor, err := s.resolveCursor(conversationID, cursor, beforeID)
+	if err != nil {
+		return nil, err
 	}
 
-	// 如果没有指定beforeID，获取最新的文档（按created_at倒序，取前limit个，然后反转）
-	if beforeID == "" {
-		documents, err := s.documentRepo.GetLatestDocumentsByConversationID(conversationID, limit)
-		if err != nil {
-			return nil, err
-		}
-		// 反转顺序，使其按时间正序排列
-		for i, j := 0, len(documents)-1; i < j; i, j = i+1, j-1 {
-			documents[i], documents[j] = documents[j], documents[i]
-		}
-		return &models.DocumentListResponse{
-			Documents: documents,
-			Total:     len(documents),
-		}, nil
+	documents, page, err := s.documentRepo.ListPage(conversationID, pageCursor, pageLimit(limit))
+	if err != nil {
+		return nil, err
 	}
-
-	// 如果指定了beforeID，不应该使用这个方法，应该使用 /api/documents/ids 获取ID列表
-	// 然后前端并发请求每个文档
-	// 这里返回空列表，表示不支持此路径
 	return &models.DocumentListResponse{
-		Documents: []models.Document{},
-		Total:     0,
+		Documents: documents,
+		Total:     len(documents),
+		PageInfo:  *page,
 	}, nil
 }
 
@@ -53,16 +47,123 @@ func (s *DocumentService) GetDocumentByID(id string) (*models.Document, error) {
 	return s.documentRepo.GetByID(id)
 }
 
-// UpdateDocument 更新文档
-func (s *DocumentService) UpdateDocument(document *models.Document) error {
-	return s.documentRepo.Update(document)
+// GetDocumentsByIDs 批量获取文档，结果与请求的ID顺序一致，不存在的文档标记为未找到
+func (s *DocumentService) GetDocumentsByIDs(ids []string) (*models.BatchDocumentsResponse, error) {
+	if len(ids) == 0 {
+		return nil, errors.New("empty_ids")
+	}
+	if len(ids) > models.MaxBatchDocuments {
+		return nil, errors.New("too_many_ids")
+	}
+
+	documents, err := s.documentRepo.GetByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*models.Document, len(documents))
+	for i := range documents {
+		byID[documents[i].ID] = &documents[i]
+	}
+
+	results := make([]models.BatchDocumentResult, len(ids))
+	for i, id := range ids {
+		doc := byID[id]
+		results[i] = models.BatchDocumentResult{ID: id, Found: doc != nil, Document: doc}
+	}
+	return &models.BatchDocumentsResponse{Documents: results}, nil
+}
+
+// UpdateDocument 修改文档内容，内容有变化时记录修改人和修改原因，返回新的修订
+func (s *DocumentService) UpdateDocument(id string, req *models.DocumentUpdateRequest) (*models.Revision, error) {
+	return s.revisionSvc.UpdateDocument(id, *req.Content, req.Author, req.Reason)
 }
 
 // DeleteDocument 删除文档
 func (s *DocumentService) DeleteDocument(id string) error {
+	doc, err := s.documentRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	// 删除的是正在选用的候选版本时，恢复原始文档的内容
+	if doc.AlternativeOf != "" {
+		original, err := s.documentRepo.GetByID(doc.AlternativeOf)
+		if err == nil && original.ActiveAlternativeID == id {
+			if err := s.documentRepo.SetActiveAlternative(original.ID, ""); err != nil {
+				return err
+			}
+		}
+	}
+	// 删除的是活动分支的最后一条文档时，活动分支回退到其父文档
+	conversation, err := s.conversationRepo.GetByID(doc.ConversationID)
+	if err == nil && conversation.HeadDocumentID == id {
+		if err := s.conversationRepo.UpdateHeadDocumentID(conversation.ID, doc.ParentID); err != nil {
+			return err
+		}
+	}
 	return s.documentRepo.Delete(id)
 }
 
+// GetAlternatives 获取助手文档的所有候选版本（包括原始文档）
+func (s *DocumentService) GetAlternatives(id string) (*models.AlternativesResponse, error) {
+	original, err := s.getOriginalDocument(id)
+	if err != nil {
+		return nil, err
+	}
+
+	alternatives, err := s.documentRepo.GetAlternatives(original.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	activeID := original.ActiveAlternativeID
+	if activeID == "" {
+		activeID = original.ID
+	}
+
+	return &models.AlternativesResponse{
+		OriginalID:   original.ID,
+		ActiveID:     activeID,
+		Alternatives: append([]models.Document{*original}, alternatives...),
+	}, nil
+}
+
+// SelectAlternative 选用某个候选版本作为后续对话上下文使用的内容
+func (s *DocumentService) SelectAlternative(id, alternativeID string) error {
+	original, err := s.getOriginalDocument(id)
+	if err != nil {
+		return err
+	}
+
+	// 选择原始文档本身表示恢复原始内容
+	if alternativeID == original.ID {
+		return s.documentRepo.SetActiveAlternative(original.ID, "")
+	}
+
+	alternative, err := s.documentRepo.GetByID(alternativeID)
+	if err != nil {
+		return err
+	}
+	if alternative.AlternativeOf != original.ID {
+		return errors.New("alternative_mismatch")
+	}
+	return s.documentRepo.SetActiveAlternative(original.ID, alternativeID)
+}
+
+// getOriginalDocument 获取助手文档对应的原始文档，id可以是原始文档或其候选版本
+func (s *DocumentService) getOriginalDocument(id string) (*models.Document, error) {
+	doc, err := s.documentRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Role != "assistant" {
+		return nil, errors.New("not_assistant_document")
+	}
+	if doc.AlternativeOf != "" {
+		return s.documentRepo.GetByID(doc.AlternativeOf)
+	}
+	return doc, nil
+}
+
 // CreateDocument 创建文档
 func (s *DocumentService) CreateDocument(conversationID, role, content, model string) (*models.Document, error) {
 	doc := &models.Document{
@@ -79,7 +180,53 @@ func (s *DocumentService) CreateDocument(conversationID, role, content, model st
 	return doc, nil
 }
 
-// GetDocumentIDsByConversationID 获取对话的文档ID列表（用于翻页）
-func (s *DocumentService) GetDocumentIDsByConversationID(conversationID string, beforeDocumentID string, limit int) ([]string, error) {
-	return s.documentRepo.GetDocumentIDsByConversationID(conversationID, beforeDocumentID, limit)
+// GetDocumentIDs 按游标获取对话的文档ID列表，未指定游标时返回最新的文档ID
+func (s *DocumentService) GetDocumentIDs(conversationID, cursor, beforeID string, limit int) (*models.DocumentIDsResponse, error) {
+	pageCursor, err := s.resolveCursor(conversationID, cursor, beforeID)
+	if err != nil {
+		return nil, err
+	}
+
+	documents, page, err := s.documentRepo.ListIDPage(conversationID, pageCursor, pageLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	documentIDs := make([]string, len(documents))
+	for i, doc := range documents {
+		documentIDs[i] = doc.ID
+	}
+	return &models.DocumentIDsResponse{
+		DocumentIDs: documentIDs,
+		PageInfo:    *page,
+	}, nil
+}
+
+// resolveCursor 解析翻页游标；未指定游标时兼容旧接口的 before_id，从该文档开始向前翻页
+// before_id 不存在或不属于该对话时返回 invalid_before_id
+func (s *DocumentService) resolveCursor(conversationID, cursor, beforeID string) (string, error) {
+	if cursor != "" || beforeID == "" {
+		return cursor, nil
+	}
+	doc, err := s.documentRepo.GetByID(beforeID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", errors.New("invalid_before_id")
+		}
+		return "", err
+	}
+	if doc.ConversationID != conversationID {
+		return "", errors.New("invalid_before_id")
+	}
+	return repository.DocumentCursor(doc, repository.CursorBefore), nil
+}
+
+// pageLimit 每页数量，默认10，最多100
+func pageLimit(limit int) int {
+	if limit <= 0 {
+		return 10
+	}
+	if limit > 100 {
+		return 100
+	}
+	return limit
 }
@@ -0,0 +1,74 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	render "grandma/backend/export"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ExportHandler struct {
+	service *ExportService
+}
+
+func NewExportHandler(service *ExportService) *ExportHandler {
+	return &ExportHandler{
+		service: service,
+	}
+}
+
+// ExportConversation 导出单个对话，format 为 md（默认）、html 或 json
+func (h *ExportHandler) ExportConversation(c *gin.Context) {
+	format := c.DefaultQuery("format", render.FormatMarkdown)
+	transcript, err := h.service.ExportConversation(c.Param("id"), format)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+		case err.Error() == "invalid_format":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "formats": render.Formats})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.Header("Content-Type", render.ContentType(format))
+	c.Header("Content-Disposition", attachment(render.Filename(transcript, format), transcript.ID+"."+format))
+	c.Status(http.StatusOK)
+	if err := render.Render(c.Writer, transcript, format); err != nil {
+		log.Printf("[export_handler ExportConversation] Failed to render %s: %v", transcript.ID, err)
+	}
+}
+
+// ExportAll 将所有对话导出为 zip 压缩包，边生成边下载
+func (h *ExportHandler) ExportAll(c *gin.Context) {
+	format := c.DefaultQuery("format", render.FormatMarkdown)
+	if !render.ValidFormat(format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_format", "formats": render.Formats})
+		return
+	}
+
+	filename := fmt.Sprintf("conversations-%s.zip", time.Now().Format("20060102-150405"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", attachment(filename, filename))
+	c.Status(http.StatusOK)
+	// 响应头已经发出，出错时只能中断下载，客户端会得到不完整的压缩包
+	count, err := h.service.ExportAll(c.Writer, format)
+	if err != nil {
+		log.Printf("[export_handler ExportAll] Export aborted after %d conversations: %v", count, err)
+		return
+	}
+	log.Printf("[export_handler ExportAll] Exported %d conversations as %s", count, format)
+}
+
+// attachment 生成下载文件的 Content-Disposition，非ASCII文件名使用 RFC 5987 编码，fallback 供不支持的客户端使用
+func attachment(filename, fallback string) string {
+	return fmt.Sprintf("attachment; filename=%q; filename*=UTF-8''%s", fallback, url.PathEscape(filename))
+}
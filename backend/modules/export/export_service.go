@@ -0,0 +1,158 @@
+package export
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	render "grandma/backend/export"
+	"grandma/backend/models"
+	"grandma/backend/repository"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+const exportBatchSize = 50 // 批量导出时每批加载的对话数
+
+type ExportService struct {
+	conversationRepo *repository.ConversationRepository
+	documentRepo     *repository.DocumentRepository
+}
+
+func NewExportService(conversationRepo *repository.ConversationRepository, documentRepo *repository.DocumentRepository) *ExportService {
+	return &ExportService{
+		conversationRepo: conversationRepo,
+		documentRepo:     documentRepo,
+	}
+}
+
+// ExportConversation 导出对话记录，format 不支持时返回 invalid_format
+func (s *ExportService) ExportConversation(id, format string) (*render.Transcript, error) {
+	if !render.ValidFormat(format) {
+		return nil, errors.New("invalid_format")
+	}
+	return s.Transcript(id, time.Now())
+}
+
+// Transcript 生成对话当前活动分支的记录，不包括回收站中的对话
+func (s *ExportService) Transcript(id string, exportedAt time.Time) (*render.Transcript, error) {
+	conversation, err := s.conversationRepo.GetMetaByID(id)
+	if err != nil {
+		return nil, err
+	}
+	conversation.Tags, err = s.conversationRepo.GetTags(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.buildTranscript(conversation, exportedAt)
+}
+
+// ExportAll 将所有对话（不含回收站中的）按指定格式导出，每个对话一个文件，写入 zip 压缩包
+// 压缩包边生成边写出，返回导出的对话数
+func (s *ExportService) ExportAll(w io.Writer, format string) (int, error) {
+	if !render.ValidFormat(format) {
+		return 0, errors.New("invalid_format")
+	}
+
+	archive := zip.NewWriter(w)
+	exportedAt := time.Now()
+	names := make(map[string]bool)
+	count := 0
+	err := s.conversationRepo.ForEach(exportBatchSize, func(conversations []models.Conversation) error {
+		for i := range conversations {
+			transcript, err := s.buildTranscript(&conversations[i], exportedAt)
+			if err != nil {
+				return err
+			}
+			file, err := archive.CreateHeader(&zip.FileHeader{
+				Name:     uniqueName(names, render.Filename(transcript, format)),
+				Method:   zip.Deflate,
+				Modified: transcript.UpdatedAt,
+			})
+			if err != nil {
+				return err
+			}
+			if err := render.Render(file, transcript, format); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, archive.Close()
+}
+
+// buildTranscript 生成对话记录：沿当前活动分支从头到尾排列消息，选用了候选版本的回复使用候选版本的内容和模型
+func (s *ExportService) buildTranscript(conversation *models.Conversation, exportedAt time.Time) (*render.Transcript, error) {
+	documents, err := s.documentRepo.GetBranch(conversation.HeadDocumentID, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(documents) == 0 {
+		// 没有活动分支的旧对话按文档顺序导出
+		documents, err = s.documentRepo.GetByConversationID(conversation.ID)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		for i, j := 0, len(documents)-1; i < j; i, j = i+1, j-1 {
+			documents[i], documents[j] = documents[j], documents[i]
+		}
+	}
+
+	var alternativeIDs []string
+	for _, doc := range documents {
+		if doc.ActiveAlternativeID != "" {
+			alternativeIDs = append(alternativeIDs, doc.ActiveAlternativeID)
+		}
+	}
+	alternatives, err := s.documentRepo.GetByIDs(alternativeIDs)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]models.Document, len(alternatives))
+	for _, alternative := range alternatives {
+		byID[alternative.ID] = alternative
+	}
+
+	transcript := &render.Transcript{
+		Version:    render.TranscriptVersion,
+		ID:         conversation.ID,
+		Title:      conversation.Title,
+		Tags:       conversation.Tags,
+		CreatedAt:  conversation.CreatedAt,
+		UpdatedAt:  conversation.UpdatedAt,
+		ExportedAt: exportedAt,
+		Messages:   make([]render.Message, 0, len(documents)),
+	}
+	for _, doc := range documents {
+		if alternative, ok := byID[doc.ActiveAlternativeID]; ok {
+			doc.Content = alternative.Content
+			doc.Model = alternative.Model
+		}
+		transcript.Messages = append(transcript.Messages, render.Message{
+			ID:        doc.ID,
+			Role:      doc.Role,
+			Model:     doc.Model,
+			Content:   doc.Content,
+			CreatedAt: doc.CreatedAt,
+		})
+	}
+	return transcript, nil
+}
+
+// uniqueName 压缩包内的文件名重复时在扩展名前加序号
+func uniqueName(names map[string]bool, name string) string {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	unique := name
+	for i := 2; names[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+	names[unique] = true
+	return unique
+}
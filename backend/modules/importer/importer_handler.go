@@ -0,0 +1,64 @@
+package importer
+
+import (
+	"errors"
+	"grandma/backend/chatexport"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportSize 上传的导出文件大小上限
+const maxImportSize = 512 << 20
+
+type ImporterHandler struct {
+	service *ImporterService
+}
+
+func NewImporterHandler(service *ImporterService) *ImporterHandler {
+	return &ImporterHandler{
+		service: service,
+	}
+}
+
+// Import 导入 ChatGPT 或 Claude 的数据导出
+// 以 multipart 表单上传：file 为导出的 zip 压缩包或 conversations.json，source 为 chatgpt 或 claude（可选），dry_run=true 时只预览不保存
+func (h *ImporterHandler) Import(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file_too_large", "max_bytes": maxImportSize})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	summary, err := h.service.Import(data, c.PostForm("source"), c.PostForm("dry_run") == "true")
+	if err != nil {
+		switch {
+		case errors.Is(err, chatexport.ErrTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error(), "max_bytes": chatexport.MaxConversationsSize})
+		case errors.Is(err, chatexport.ErrUnknownFormat), err.Error() == "conversations_not_found", err.Error() == "invalid_source":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
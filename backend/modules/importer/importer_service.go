@@ -0,0 +1,149 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"grandma/backend/chatexport"
+	"grandma/backend/models"
+	"grandma/backend/repository"
+	"grandma/backend/utils"
+	"strings"
+)
+
+const (
+	defaultImportTitle = "导入的对话" // 来源中没有标题，且没有用户消息时使用的标题
+	maxTitleLength     = 30      // 用第一条用户消息生成标题时保留的字符数
+)
+
+type ImporterService struct {
+	conversationRepo *repository.ConversationRepository
+}
+
+func NewImporterService(conversationRepo *repository.ConversationRepository) *ImporterService {
+	return &ImporterService{
+		conversationRepo: conversationRepo,
+	}
+}
+
+// Import 导入 ChatGPT 或 Claude 的数据导出，source 为空时自动识别来源
+// 之前导入过的对话（包括已移入回收站的）按来源中的ID跳过，来源中没有ID的按内容生成的ID跳过；dryRun 为true时只解析和检查重复，不保存
+func (s *ImporterService) Import(data []byte, source string, dryRun bool) (*models.ImportSummary, error) {
+	if source != "" && source != chatexport.SourceChatGPT && source != chatexport.SourceClaude {
+		return nil, errors.New("invalid_source")
+	}
+	archive, err := chatexport.Parse(data, source)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceIDs := make([]string, 0, len(archive.Conversations))
+	for _, conversation := range archive.Conversations {
+		sourceIDs = append(sourceIDs, conversation.SourceID)
+	}
+	imported, err := s.conversationRepo.GetImportedIDs(archive.Source, sourceIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &models.ImportSummary{
+		Source:  archive.Source,
+		DryRun:  dryRun,
+		Total:   len(archive.Conversations),
+		Results: make([]models.ImportResult, 0, len(archive.Conversations)),
+	}
+	for i := range archive.Conversations {
+		item := &archive.Conversations[i]
+		conversation, documents := convert(archive.Source, item)
+		result := models.ImportResult{
+			SourceID: item.SourceID,
+			Title:    conversation.Title,
+			Messages: len(documents),
+		}
+
+		switch {
+		case imported[item.SourceID] != "":
+			result.Status = models.ImportStatusDuplicate
+			result.ConversationID = imported[item.SourceID]
+			result.Messages = 0
+			summary.Duplicates++
+		case len(documents) == 0:
+			result.Status = models.ImportStatusEmpty
+			summary.Empty++
+		case dryRun:
+			result.Status = models.ImportStatusImported
+			summary.Imported++
+			summary.Messages += len(documents)
+		default:
+			if err := s.conversationRepo.Import(conversation, documents); err != nil {
+				fmt.Printf("[importer_service Import] Failed to import %s %s: %+v\n", archive.Source, item.SourceID, err)
+				result.Status = models.ImportStatusFailed
+				result.Error = err.Error()
+				result.Messages = 0
+				summary.Failed++
+				break
+			}
+			// 同一份导出中重复的对话只导入一次
+			imported[item.SourceID] = conversation.ID
+			result.Status = models.ImportStatusImported
+			result.ConversationID = conversation.ID
+			summary.Imported++
+			summary.Messages += len(documents)
+		}
+		summary.Results = append(summary.Results, result)
+	}
+	return summary, nil
+}
+
+// convert 将导出数据中的对话转换为对话和文档：保留原来的分支、时间和模型，当前分支的最后一条消息作为对话的当前位置
+func convert(source string, from *chatexport.Conversation) (*models.Conversation, []models.Document) {
+	conversation := &models.Conversation{
+		ID:        utils.GenerateConversationID(),
+		Title:     importTitle(from),
+		Source:    source,
+		SourceID:  from.SourceID,
+		CreatedAt: from.CreatedAt,
+		UpdatedAt: from.UpdatedAt,
+	}
+
+	// 消息按时间排列，上一条消息总在前面，因此可以按顺序生成文档
+	documents := make([]models.Document, 0, len(from.Messages))
+	for i, msg := range from.Messages {
+		seq := i + 1
+		doc := models.Document{
+			ID:             utils.GenerateDocumentID(),
+			ConversationID: conversation.ID,
+			Role:           msg.Role,
+			Content:        msg.Content,
+			Model:          msg.Model,
+			Seq:            &seq,
+			CreatedAt:      msg.CreatedAt,
+			UpdatedAt:      msg.CreatedAt,
+		}
+		if msg.Parent >= 0 {
+			doc.ParentID = documents[msg.Parent].ID
+		}
+		documents = append(documents, doc)
+	}
+	if from.Current >= 0 && from.Current < len(documents) {
+		conversation.HeadDocumentID = documents[from.Current].ID
+	}
+	return conversation, documents
+}
+
+// importTitle 导入后的标题：来源中没有标题时使用第一条用户消息的开头
+func importTitle(from *chatexport.Conversation) string {
+	if from.Title != "" {
+		return from.Title
+	}
+	for _, msg := range from.Messages {
+		if msg.Role != "user" {
+			continue
+		}
+		title := strings.Join(strings.Fields(msg.Content), " ")
+		if runes := []rune(title); len(runes) > maxTitleLength {
+			title = string(runes[:maxTitleLength]) + "…"
+		}
+		return title
+	}
+	return defaultImportTitle
+}
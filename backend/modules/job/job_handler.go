@@ -0,0 +1,126 @@
+package job
+
+import (
+	"errors"
+	"grandma/backend/models"
+	"grandma/backend/modules/chat"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type JobHandler struct {
+	service *JobService
+}
+
+func NewJobHandler(service *JobService) *JobHandler {
+	return &JobHandler{
+		service: service,
+	}
+}
+
+// SubmitJob 提交后台任务，立即返回排队中的任务
+func (h *JobHandler) SubmitJob(c *gin.Context) {
+	var req models.SubmitJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateSubmitRequest(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	job, err := h.service.Submit(&req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+// ListJobs 获取后台任务列表
+func (h *JobHandler) ListJobs(c *gin.Context) {
+	var req models.JobListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.service.ListJobs(req.Status, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetJob 获取后台任务的进度和结果
+func (h *JobHandler) GetJob(c *gin.Context) {
+	response, err := h.service.GetJob(c.Param("id"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CancelJob 取消后台任务
+func (h *JobHandler) CancelJob(c *gin.Context) {
+	job, err := h.service.CancelJob(c.Param("id"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// RetryJob 重新执行失败或已取消的后台任务
+func (h *JobHandler) RetryJob(c *gin.Context) {
+	job, err := h.service.RetryJob(c.Param("id"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// validateSubmitRequest 校验任务参数，chat 任务按聊天接口的规则校验
+func validateSubmitRequest(req *models.SubmitJobRequest) error {
+	switch req.Type {
+	case models.JobTypeChat:
+		if req.Request == nil {
+			return errors.New("request is required")
+		}
+		if err := chat.ValidateChatRequest(req.Request); err != nil {
+			return err
+		}
+		if req.Request.Type == models.ChatTypeCompare {
+			return errors.New("compare is not supported in jobs")
+		}
+	case models.JobTypeWorkflow:
+		if req.ProjectID == "" {
+			return errors.New("project_id is required")
+		}
+	default:
+		return errors.New("unsupported job type: " + req.Type)
+	}
+	return nil
+}
+
+// writeError 根据错误类型返回不同的状态码
+func writeError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return
+	}
+	switch err.Error() {
+	case "job_finished", "job_not_finished", "job_conflict":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case "invalid_stage", "document_id_required", "unsupported_job_type":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,585 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"grandma/backend/events"
+	"grandma/backend/models"
+	"grandma/backend/modules/chat"
+	"grandma/backend/modules/workflow"
+	"grandma/backend/repository"
+	"grandma/backend/utils"
+	"log"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultJobLimit   = 50
+	maxJobLimit       = 200
+	pollInterval      = 2 * time.Second  // 没有新任务通知时检查队列的间隔
+	retryDelay        = 10 * time.Second // 失败后第n次重试延后n倍的时间
+	busyRetryDelay    = 5 * time.Second  // 对话有进行中的回复时延后的时间，不计入执行次数
+	progressInterval  = time.Second      // 生成字符数写入数据库的最小间隔
+	maxStatusAttempts = 3                // 取消、重试时任务状态同时被改变，重新读取任务的最多次数
+)
+
+// 不可重试的错误：重试也不会成功
+var permanentErrors = map[string]bool{
+	"invalid_stage":          true,
+	"not_assistant_document": true,
+	"document_id_required":   true,
+	// 斜杠命令的错误
+	"conversation_id_required": true,
+	"no_assistant_document":    true,
+	"duplicate_story":          true,
+}
+
+type JobConfig struct {
+	Workers     int // 工作协程数
+	MaxAttempts int // 默认最多执行的次数
+}
+
+type JobService struct {
+	jobRepo          *repository.JobRepository
+	conversationRepo *repository.ConversationRepository
+	documentRepo     *repository.DocumentRepository
+	projectRepo      *repository.StoryProjectRepository
+	chatService      *chat.ChatService
+	workflowService  *workflow.WorkflowService
+	config           *JobConfig
+	events           *events.Hub
+	wake             chan struct{}
+	claimMu          sync.Mutex // 串行领取任务，避免SQLite写冲突
+	runningMu        sync.Mutex
+	running          map[string]context.CancelFunc // 执行中的任务，用于取消
+}
+
+func NewJobService(jobRepo *repository.JobRepository, conversationRepo *repository.ConversationRepository, documentRepo *repository.DocumentRepository, projectRepo *repository.StoryProjectRepository, chatService *chat.ChatService, workflowService *workflow.WorkflowService, config *JobConfig, hub *events.Hub) *JobService {
+	return &JobService{
+		jobRepo:          jobRepo,
+		conversationRepo: conversationRepo,
+		documentRepo:     documentRepo,
+		projectRepo:      projectRepo,
+		chatService:      chatService,
+		workflowService:  workflowService,
+		config:           config,
+		events:           hub,
+		wake:             make(chan struct{}, 1),
+		running:          make(map[string]context.CancelFunc),
+	}
+}
+
+// Start 将上次运行中断的任务重新排队，并启动工作协程
+func (s *JobService) Start(ctx context.Context) {
+	requeued, err := s.jobRepo.RequeueRunning()
+	if err != nil {
+		log.Printf("[job_service Start] Failed to requeue interrupted jobs: %v", err)
+	} else if requeued > 0 {
+		log.Printf("[job_service Start] Requeued %d interrupted jobs", requeued)
+	}
+
+	workers := s.config.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go s.worker(ctx)
+	}
+}
+
+// Submit 提交后台任务，chat 任务的请求需已通过 chat.ValidateChatRequest 校验
+func (s *JobService) Submit(req *models.SubmitJobRequest) (*models.Job, error) {
+	job := &models.Job{
+		ID:          utils.GenerateJobID(),
+		Type:        req.Type,
+		Status:      models.JobStatusQueued,
+		MaxAttempts: req.MaxAttempts,
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = s.config.MaxAttempts
+	}
+
+	switch req.Type {
+	case models.JobTypeChat:
+		if err := s.prepareChat(job, req.Request); err != nil {
+			return nil, err
+		}
+	case models.JobTypeWorkflow:
+		project, err := s.projectRepo.GetByID(req.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		if project.Stage == models.StageCompleted {
+			return nil, errors.New("invalid_stage")
+		}
+		job.ProjectID = project.ID
+		job.ConversationID = project.ConversationID
+		job.Total = project.ChapterCount + 1 // 大纲和各章
+		job.Progress = completedSteps(project)
+	default:
+		return nil, errors.New("unsupported_job_type")
+	}
+
+	if err := s.jobRepo.Create(job); err != nil {
+		return nil, err
+	}
+	s.publish(job)
+	s.notify()
+	return job, nil
+}
+
+// prepareChat 确定 chat 任务所属的对话：未指定对话的新消息先创建对话，便于重试时判断已保存的内容
+func (s *JobService) prepareChat(job *models.Job, req *models.ChatRequest) error {
+	switch {
+	case req.Type == models.ChatTypeContinue && req.DocumentID != "":
+		doc, err := s.documentRepo.GetByID(req.DocumentID)
+		if err != nil {
+			return err
+		}
+		req.ConversationID = doc.ConversationID
+	case req.ConversationID != "":
+		if _, err := s.conversationRepo.GetMetaByID(req.ConversationID); err != nil {
+			return err
+		}
+	case req.Type == models.ChatTypeMessage:
+		conversation, err := s.chatService.CreateConversation(req.Messages)
+		if err != nil {
+			return err
+		}
+		req.ConversationID = conversation.ID
+	default:
+		return errors.New("document_id_required")
+	}
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	job.ConversationID = req.ConversationID
+	job.Payload = string(payload)
+	job.Total = 1
+	return nil
+}
+
+// GetJob 获取任务详情
+func (s *JobService) GetJob(id string) (*models.JobResponse, error) {
+	job, err := s.jobRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	response := &models.JobResponse{Job: *job}
+	if job.Payload != "" {
+		response.Payload = json.RawMessage(job.Payload)
+	}
+	if job.Result != "" {
+		response.Result = json.RawMessage(job.Result)
+	}
+	return response, nil
+}
+
+// ListJobs 获取任务列表，新任务在前
+func (s *JobService) ListJobs(status string, limit int) (*models.JobListResponse, error) {
+	if limit <= 0 {
+		limit = defaultJobLimit
+	}
+	if limit > maxJobLimit {
+		limit = maxJobLimit
+	}
+	jobs, err := s.jobRepo.List(status, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &models.JobListResponse{Jobs: jobs}, nil
+}
+
+// CancelJob 取消排队或执行中的任务，执行中的任务已生成的内容会保留
+// 取消的同时任务状态被执行线程改变时（例如刚开始执行），重新读取任务后再试
+func (s *JobService) CancelJob(id string) (*models.Job, error) {
+	for attempt := 0; attempt < maxStatusAttempts; attempt++ {
+		job, err := s.jobRepo.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+		if job.Status != models.JobStatusQueued && job.Status != models.JobStatusRunning {
+			return nil, errors.New("job_finished")
+		}
+
+		updated, err := s.jobRepo.UpdateStatus(id, job.Status, map[string]interface{}{
+			"status":      models.JobStatusCancelled,
+			"finished_at": time.Now(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !updated {
+			continue
+		}
+		s.runningMu.Lock()
+		if cancel, ok := s.running[id]; ok {
+			cancel()
+		}
+		s.runningMu.Unlock()
+		return s.reload(id)
+	}
+	return nil, errors.New("job_conflict")
+}
+
+// RetryJob 重新执行失败或已取消的任务，执行次数累计，允许再执行默认的最多次数
+// 同时有其他请求重试了该任务时返回 job_not_finished
+func (s *JobService) RetryJob(id string) (*models.Job, error) {
+	for attempt := 0; attempt < maxStatusAttempts; attempt++ {
+		job, err := s.jobRepo.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+		if job.Status != models.JobStatusFailed && job.Status != models.JobStatusCancelled {
+			return nil, errors.New("job_not_finished")
+		}
+
+		updated, err := s.jobRepo.UpdateStatus(id, job.Status, map[string]interface{}{
+			"status":       models.JobStatusQueued,
+			"max_attempts": job.Attempts + s.config.MaxAttempts,
+			"run_after":    time.Now(),
+			"finished_at":  nil,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !updated {
+			continue
+		}
+		s.notify()
+		return s.reload(id)
+	}
+	return nil, errors.New("job_conflict")
+}
+
+// worker 循环领取并执行任务
+func (s *JobService) worker(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		s.claimMu.Lock()
+		job, err := s.jobRepo.ClaimNext()
+		s.claimMu.Unlock()
+		if err == nil {
+			s.run(ctx, job)
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("[job_service worker] Failed to claim job: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// notify 通知空闲的工作协程有新任务
+func (s *JobService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run 执行一次任务并根据结果更新状态
+func (s *JobService) run(parent context.Context, job *models.Job) {
+	ctx, cancel := context.WithCancel(parent)
+	s.runningMu.Lock()
+	s.running[job.ID] = cancel
+	s.runningMu.Unlock()
+	defer func() {
+		s.runningMu.Lock()
+		delete(s.running, job.ID)
+		s.runningMu.Unlock()
+		cancel()
+	}()
+
+	log.Printf("[job_service run] Job %s (%s) attempt %d/%d", job.ID, job.Type, job.Attempts, job.MaxAttempts)
+	s.publish(job)
+	progress := &jobProgress{service: s, job: job}
+
+	var result interface{}
+	var err error
+	switch job.Type {
+	case models.JobTypeChat:
+		result, err = s.runChat(ctx, job, progress)
+	case models.JobTypeWorkflow:
+		result, err = s.runWorkflow(ctx, job, progress)
+	default:
+		err = errors.New("unsupported_job_type")
+	}
+	if ctx.Err() != nil && parent.Err() == nil {
+		// 任务已被取消，状态在取消时已更新
+		s.publish(job)
+		return
+	}
+	s.finish(job, result, err)
+}
+
+// finish 保存执行结果：成功时保存结果，失败时按重试策略重新排队或标记为失败
+func (s *JobService) finish(job *models.Job, result interface{}, runErr error) {
+	now := time.Now()
+	fields := map[string]interface{}{}
+	switch {
+	case runErr == nil:
+		data, err := json.Marshal(result)
+		if err != nil {
+			log.Printf("[job_service finish] Failed to encode result of %s: %v", job.ID, err)
+		}
+		fields["status"] = models.JobStatusSucceeded
+		fields["result"] = string(data)
+		fields["progress"] = job.Total
+		fields["error"] = ""
+		fields["finished_at"] = now
+	case errors.Is(runErr, chat.ErrConversationBusy):
+		// 对话正在生成其他回复，稍后再试，不计入执行次数
+		fields["status"] = models.JobStatusQueued
+		fields["attempts"] = job.Attempts - 1
+		fields["run_after"] = now.Add(busyRetryDelay)
+		fields["error"] = runErr.Error()
+	case job.Attempts < job.MaxAttempts && retryable(runErr):
+		fields["status"] = models.JobStatusQueued
+		fields["run_after"] = now.Add(time.Duration(job.Attempts) * retryDelay)
+		fields["error"] = runErr.Error()
+	default:
+		fields["status"] = models.JobStatusFailed
+		fields["error"] = runErr.Error()
+		fields["finished_at"] = now
+	}
+	if runErr != nil {
+		log.Printf("[job_service finish] Job %s attempt %d failed: %v", job.ID, job.Attempts, runErr)
+	}
+
+	// 只更新仍在执行中的任务，执行期间被取消的任务保持取消状态
+	updated, err := s.jobRepo.UpdateStatus(job.ID, models.JobStatusRunning, fields)
+	if err != nil {
+		log.Printf("[job_service finish] Failed to update job %s: %v", job.ID, err)
+		return
+	}
+	if !updated {
+		log.Printf("[job_service finish] Job %s is no longer running (cancelled), status not updated", job.ID)
+	}
+	if current, err := s.jobRepo.GetByID(job.ID); err == nil {
+		s.publish(current)
+	}
+}
+
+// retryable 判断失败的任务是否值得重试
+func retryable(err error) bool {
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, chat.ErrContentBlocked) {
+		return false
+	}
+	return !permanentErrors[err.Error()]
+}
+
+// runChat 执行 chat 任务；重试时根据上一次执行已保存的内容续写或直接生成回复，避免重复保存用户消息
+func (s *JobService) runChat(ctx context.Context, job *models.Job, progress *jobProgress) (interface{}, error) {
+	var req models.ChatRequest
+	if err := json.Unmarshal([]byte(job.Payload), &req); err != nil {
+		return nil, err
+	}
+
+	if job.Attempts > 1 {
+		response, err := s.resumeChat(job, &req)
+		if err != nil || response != nil {
+			return response, err
+		}
+	} else if req.Type == models.ChatTypeMessage {
+		conversation, err := s.conversationRepo.GetMetaByID(job.ConversationID)
+		if err != nil {
+			return nil, err
+		}
+		job.BaseDocumentID = conversation.HeadDocumentID
+		if err := s.jobRepo.Updates(job.ID, map[string]interface{}{"base_document_id": job.BaseDocumentID}); err != nil {
+			return nil, err
+		}
+	}
+
+	progress.step("generating", 0)
+	response, err := s.chatService.Chat(ctx, &req, progress)
+	if err != nil {
+		return nil, err
+	}
+	progress.flush()
+	// 记录生成的文档，生成失败时下次从该文档续写
+	job.DocumentID = response.DocumentID
+	if err := s.jobRepo.Updates(job.ID, map[string]interface{}{"document_id": response.DocumentID}); err != nil {
+		return nil, err
+	}
+	if response.FinishReason == "error" || response.FinishReason == "cancelled" {
+		return nil, errors.New("generation_failed")
+	}
+	return response, nil
+}
+
+// resumeChat 根据上一次执行已保存的内容调整请求；上一次已生成完整回复时直接返回该回复
+func (s *JobService) resumeChat(job *models.Job, req *models.ChatRequest) (*models.ChatResponse, error) {
+	// 已生成部分内容：从该文档续写
+	if job.DocumentID != "" {
+		*req = models.ChatRequest{
+			ConversationID: job.ConversationID,
+			Model:          req.Model,
+			Type:           models.ChatTypeContinue,
+			DocumentID:     job.DocumentID,
+		}
+		return nil, nil
+	}
+	if req.Type != models.ChatTypeMessage {
+		return nil, nil
+	}
+
+	conversation, err := s.conversationRepo.GetMetaByID(job.ConversationID)
+	if err != nil {
+		return nil, err
+	}
+	if conversation.HeadDocumentID == job.BaseDocumentID {
+		// 上一次没有保存任何内容，重新发送
+		return nil, nil
+	}
+	head, err := s.documentRepo.GetByID(conversation.HeadDocumentID)
+	if err != nil {
+		return nil, err
+	}
+	switch head.Role {
+	case "user":
+		if head.ParentID == job.BaseDocumentID {
+			// 用户消息已保存而回复未完成：删除中断时未接入分支的回复，以活动分支为上下文直接生成回复
+			children, err := s.documentRepo.GetChildren(head.ID)
+			if err != nil {
+				return nil, err
+			}
+			for _, child := range children {
+				if err := s.documentRepo.Purge(child.ID); err != nil {
+					return nil, err
+				}
+			}
+			req.Messages = nil
+		}
+	case "assistant":
+		if parent, err := s.documentRepo.GetByID(head.ParentID); err == nil && parent.ParentID == job.BaseDocumentID {
+			// 回复已生成完毕，只是任务状态没有保存
+			job.DocumentID = head.ID
+			if err := s.jobRepo.Updates(job.ID, map[string]interface{}{"document_id": head.ID}); err != nil {
+				return nil, err
+			}
+			return &models.ChatResponse{
+				ConversationID: job.ConversationID,
+				UserDocumentID: parent.ID,
+				DocumentID:     head.ID,
+				Content:        head.Content,
+				Model:          head.Model,
+				FinishReason:   "stop",
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// runWorkflow 执行故事创作项目剩余的全部步骤；各步骤完成后即保存，重试时从未完成的步骤继续
+func (s *JobService) runWorkflow(ctx context.Context, job *models.Job, progress *jobProgress) (interface{}, error) {
+	project, err := s.projectRepo.GetByID(job.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	done := completedSteps(project)
+	progress.step("", done)
+
+	emit := func(event models.WorkflowEvent) {
+		switch event.Type {
+		case workflow.EventStep:
+			step := event.Stage
+			if event.Chapter > 0 {
+				step = fmt.Sprintf("chapter_%d", event.Chapter)
+			}
+			progress.step(step, done)
+		case workflow.EventDelta:
+			progress.Write([]byte(event.Content))
+		case workflow.EventStepCompleted:
+			done++
+			progress.step("", done)
+		}
+	}
+	return s.workflowService.Run(ctx, job.ProjectID, emit)
+}
+
+// completedSteps 项目已完成的步骤数：大纲和已生成的各章
+func completedSteps(project *models.StoryProject) int {
+	done := len(project.Chapters)
+	if project.Stage != models.StageOutline {
+		done++
+	}
+	return done
+}
+
+// reload 重新读取任务并推送更新事件
+func (s *JobService) reload(id string) (*models.Job, error) {
+	job, err := s.jobRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(job)
+	return job, nil
+}
+
+// publish 推送任务更新事件
+func (s *JobService) publish(job *models.Job) {
+	s.events.Publish(events.Event{
+		Type:           events.TypeJobUpdated,
+		ConversationID: job.ConversationID,
+		Data:           job,
+	})
+}
+
+// jobProgress 记录任务的当前步骤和已生成的字符数
+type jobProgress struct {
+	service  *JobService
+	job      *models.Job
+	chars    int
+	lastSave time.Time
+}
+
+func (p *jobProgress) Write(b []byte) (int, error) {
+	p.chars += utf8.RuneCount(b)
+	if time.Since(p.lastSave) >= progressInterval {
+		p.save(map[string]interface{}{"generated_chars": p.chars})
+	}
+	return len(b), nil
+}
+
+// flush 保存已生成的字符数
+func (p *jobProgress) flush() {
+	p.save(map[string]interface{}{"generated_chars": p.chars})
+}
+
+// step 开始新的步骤
+func (p *jobProgress) step(step string, progress int) {
+	p.chars = 0
+	p.job.Step = step
+	p.job.Progress = progress
+	p.job.GeneratedChars = 0
+	p.save(map[string]interface{}{
+		"step":            step,
+		"progress":        progress,
+		"generated_chars": 0,
+	})
+	p.service.publish(p.job)
+}
+
+func (p *jobProgress) save(fields map[string]interface{}) {
+	p.lastSave = time.Now()
+	if err := p.service.jobRepo.Updates(p.job.ID, fields); err != nil {
+		log.Printf("[job_service progress] Failed to update job %s: %v", p.job.ID, err)
+	}
+}
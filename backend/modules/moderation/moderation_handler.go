@@ -0,0 +1,79 @@
+package moderation
+
+import (
+	"errors"
+	"grandma/backend/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ModerationHandler struct {
+	service *ModerationService
+}
+
+func NewModerationHandler(service *ModerationService) *ModerationHandler {
+	return &ModerationHandler{
+		service: service,
+	}
+}
+
+// GetRules 获取内容安全过滤规则
+func (h *ModerationHandler) GetRules(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.GetRules())
+}
+
+// ListInterventions 获取内容安全处理记录，可按对话筛选
+func (h *ModerationHandler) ListInterventions(c *gin.Context) {
+	var req models.InterventionListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.service.ListInterventions(req.ConversationID, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetDocumentInterventions 获取文档的内容安全处理记录
+func (h *ModerationHandler) GetDocumentInterventions(c *gin.Context) {
+	response, err := h.service.GetDocumentInterventions(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateAgeBand 设置对话内容安全过滤使用的年龄段
+func (h *ModerationHandler) UpdateAgeBand(c *gin.Context) {
+	var req models.UpdateAgeBandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.service.UpdateAgeBand(c.Param("id"), req.AgeBand)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+			return
+		}
+		if err.Error() == "invalid_age_band" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
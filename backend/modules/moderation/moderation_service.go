@@ -0,0 +1,93 @@
+package moderation
+
+import (
+	"errors"
+	"grandma/backend/models"
+	"grandma/backend/repository"
+	"grandma/backend/safety"
+)
+
+const (
+	defaultInterventionLimit = 50
+	maxInterventionLimit     = 200
+)
+
+type ModerationService struct {
+	safetyRepo       *repository.SafetyRepository
+	conversationRepo *repository.ConversationRepository
+	documentRepo     *repository.DocumentRepository
+	filter           *safety.Filter
+}
+
+func NewModerationService(safetyRepo *repository.SafetyRepository, conversationRepo *repository.ConversationRepository, documentRepo *repository.DocumentRepository, filter *safety.Filter) *ModerationService {
+	return &ModerationService{
+		safetyRepo:       safetyRepo,
+		conversationRepo: conversationRepo,
+		documentRepo:     documentRepo,
+		filter:           filter,
+	}
+}
+
+// GetRules 获取当前使用的过滤规则
+func (s *ModerationService) GetRules() *models.SafetyRulesResponse {
+	rules := make([]models.SafetyRuleInfo, 0)
+	for _, rule := range s.filter.Rules() {
+		rules = append(rules, models.SafetyRuleInfo{
+			ID:       rule.ID,
+			Category: rule.Category,
+			Keywords: rule.Keywords,
+			Patterns: rule.Patterns,
+			Actions:  rule.Actions,
+		})
+	}
+	return &models.SafetyRulesResponse{
+		Enabled:        s.filter.Enabled(),
+		DefaultAgeBand: s.filter.Band(""),
+		Rules:          rules,
+	}
+}
+
+// ListInterventions 获取处理记录，新记录在前
+func (s *ModerationService) ListInterventions(conversationID string, limit int) (*models.InterventionListResponse, error) {
+	if limit <= 0 {
+		limit = defaultInterventionLimit
+	}
+	if limit > maxInterventionLimit {
+		limit = maxInterventionLimit
+	}
+	interventions, err := s.safetyRepo.List(conversationID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &models.InterventionListResponse{Interventions: interventions}, nil
+}
+
+// GetDocumentInterventions 获取文档的处理记录
+func (s *ModerationService) GetDocumentInterventions(documentID string) (*models.InterventionListResponse, error) {
+	if _, err := s.documentRepo.GetByID(documentID); err != nil {
+		return nil, err
+	}
+	interventions, err := s.safetyRepo.GetByDocumentID(documentID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.InterventionListResponse{Interventions: interventions}, nil
+}
+
+// UpdateAgeBand 设置对话的年龄段，为空表示使用默认年龄段
+func (s *ModerationService) UpdateAgeBand(conversationID, ageBand string) (*models.AgeBandResponse, error) {
+	if ageBand != "" && !safety.ValidBand(ageBand) {
+		return nil, errors.New("invalid_age_band")
+	}
+	if _, err := s.conversationRepo.GetMetaByID(conversationID); err != nil {
+		return nil, err
+	}
+	if err := s.conversationRepo.UpdateAgeBand(conversationID, ageBand); err != nil {
+		return nil, err
+	}
+	return &models.AgeBandResponse{
+		ConversationID: conversationID,
+		AgeBand:        ageBand,
+		EffectiveBand:  s.filter.Band(ageBand),
+	}, nil
+}
@@ -0,0 +1,171 @@
+package organize
+
+import (
+	"errors"
+	"grandma/backend/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type OrganizeHandler struct {
+	service *OrganizeService
+}
+
+func NewOrganizeHandler(service *OrganizeService) *OrganizeHandler {
+	return &OrganizeHandler{
+		service: service,
+	}
+}
+
+// ListFolders 获取文件夹列表
+func (h *OrganizeHandler) ListFolders(c *gin.Context) {
+	response, err := h.service.ListFolders()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateFolder 创建文件夹
+func (h *OrganizeHandler) CreateFolder(c *gin.Context) {
+	var req models.FolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	folder, err := h.service.CreateFolder(req.Name)
+	if err != nil {
+		writeError(c, err, "Folder not found")
+		return
+	}
+	c.JSON(http.StatusOK, folder)
+}
+
+// RenameFolder 重命名文件夹
+func (h *OrganizeHandler) RenameFolder(c *gin.Context) {
+	var req models.FolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	folder, err := h.service.RenameFolder(c.Param("id"), req.Name)
+	if err != nil {
+		writeError(c, err, "Folder not found")
+		return
+	}
+	c.JSON(http.StatusOK, folder)
+}
+
+// DeleteFolder 删除文件夹，其中的对话不会被删除
+func (h *OrganizeHandler) DeleteFolder(c *gin.Context) {
+	if err := h.service.DeleteFolder(c.Param("id")); err != nil {
+		writeError(c, err, "Folder not found")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Folder deleted successfully"})
+}
+
+// ListTags 获取标签列表
+func (h *OrganizeHandler) ListTags(c *gin.Context) {
+	response, err := h.service.ListTags()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// PinConversation 置顶或取消置顶对话
+func (h *OrganizeHandler) PinConversation(c *gin.Context) {
+	var req models.PinConversationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.service.SetPinned(c.Param("id"), req.Pinned); err != nil {
+		writeError(c, err, "Conversation not found")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Conversation updated successfully", "pinned": req.Pinned})
+}
+
+// ArchiveConversation 归档或取消归档对话
+func (h *OrganizeHandler) ArchiveConversation(c *gin.Context) {
+	var req models.ArchiveConversationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.service.SetArchived(c.Param("id"), req.Archived); err != nil {
+		writeError(c, err, "Conversation not found")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Conversation updated successfully", "archived": req.Archived})
+}
+
+// MoveConversation 将对话移动到文件夹
+func (h *OrganizeHandler) MoveConversation(c *gin.Context) {
+	var req models.MoveConversationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.service.MoveToFolder(c.Param("id"), req.FolderID); err != nil {
+		writeError(c, err, "Conversation not found")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Conversation updated successfully", "folder_id": req.FolderID})
+}
+
+// SetConversationTags 设置对话的标签
+func (h *OrganizeHandler) SetConversationTags(c *gin.Context) {
+	var req models.ConversationTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tags, err := h.service.SetTags(c.Param("id"), req.Tags)
+	if err != nil {
+		writeError(c, err, "Conversation not found")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Conversation updated successfully", "tags": tags})
+}
+
+// BulkUpdate 批量整理对话：移动、添加或移除标签、归档、置顶
+func (h *OrganizeHandler) BulkUpdate(c *gin.Context) {
+	var req models.BulkConversationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	response, err := h.service.BulkUpdate(&req)
+	if err != nil {
+		if err.Error() == "too_many_ids" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "max_ids": models.MaxBulkConversations})
+			return
+		}
+		writeError(c, err, "Conversation not found")
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func writeError(c *gin.Context, err error, notFound string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+	case err.Error() == "folder_not_found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err.Error() == "duplicate_folder":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err.Error() == "invalid_name", err.Error() == "invalid_tag", err.Error() == "too_many_tags",
+		err.Error() == "invalid_action", err.Error() == "empty_ids":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,270 @@
+package organize
+
+import (
+	"errors"
+	"grandma/backend/models"
+	"grandma/backend/repository"
+	"grandma/backend/utils"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+const (
+	maxFolderNameLength = 50 // 文件夹名称的最大字符数
+	maxTagLength        = 32 // 标签的最大字符数
+	maxTags             = 20 // 一次设置或添加的标签数上限
+)
+
+type OrganizeService struct {
+	conversationRepo *repository.ConversationRepository
+	folderRepo       *repository.FolderRepository
+}
+
+func NewOrganizeService(conversationRepo *repository.ConversationRepository, folderRepo *repository.FolderRepository) *OrganizeService {
+	return &OrganizeService{
+		conversationRepo: conversationRepo,
+		folderRepo:       folderRepo,
+	}
+}
+
+// ListFolders 获取所有文件夹
+func (s *OrganizeService) ListFolders() (*models.FolderListResponse, error) {
+	folders, err := s.folderRepo.List()
+	if err != nil {
+		return nil, err
+	}
+	return &models.FolderListResponse{Folders: folders}, nil
+}
+
+// CreateFolder 创建文件夹，名称不能与已有文件夹重复
+func (s *OrganizeService) CreateFolder(name string) (*models.Folder, error) {
+	name, err := s.checkFolderName("", name)
+	if err != nil {
+		return nil, err
+	}
+	folder := &models.Folder{
+		ID:   utils.GenerateFolderID(),
+		Name: name,
+	}
+	if err := s.folderRepo.Create(folder); err != nil {
+		return nil, err
+	}
+	return folder, nil
+}
+
+// RenameFolder 重命名文件夹
+func (s *OrganizeService) RenameFolder(id, name string) (*models.Folder, error) {
+	if _, err := s.folderRepo.GetByID(id); err != nil {
+		return nil, err
+	}
+	name, err := s.checkFolderName(id, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.folderRepo.Rename(id, name); err != nil {
+		return nil, err
+	}
+	return s.folderRepo.GetByID(id)
+}
+
+// DeleteFolder 删除文件夹，其中的对话变为未分类
+func (s *OrganizeService) DeleteFolder(id string) error {
+	return s.folderRepo.Delete(id)
+}
+
+// checkFolderName 检查文件夹名称，返回去掉首尾空白的名称
+// 名称为空或过长时返回 invalid_name，与其它文件夹重名时返回 duplicate_folder
+func (s *OrganizeService) checkFolderName(id, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxFolderNameLength {
+		return "", errors.New("invalid_name")
+	}
+	existing, err := s.folderRepo.GetByName(name)
+	if err == nil && existing.ID != id {
+		return "", errors.New("duplicate_folder")
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+	return name, nil
+}
+
+// ListTags 获取所有标签及使用次数
+func (s *OrganizeService) ListTags() (*models.TagListResponse, error) {
+	tags, err := s.conversationRepo.ListTags()
+	if err != nil {
+		return nil, err
+	}
+	if tags == nil {
+		tags = []models.TagCount{}
+	}
+	return &models.TagListResponse{Tags: tags}, nil
+}
+
+// SetPinned 置顶或取消置顶对话
+func (s *OrganizeService) SetPinned(id string, pinned bool) error {
+	if _, err := s.conversationRepo.GetMetaByID(id); err != nil {
+		return err
+	}
+	_, err := s.conversationRepo.SetPinned([]string{id}, pinned)
+	return err
+}
+
+// SetArchived 归档或取消归档对话
+func (s *OrganizeService) SetArchived(id string, archived bool) error {
+	if _, err := s.conversationRepo.GetMetaByID(id); err != nil {
+		return err
+	}
+	_, err := s.conversationRepo.SetArchived([]string{id}, archived)
+	return err
+}
+
+// MoveToFolder 将对话移动到文件夹，folderID 为空表示移出文件夹
+func (s *OrganizeService) MoveToFolder(id, folderID string) error {
+	if _, err := s.conversationRepo.GetMetaByID(id); err != nil {
+		return err
+	}
+	if err := s.checkFolder(folderID); err != nil {
+		return err
+	}
+	_, err := s.conversationRepo.SetFolder([]string{id}, folderID)
+	return err
+}
+
+// SetTags 将对话的标签替换为指定的标签，返回整理后的标签
+func (s *OrganizeService) SetTags(id string, tags []string) ([]string, error) {
+	if _, err := s.conversationRepo.GetMetaByID(id); err != nil {
+		return nil, err
+	}
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.conversationRepo.SetTags(id, tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// BulkUpdate 对多个对话执行同一个整理操作，不存在的对话跳过并在结果中列出
+func (s *OrganizeService) BulkUpdate(req *models.BulkConversationRequest) (*models.BulkConversationResponse, error) {
+	ids := dedupe(req.IDs)
+	if len(ids) == 0 {
+		return nil, errors.New("empty_ids")
+	}
+	if len(ids) > models.MaxBulkConversations {
+		return nil, errors.New("too_many_ids")
+	}
+
+	var tags []string
+	switch req.Action {
+	case models.BulkActionMove:
+		if err := s.checkFolder(req.FolderID); err != nil {
+			return nil, err
+		}
+	case models.BulkActionTag, models.BulkActionUntag:
+		var err error
+		tags, err = normalizeTags(req.Tags)
+		if err != nil {
+			return nil, err
+		}
+		if len(tags) == 0 {
+			return nil, errors.New("invalid_tag")
+		}
+	case models.BulkActionArchive, models.BulkActionUnarchive, models.BulkActionPin, models.BulkActionUnpin:
+	default:
+		return nil, errors.New("invalid_action")
+	}
+
+	existing, err := s.conversationRepo.ExistingIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]bool, len(existing))
+	for _, id := range existing {
+		found[id] = true
+	}
+	notFound := []string{}
+	for _, id := range ids {
+		if !found[id] {
+			notFound = append(notFound, id)
+		}
+	}
+
+	var updated int64
+	if len(existing) > 0 {
+		switch req.Action {
+		case models.BulkActionMove:
+			updated, err = s.conversationRepo.SetFolder(existing, req.FolderID)
+		case models.BulkActionTag:
+			updated, err = s.conversationRepo.AddTags(existing, tags)
+		case models.BulkActionUntag:
+			updated, err = s.conversationRepo.RemoveTags(existing, tags)
+		case models.BulkActionArchive, models.BulkActionUnarchive:
+			updated, err = s.conversationRepo.SetArchived(existing, req.Action == models.BulkActionArchive)
+		case models.BulkActionPin, models.BulkActionUnpin:
+			updated, err = s.conversationRepo.SetPinned(existing, req.Action == models.BulkActionPin)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &models.BulkConversationResponse{
+		Action:   req.Action,
+		Updated:  updated,
+		NotFound: notFound,
+	}, nil
+}
+
+// checkFolder 检查目标文件夹是否存在，为空表示未分类
+func (s *OrganizeService) checkFolder(folderID string) error {
+	if folderID == "" {
+		return nil
+	}
+	if _, err := s.folderRepo.GetByID(folderID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("folder_not_found")
+		}
+		return err
+	}
+	return nil
+}
+
+// normalizeTags 去掉标签首尾的空白并合并连续空白，去掉空标签和重复的标签
+// 标签过长时返回 invalid_tag，数量超过上限时返回 too_many_tags
+func normalizeTags(tags []string) ([]string, error) {
+	result := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.Join(strings.Fields(tag), " ")
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, errors.New("invalid_tag")
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	if len(result) > maxTags {
+		return nil, errors.New("too_many_tags")
+	}
+	return result, nil
+}
+
+// dedupe 去掉重复和空的ID，保持原来的顺序
+func dedupe(ids []string) []string {
+	result := make([]string, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
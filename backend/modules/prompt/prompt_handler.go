@@ -0,0 +1,97 @@
+package prompt
+
+import (
+	"errors"
+	"grandma/backend/models"
+	"grandma/backend/prompts"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PromptHandler struct {
+	service *PromptService
+}
+
+func NewPromptHandler(service *PromptService) *PromptHandler {
+	return &PromptHandler{
+		service: service,
+	}
+}
+
+// ListTemplates 获取提示词模板列表
+func (h *PromptHandler) ListTemplates(c *gin.Context) {
+	response, err := h.service.ListTemplates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetTemplate 获取提示词模板详情和历史版本
+func (h *PromptHandler) GetTemplate(c *gin.Context) {
+	response, err := h.service.GetTemplate(c.Param("name"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// SaveTemplate 保存提示词模板的新版本
+func (h *PromptHandler) SaveTemplate(c *gin.Context) {
+	var req models.SavePromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, err := h.service.SaveTemplate(c.Param("name"), req.Content, req.Description)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, template)
+}
+
+// RevertTemplate 将提示词模板恢复到指定版本
+func (h *PromptHandler) RevertTemplate(c *gin.Context) {
+	var req models.RevertPromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, err := h.service.RevertTemplate(c.Param("name"), *req.Version)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, template)
+}
+
+// RenderTemplate 渲染提示词模板（预览）
+func (h *PromptHandler) RenderTemplate(c *gin.Context) {
+	var req models.RenderPromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.service.RenderTemplate(c.Param("name"), &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// writeError 根据错误类型返回不同的状态码
+func writeError(c *gin.Context, err error) {
+	if errors.Is(err, prompts.ErrTemplateNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,176 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"grandma/backend/models"
+	"grandma/backend/prompts"
+	"grandma/backend/repository"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// namePattern 模板名称只允许小写字母、数字、点、下划线和连字符
+var namePattern = regexp.MustCompile(`^[a-z0-9_.-]+$`)
+
+type PromptService struct {
+	promptRepo *repository.PromptRepository
+	store      *prompts.Store
+}
+
+func NewPromptService(promptRepo *repository.PromptRepository, store *prompts.Store) *PromptService {
+	return &PromptService{
+		promptRepo: promptRepo,
+		store:      store,
+	}
+}
+
+// ListTemplates 获取所有模板的当前版本，包括尚未修改过的内置默认模板
+func (s *PromptService) ListTemplates() (*models.PromptListResponse, error) {
+	saved, err := s.promptRepo.ListLatest()
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]models.PromptTemplate)
+	for _, template := range saved {
+		template.Variables = prompts.Variables(template.Content)
+		byName[template.Name] = template
+	}
+	for _, name := range prompts.DefaultNames() {
+		if _, ok := byName[name]; ok {
+			continue
+		}
+		template, err := prompts.Default(name)
+		if err != nil {
+			return nil, err
+		}
+		byName[name] = *template
+	}
+
+	templates := make([]models.PromptTemplate, 0, len(byName))
+	for _, template := range byName {
+		templates = append(templates, template)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+	return &models.PromptListResponse{Templates: templates}, nil
+}
+
+// GetTemplate 获取模板的当前版本和全部历史版本
+func (s *PromptService) GetTemplate(name string) (*models.PromptDetailResponse, error) {
+	current, err := s.store.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := s.promptRepo.ListVersions(name)
+	if err != nil {
+		return nil, err
+	}
+	for i := range versions {
+		versions[i].Variables = prompts.Variables(versions[i].Content)
+	}
+	if template, err := prompts.Default(name); err == nil {
+		versions = append(versions, *template)
+	}
+	return &models.PromptDetailResponse{
+		Current:  *current,
+		Versions: versions,
+	}, nil
+}
+
+// SaveTemplate 保存模板的新版本
+// 内置模板由服务端按固定的变量渲染，新内容只能引用默认模板中的变量
+func (s *PromptService) SaveTemplate(name, content, description string) (*models.PromptTemplate, error) {
+	if !namePattern.MatchString(name) {
+		return nil, errors.New("invalid_name")
+	}
+	if strings.TrimSpace(content) == "" {
+		return nil, errors.New("content_required")
+	}
+
+	if defaultTemplate, err := prompts.Default(name); err == nil {
+		allowed := make(map[string]bool)
+		for _, variable := range defaultTemplate.Variables {
+			allowed[variable] = true
+		}
+		var unknown []string
+		for _, variable := range prompts.Variables(content) {
+			if !allowed[variable] {
+				unknown = append(unknown, variable)
+			}
+		}
+		if len(unknown) > 0 {
+			return nil, fmt.Errorf("unknown variables %s, allowed: %s", strings.Join(unknown, ", "), strings.Join(defaultTemplate.Variables, ", "))
+		}
+		if description == "" {
+			description = defaultTemplate.Description
+		}
+	}
+
+	template := &models.PromptTemplate{
+		Name:        name,
+		Content:     content,
+		Description: description,
+	}
+	err := s.promptRepo.CreateVersion(template)
+	if err != nil {
+		return nil, err
+	}
+	template.Variables = prompts.Variables(content)
+	return template, nil
+}
+
+// RevertTemplate 将模板恢复到指定版本，恢复的内容保存为新版本，版本0为内置默认模板
+func (s *PromptService) RevertTemplate(name string, version int) (*models.PromptTemplate, error) {
+	source, err := s.getVersion(name, version)
+	if err != nil {
+		return nil, err
+	}
+	return s.SaveTemplate(name, source.Content, source.Description)
+}
+
+// RenderTemplate 渲染模板，用于预览：可指定版本，或直接传入尚未保存的模板内容
+func (s *PromptService) RenderTemplate(name string, req *models.RenderPromptRequest) (*models.RenderPromptResponse, error) {
+	var template *models.PromptTemplate
+	var err error
+	switch {
+	case req.Content != "":
+		template = &models.PromptTemplate{Name: name, Content: req.Content}
+	case req.Version != nil:
+		template, err = s.getVersion(name, *req.Version)
+	default:
+		template, err = s.store.Get(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rendered, missing := prompts.RenderContent(template.Content, req.Variables)
+	return &models.RenderPromptResponse{
+		Name:             name,
+		Version:          template.Version,
+		Rendered:         rendered,
+		Variables:        prompts.Variables(template.Content),
+		MissingVariables: missing,
+	}, nil
+}
+
+// getVersion 获取模板的指定版本，版本0为内置默认模板
+func (s *PromptService) getVersion(name string, version int) (*models.PromptTemplate, error) {
+	if version == 0 {
+		return prompts.Default(name)
+	}
+	template, err := s.promptRepo.GetVersion(name, version)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, prompts.ErrTemplateNotFound
+		}
+		return nil, err
+	}
+	return template, nil
+}
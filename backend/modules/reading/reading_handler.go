@@ -0,0 +1,81 @@
+package reading
+
+import (
+	"errors"
+	"grandma/backend/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ReadingHandler struct {
+	service *ReadingService
+}
+
+func NewReadingHandler(service *ReadingService) *ReadingHandler {
+	return &ReadingHandler{
+		service: service,
+	}
+}
+
+// AnalyzeDocument 获取文档的可读性分析
+func (h *ReadingHandler) AnalyzeDocument(c *gin.Context) {
+	var req models.ReadabilityRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.service.AnalyzeDocument(c.Param("id"), req.TargetAge)
+	if err != nil {
+		writeError(c, err, "Document not found")
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// AnalyzeStory 获取故事的可读性分析
+func (h *ReadingHandler) AnalyzeStory(c *gin.Context) {
+	var req models.ReadabilityRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.service.AnalyzeStory(c.Param("id"), req.TargetAge)
+	if err != nil {
+		writeError(c, err, "Story not found")
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateTargetAge 设置对话的目标读者年龄
+func (h *ReadingHandler) UpdateTargetAge(c *gin.Context) {
+	var req models.UpdateTargetAgeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.service.UpdateTargetAge(c.Param("id"), *req.TargetAge)
+	if err != nil {
+		writeError(c, err, "Conversation not found")
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// writeError 根据错误类型返回不同的状态码
+func writeError(c *gin.Context, err error, notFound string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+		return
+	}
+	if err.Error() == "invalid_target_age" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
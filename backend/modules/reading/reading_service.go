@@ -0,0 +1,130 @@
+package reading
+
+import (
+	"errors"
+	"grandma/backend/models"
+	"grandma/backend/modules/chat"
+	"grandma/backend/readability"
+	"grandma/backend/repository"
+)
+
+type ReadingService struct {
+	conversationRepo *repository.ConversationRepository
+	documentRepo     *repository.DocumentRepository
+	storyRepo        *repository.StoryRepository
+}
+
+func NewReadingService(conversationRepo *repository.ConversationRepository, documentRepo *repository.DocumentRepository, storyRepo *repository.StoryRepository) *ReadingService {
+	return &ReadingService{
+		conversationRepo: conversationRepo,
+		documentRepo:     documentRepo,
+		storyRepo:        storyRepo,
+	}
+}
+
+// AnalyzeDocument 分析文档的可读性，未指定目标年龄时使用所属对话的目标年龄
+func (s *ReadingService) AnalyzeDocument(documentID string, targetAge int) (*models.ReadabilityResponse, error) {
+	if err := validateTargetAge(targetAge); err != nil {
+		return nil, err
+	}
+	doc, err := s.documentRepo.GetByID(documentID)
+	if err != nil {
+		return nil, err
+	}
+	if targetAge == 0 {
+		if conversation, err := s.conversationRepo.GetMetaByID(doc.ConversationID); err == nil {
+			targetAge = conversation.TargetAge
+		}
+	}
+
+	response := analyze(doc.Content, targetAge)
+	response.DocumentID = doc.ID
+	return response, nil
+}
+
+// AnalyzeStory 分析故事的可读性
+func (s *ReadingService) AnalyzeStory(storyID string, targetAge int) (*models.ReadabilityResponse, error) {
+	if err := validateTargetAge(targetAge); err != nil {
+		return nil, err
+	}
+	story, err := s.storyRepo.GetByID(storyID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := analyze(story.Content, targetAge)
+	response.StoryID = story.ID
+	return response, nil
+}
+
+// UpdateTargetAge 设置对话的目标读者年龄，0表示不限制
+func (s *ReadingService) UpdateTargetAge(conversationID string, targetAge int) (*models.TargetAgeResponse, error) {
+	if err := validateTargetAge(targetAge); err != nil {
+		return nil, err
+	}
+	if _, err := s.conversationRepo.GetMetaByID(conversationID); err != nil {
+		return nil, err
+	}
+	if err := s.conversationRepo.UpdateTargetAge(conversationID, targetAge); err != nil {
+		return nil, err
+	}
+
+	response := &models.TargetAgeResponse{
+		ConversationID: conversationID,
+		TargetAge:      targetAge,
+	}
+	if targetAge != 0 {
+		level := readability.LevelFor(targetAge)
+		response.Level = &models.ReadingLevel{
+			Age:              level.Age,
+			MaxSentenceZh:    level.MaxSentenceZh,
+			MaxSentenceEn:    level.MaxSentenceEn,
+			MinBasicRatio:    level.MinBasicRatio,
+			MaxAdvancedRatio: level.MaxAdvancedRatio,
+		}
+	}
+	return response, nil
+}
+
+// analyze 分析文本，指定了目标年龄时附带检查结果
+func analyze(content string, targetAge int) *models.ReadabilityResponse {
+	report := readability.Analyze(content)
+	response := &models.ReadabilityResponse{Report: toReport(report)}
+	if targetAge != 0 {
+		response.ReadingLevel = chat.NewReadingLevelCheck(report, targetAge)
+	}
+	return response
+}
+
+// toReport 将分析结果转换为响应中的报告
+func toReport(report *readability.Report) *models.ReadabilityReport {
+	words := make([]models.UncommonWord, len(report.UncommonWords))
+	for i, word := range report.UncommonWords {
+		words[i] = models.UncommonWord{Text: word.Text, Count: word.Count}
+	}
+	return &models.ReadabilityReport{
+		Language:          report.Language,
+		Characters:        report.Characters,
+		Words:             report.Words,
+		Sentences:         report.Sentences,
+		AvgSentenceLength: report.AvgSentenceLength,
+		MaxSentenceLength: report.MaxSentenceLength,
+		Tiers: models.WordTiers{
+			Basic:    report.Tiers.Basic,
+			Common:   report.Tiers.Common,
+			Advanced: report.Tiers.Advanced,
+		},
+		BasicRatio:    report.BasicRatio,
+		AdvancedRatio: report.AdvancedRatio,
+		UncommonWords: words,
+		EstimatedAge:  report.EstimatedAge,
+	}
+}
+
+// validateTargetAge 校验目标年龄，0表示未指定
+func validateTargetAge(targetAge int) error {
+	if targetAge != 0 && !readability.ValidTargetAge(targetAge) {
+		return errors.New("invalid_target_age")
+	}
+	return nil
+}
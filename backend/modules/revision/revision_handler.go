@@ -0,0 +1,106 @@
+package revision
+
+import (
+	"errors"
+	"grandma/backend/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RevisionHandler struct {
+	service *RevisionService
+}
+
+func NewRevisionHandler(service *RevisionService) *RevisionHandler {
+	return &RevisionHandler{
+		service: service,
+	}
+}
+
+// ListRevisions 获取文档或故事的修订列表
+func (h *RevisionHandler) ListRevisions(c *gin.Context) {
+	response, err := h.service.ListRevisions(targetType(c), c.Param("id"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetRevision 获取一个修订的完整内容
+func (h *RevisionHandler) GetRevision(c *gin.Context) {
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_revision"})
+		return
+	}
+	revision, err := h.service.GetRevision(targetType(c), c.Param("id"), number)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, revision)
+}
+
+// DiffRevisions 逐行和逐字比较两个修订
+func (h *RevisionHandler) DiffRevisions(c *gin.Context) {
+	var req models.RevisionDiffRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_revision"})
+		return
+	}
+	response, err := h.service.DiffRevisions(targetType(c), c.Param("id"), req.From, req.To)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RevertRevision 恢复到指定修订的内容
+func (h *RevisionHandler) RevertRevision(c *gin.Context) {
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_revision"})
+		return
+	}
+	var req models.RevertRequest
+	// 请求体可以为空
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	revision, err := h.service.Revert(targetType(c), c.Param("id"), number, &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, revision)
+}
+
+// targetType 根据路由判断修订属于文档还是故事：/api/stories/:id/revisions 为故事，/api/documents/:id/revisions 为文档
+func targetType(c *gin.Context) string {
+	if strings.HasPrefix(c.FullPath(), "/api/stories/") {
+		return models.RevisionTargetStory
+	}
+	return models.RevisionTargetDocument
+}
+
+func writeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+	case err.Error() == "no_changes":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err.Error() == "invalid_revision", err.Error() == "invalid_author", err.Error() == "invalid_reason":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
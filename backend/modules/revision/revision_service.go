@@ -0,0 +1,296 @@
+package revision
+
+import (
+	"errors"
+	"grandma/backend/diff"
+	"grandma/backend/models"
+	"grandma/backend/repository"
+	"grandma/backend/utils"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxAuthorLength = 50  // 修改人的最大字符数
+	maxReasonLength = 500 // 修改原因的最大字符数
+)
+
+type RevisionService struct {
+	revisionRepo *repository.RevisionRepository
+	documentRepo *repository.DocumentRepository
+	storyRepo    *repository.StoryRepository
+}
+
+func NewRevisionService(
+	revisionRepo *repository.RevisionRepository,
+	documentRepo *repository.DocumentRepository,
+	storyRepo *repository.StoryRepository,
+) *RevisionService {
+	return &RevisionService{
+		revisionRepo: revisionRepo,
+		documentRepo: documentRepo,
+		storyRepo:    storyRepo,
+	}
+}
+
+// UpdateDocument 修改文档的内容，其他字段保持不变；内容有变化时记录修订并返回新的修订，否则返回 nil
+func (s *RevisionService) UpdateDocument(id, content, author, reason string) (*models.Revision, error) {
+	author, reason, err := checkNote(author, reason)
+	if err != nil {
+		return nil, err
+	}
+	document, err := s.documentRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if document.Content == content {
+		return nil, nil
+	}
+
+	current := documentSnapshot(document)
+	document.Content = content
+	revision := newRevision(models.RevisionTargetDocument, document.ID, "", content)
+	revision.Action = models.RevisionActionEdit
+	revision.Author = author
+	revision.Reason = reason
+	if err := s.documentRepo.UpdateWithRevision(document, current, revision); err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// UpdateStory 修改故事的标题和内容，有变化时记录修订
+func (s *RevisionService) UpdateStory(id string, req *models.StoryUpdateRequest) (*models.Story, error) {
+	author, reason, err := checkNote(req.Author, req.Reason)
+	if err != nil {
+		return nil, err
+	}
+	story, err := s.storyRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	title := story.Title
+	if req.Title != nil {
+		title = strings.TrimSpace(*req.Title)
+	}
+	if title == story.Title && req.Content == story.Content {
+		return story, nil
+	}
+
+	current := storySnapshot(story)
+	story.Title = title
+	story.Content = req.Content
+	story.ContentHash = utils.CalculateContentHash(req.Content)
+	revision := newRevision(models.RevisionTargetStory, story.ID, story.Title, story.Content)
+	revision.Action = models.RevisionActionEdit
+	revision.Author = author
+	revision.Reason = reason
+	if err := s.storyRepo.UpdateWithRevision(story, current, revision); err != nil {
+		return nil, err
+	}
+	return story, nil
+}
+
+// ListRevisions 获取文档或故事的修订，最新的在前；还没有修改过时为空
+func (s *RevisionService) ListRevisions(targetType, targetID string) (*models.RevisionListResponse, error) {
+	if err := s.checkTarget(targetType, targetID); err != nil {
+		return nil, err
+	}
+	revisions, err := s.revisionRepo.List(targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.RevisionListResponse{Revisions: revisions, Total: len(revisions)}, nil
+}
+
+// GetRevision 获取一个修订的完整内容
+func (s *RevisionService) GetRevision(targetType, targetID string, number int) (*models.Revision, error) {
+	if err := s.checkTarget(targetType, targetID); err != nil {
+		return nil, err
+	}
+	return s.revisionRepo.Get(targetType, targetID, number)
+}
+
+// DiffRevisions 比较两个修订，to 为0时使用最新的修订，from 为0时使用 to 的上一个修订
+func (s *RevisionService) DiffRevisions(targetType, targetID string, from, to int) (*models.RevisionDiffResponse, error) {
+	if err := s.checkTarget(targetType, targetID); err != nil {
+		return nil, err
+	}
+	if from < 0 || to < 0 {
+		return nil, errors.New("invalid_revision")
+	}
+
+	var newer *models.Revision
+	var err error
+	if to == 0 {
+		newer, err = s.revisionRepo.Latest(targetType, targetID)
+	} else {
+		newer, err = s.revisionRepo.Get(targetType, targetID, to)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if from == 0 {
+		from = newer.Number - 1
+		if from == 0 {
+			// 只有一个修订，没有可比较的上一个修订
+			return nil, errors.New("invalid_revision")
+		}
+	}
+	older, err := s.revisionRepo.Get(targetType, targetID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	oldText, newText := older.Content, newer.Content
+	if targetType == models.RevisionTargetStory {
+		// 故事的标题作为第一行一起比较
+		oldText = older.Title + "\n\n" + oldText
+		newText = newer.Title + "\n\n" + newText
+	}
+	return &models.RevisionDiffResponse{
+		From: older.Number,
+		To:   newer.Number,
+		Diff: toDiffResult(diff.Compare(oldText, newText)),
+	}, nil
+}
+
+// toDiffResult 将比较结果转换为响应中的结构
+func toDiffResult(result *diff.Result) *models.DiffResult {
+	lines := make([]models.DiffLine, len(result.Lines))
+	for i, line := range result.Lines {
+		lines[i] = models.DiffLine{
+			Op:      line.Op,
+			OldLine: line.OldLine,
+			NewLine: line.NewLine,
+			Text:    line.Text,
+		}
+		if line.Segments != nil {
+			lines[i].Segments = make([]models.DiffSegment, len(line.Segments))
+			for j, segment := range line.Segments {
+				lines[i].Segments[j] = models.DiffSegment{Op: segment.Op, Text: segment.Text}
+			}
+		}
+	}
+	return &models.DiffResult{
+		Lines: lines,
+		Stats: models.DiffStats(result.Stats),
+	}
+}
+
+// Revert 将文档或故事恢复到指定修订的内容，并记录为新的修订
+// 当前内容与该修订相同时返回 no_changes
+func (s *RevisionService) Revert(targetType, targetID string, number int, req *models.RevertRequest) (*models.Revision, error) {
+	author, reason, err := checkNote(req.Author, req.Reason)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkTarget(targetType, targetID); err != nil {
+		return nil, err
+	}
+	target, err := s.revisionRepo.Get(targetType, targetID, number)
+	if err != nil {
+		return nil, err
+	}
+
+	revision := newRevision(targetType, targetID, target.Title, target.Content)
+	revision.Action = models.RevisionActionRevert
+	revision.Author = author
+	revision.Reason = reason
+	revision.RevertedFrom = &number
+
+	switch targetType {
+	case models.RevisionTargetDocument:
+		document, err := s.documentRepo.GetByID(targetID)
+		if err != nil {
+			return nil, err
+		}
+		if document.Content == target.Content {
+			return nil, errors.New("no_changes")
+		}
+		current := documentSnapshot(document)
+		document.Content = target.Content
+		if err := s.documentRepo.UpdateWithRevision(document, current, revision); err != nil {
+			return nil, err
+		}
+	case models.RevisionTargetStory:
+		story, err := s.storyRepo.GetByID(targetID)
+		if err != nil {
+			return nil, err
+		}
+		if story.Title == target.Title && story.Content == target.Content {
+			return nil, errors.New("no_changes")
+		}
+		current := storySnapshot(story)
+		story.Title = target.Title
+		story.Content = target.Content
+		story.ContentHash = utils.CalculateContentHash(target.Content)
+		if err := s.storyRepo.UpdateWithRevision(story, current, revision); err != nil {
+			return nil, err
+		}
+	}
+	return revision, nil
+}
+
+// checkTarget 检查文档或故事是否存在，回收站中的不能查看和修改修订
+func (s *RevisionService) checkTarget(targetType, targetID string) error {
+	var err error
+	switch targetType {
+	case models.RevisionTargetDocument:
+		_, err = s.documentRepo.GetByID(targetID)
+	case models.RevisionTargetStory:
+		_, err = s.storyRepo.GetByID(targetID)
+	default:
+		err = errors.New("invalid_target_type")
+	}
+	return err
+}
+
+// checkNote 检查修改人和修改原因，修改人为空时使用默认的修改人
+func checkNote(author, reason string) (string, string, error) {
+	author = strings.TrimSpace(author)
+	reason = strings.TrimSpace(reason)
+	if author == "" {
+		author = models.DefaultRevisionAuthor
+	}
+	if utf8.RuneCountInString(author) > maxAuthorLength {
+		return "", "", errors.New("invalid_author")
+	}
+	if utf8.RuneCountInString(reason) > maxReasonLength {
+		return "", "", errors.New("invalid_reason")
+	}
+	return author, reason, nil
+}
+
+// documentSnapshot 文档修改前的内容，修订人为生成回复的模型，用户的文档为 user
+func documentSnapshot(document *models.Document) *models.Revision {
+	revision := newRevision(models.RevisionTargetDocument, document.ID, "", document.Content)
+	revision.Action = models.RevisionActionOriginal
+	revision.Author = document.Model
+	if revision.Author == "" {
+		revision.Author = models.DefaultRevisionAuthor
+	}
+	revision.CreatedAt = document.UpdatedAt
+	return revision
+}
+
+// storySnapshot 故事修改前的标题和内容
+func storySnapshot(story *models.Story) *models.Revision {
+	revision := newRevision(models.RevisionTargetStory, story.ID, story.Title, story.Content)
+	revision.Action = models.RevisionActionOriginal
+	revision.Author = models.DefaultRevisionAuthor
+	revision.CreatedAt = story.UpdatedAt
+	return revision
+}
+
+// newRevision 创建修订，计算内容的特征值和字符数
+func newRevision(targetType, targetID, title, content string) *models.Revision {
+	return &models.Revision{
+		TargetType:  targetType,
+		TargetID:    targetID,
+		Title:       title,
+		Content:     content,
+		ContentHash: utils.CalculateContentHash(content),
+		Length:      utf8.RuneCountInString(content),
+	}
+}
@@ -0,0 +1,165 @@
+package revision
+
+import (
+	"grandma/backend/database"
+	"grandma/backend/models"
+	"grandma/backend/repository"
+	"path/filepath"
+	"testing"
+)
+
+// newTestService 使用临时数据库创建修订服务，并创建一个包含两条文档的对话
+func newTestService(t *testing.T) (*RevisionService, *repository.DocumentRepository, *models.Document) {
+	t.Helper()
+	if err := database.InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	conversationRepo := repository.NewConversationRepository(database.DB)
+	documentRepo := repository.NewDocumentRepository(database.DB)
+	storyRepo := repository.NewStoryRepository(database.DB)
+
+	if err := conversationRepo.Create(&models.Conversation{ID: "conv_1", Title: "测试"}); err != nil {
+		t.Fatalf("create conversation: %v", err)
+	}
+	seq1, seq2 := 1, 2
+	user := &models.Document{ID: "doc_1", ConversationID: "conv_1", Role: "user", Content: "讲个故事", ParentID: "", Seq: &seq1}
+	reply := &models.Document{ID: "doc_2", ConversationID: "conv_1", Role: "assistant", Model: "openai", Content: "从前有一只小兔子。", ParentID: "doc_1", Seq: &seq2}
+	for _, doc := range []*models.Document{user, reply} {
+		if err := documentRepo.Create(doc); err != nil {
+			t.Fatalf("create document: %v", err)
+		}
+	}
+	service := NewRevisionService(repository.NewRevisionRepository(database.DB), documentRepo, storyRepo)
+	return service, documentRepo, reply
+}
+
+// checkDocumentFields 修改内容后文档的其他字段保持不变
+func checkDocumentFields(t *testing.T, got, want *models.Document) {
+	t.Helper()
+	if got.ConversationID != want.ConversationID || got.Role != want.Role || got.Model != want.Model ||
+		got.ParentID != want.ParentID || got.Seq == nil || *got.Seq != *want.Seq ||
+		!got.CreatedAt.Equal(want.CreatedAt) {
+		t.Fatalf("document fields changed: got %+v, want %+v", got, want)
+	}
+}
+
+func TestUpdateDocumentKeepsOtherFields(t *testing.T) {
+	service, documentRepo, reply := newTestService(t)
+	before, err := documentRepo.GetByID(reply.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revision, err := service.UpdateDocument(reply.ID, "从前有一只小白兔。", "奶奶", "改成白兔")
+	if err != nil {
+		t.Fatalf("UpdateDocument: %v", err)
+	}
+	if revision == nil || revision.Number != 2 || revision.Action != models.RevisionActionEdit ||
+		revision.Author != "奶奶" || revision.Reason != "改成白兔" {
+		t.Fatalf("unexpected revision: %+v", revision)
+	}
+
+	after, err := documentRepo.GetByID(reply.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Content != "从前有一只小白兔。" {
+		t.Fatalf("content = %q", after.Content)
+	}
+	checkDocumentFields(t, after, before)
+
+	// 内容没有变化时不记录修订
+	revision, err = service.UpdateDocument(reply.ID, "从前有一只小白兔。", "", "")
+	if err != nil || revision != nil {
+		t.Fatalf("unchanged update: revision %+v, err %v", revision, err)
+	}
+}
+
+func TestUpdateAndRevertRoundTrip(t *testing.T) {
+	service, documentRepo, reply := newTestService(t)
+	before, err := documentRepo.GetByID(reply.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, content := range []string{"第一版", "第二版"} {
+		if _, err := service.UpdateDocument(reply.ID, content, "", ""); err != nil {
+			t.Fatalf("UpdateDocument(%q): %v", content, err)
+		}
+	}
+
+	list, err := service.ListRevisions(models.RevisionTargetDocument, reply.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantActions := []string{models.RevisionActionEdit, models.RevisionActionEdit, models.RevisionActionOriginal}
+	if list.Total != len(wantActions) {
+		t.Fatalf("revisions = %d, want %d", list.Total, len(wantActions))
+	}
+	for i, action := range wantActions {
+		if list.Revisions[i].Action != action || list.Revisions[i].Number != len(wantActions)-i {
+			t.Fatalf("revision %d = %+v", i, list.Revisions[i])
+		}
+	}
+	if list.Revisions[2].Author != "openai" {
+		t.Fatalf("original author = %q, want the model", list.Revisions[2].Author)
+	}
+
+	revision, err := service.Revert(models.RevisionTargetDocument, reply.ID, 1, &models.RevertRequest{Reason: "还是原来的好"})
+	if err != nil {
+		t.Fatalf("Revert: %v", err)
+	}
+	if revision.Number != 4 || revision.Action != models.RevisionActionRevert ||
+		revision.RevertedFrom == nil || *revision.RevertedFrom != 1 || revision.Author != models.DefaultRevisionAuthor {
+		t.Fatalf("unexpected revert revision: %+v", revision)
+	}
+	after, err := documentRepo.GetByID(reply.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Content != before.Content {
+		t.Fatalf("content after revert = %q, want %q", after.Content, before.Content)
+	}
+	checkDocumentFields(t, after, before)
+
+	// 再次恢复到相同内容没有变化
+	if _, err := service.Revert(models.RevisionTargetDocument, reply.ID, 1, &models.RevertRequest{}); err == nil || err.Error() != "no_changes" {
+		t.Fatalf("second revert err = %v, want no_changes", err)
+	}
+
+	result, err := service.DiffRevisions(models.RevisionTargetDocument, reply.ID, 3, 4)
+	if err != nil {
+		t.Fatalf("DiffRevisions: %v", err)
+	}
+	if result.From != 3 || result.To != 4 || result.Diff.Stats.LinesAdded != 1 || result.Diff.Stats.LinesDeleted != 1 {
+		t.Fatalf("unexpected diff: %+v", result)
+	}
+
+	// 分支和分页依赖的字段没有被修改
+	branch, err := documentRepo.GetBranch(reply.ID, 0)
+	if err != nil || len(branch) != 2 {
+		t.Fatalf("branch = %d documents, err %v", len(branch), err)
+	}
+}
+
+func TestRevisionSnapshotsExternalChange(t *testing.T) {
+	service, documentRepo, reply := newTestService(t)
+	if _, err := service.UpdateDocument(reply.ID, "修改后", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	// 续写等在修订之外的更改
+	if err := documentRepo.AppendContent(reply.ID, "续写"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.UpdateDocument(reply.ID, "再次修改", "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	revision, err := service.GetRevision(models.RevisionTargetDocument, reply.ID, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revision.Action != models.RevisionActionOriginal || revision.Content != "修改后续写" {
+		t.Fatalf("snapshot = %+v", revision)
+	}
+}
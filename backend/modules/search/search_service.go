@@ -0,0 +1,114 @@
+package search
+
+import (
+	"errors"
+	"grandma/backend/models"
+	"grandma/backend/repository"
+	textsearch "grandma/backend/search"
+	"strings"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 50
+	snippetWidth    = 120 // 片段的最大字符数
+)
+
+type SearchService struct {
+	searchRepo *repository.SearchRepository
+}
+
+func NewSearchService(searchRepo *repository.SearchRepository) *SearchService {
+	return &SearchService{
+		searchRepo: searchRepo,
+	}
+}
+
+// Search 在对话标题、文档和故事中搜索，返回带高亮片段的结果
+func (s *SearchService) Search(req *models.SearchRequest) (*models.SearchResponse, error) {
+	terms := textsearch.Terms(req.Query)
+	if len(terms) == 0 {
+		return nil, errors.New("empty_query")
+	}
+
+	var kinds []string
+	for _, kind := range strings.Split(req.Types, ",") {
+		kind = strings.TrimSpace(kind)
+		if kind == "" {
+			continue
+		}
+		if !textsearch.ValidKind(kind) {
+			return nil, errors.New("invalid_type")
+		}
+		kinds = append(kinds, kind)
+	}
+
+	page, pageSize := req.Page, req.PageSize
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	hits, total, err := s.searchRepo.Search(repository.SearchQuery{
+		Query:          req.Query,
+		Kinds:          kinds,
+		ConversationID: req.ConversationID,
+		Offset:         (page - 1) * pageSize,
+		Limit:          pageSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 文档结果显示所属对话的标题
+	var conversationIDs []string
+	for _, hit := range hits {
+		if hit.Kind == textsearch.KindDocument {
+			conversationIDs = append(conversationIDs, hit.ConversationID)
+		}
+	}
+	titles, err := s.searchRepo.GetConversationTitles(conversationIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]models.SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		result := models.SearchResult{
+			Type:           hit.Kind,
+			ID:             hit.RefID,
+			ConversationID: hit.ConversationID,
+			Score:          hit.Score,
+			UpdatedAt:      hit.UpdatedAt,
+		}
+		if hit.Title != "" {
+			result.Title = textsearch.Highlight(hit.Title, terms)
+		}
+		if hit.Body != "" {
+			result.Snippet = textsearch.Snippet(hit.Body, terms, snippetWidth)
+		}
+		if hit.Kind == textsearch.KindDocument {
+			result.ConversationTitle = titles[hit.ConversationID]
+		}
+		results = append(results, result)
+	}
+
+	mode := "like"
+	if s.searchRepo.FTSEnabled() {
+		mode = "fts"
+	}
+	return &models.SearchResponse{
+		Query:    req.Query,
+		Mode:     mode,
+		Results:  results,
+		Total:    int(total),
+		Page:     page,
+		PageSize: pageSize,
+		HasMore:  int64(page*pageSize) < total,
+	}, nil
+}
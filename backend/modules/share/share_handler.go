@@ -0,0 +1,146 @@
+package share
+
+import (
+	"errors"
+	"fmt"
+	render "grandma/backend/export"
+	"grandma/backend/models"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errorPage 分享页面无法打开时显示的页面，message 只使用下面的固定文字
+const errorPage = `<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>%[1]s</title>
+</head>
+<body style="max-width: 760px; margin: 4em auto; padding: 0 1em; font-family: sans-serif; font-size: 1.2em; color: #444;">
+<p>%[1]s</p>
+</body>
+</html>
+`
+
+// pageMessages 各种错误在分享页面上显示的文字
+var pageMessages = map[string]string{
+	"share_not_found":   "链接不存在，请向分享的人确认链接是否完整。",
+	"share_expired":     "链接已过期，请让分享的人重新发送。",
+	"share_revoked":     "链接已失效，请让分享的人重新发送。",
+	"content_not_found": "分享的内容已被删除。",
+}
+
+type ShareHandler struct {
+	service *ShareService
+}
+
+func NewShareHandler(service *ShareService) *ShareHandler {
+	return &ShareHandler{
+		service: service,
+	}
+}
+
+// CreateLink 为对话或故事创建只读分享链接
+func (h *ShareHandler) CreateLink(c *gin.Context) {
+	var req models.ShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	link, err := h.service.CreateLink(&req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, link)
+}
+
+// ListLinks 获取分享链接，target_type 和 target_id 可选
+func (h *ShareHandler) ListLinks(c *gin.Context) {
+	response, err := h.service.ListLinks(c.Query("target_type"), c.Query("target_id"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RevokeLink 撤销分享链接
+func (h *ShareHandler) RevokeLink(c *gin.Context) {
+	link, err := h.service.RevokeLink(c.Param("id"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, link)
+}
+
+// GetShared 以JSON返回分享的内容，不需要登录
+func (h *ShareHandler) GetShared(c *gin.Context) {
+	publicHeaders(c)
+	content, err := h.service.Open(c.Param("token"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, content)
+}
+
+// SharedPage 以HTML页面显示分享的内容，不需要登录
+func (h *ShareHandler) SharedPage(c *gin.Context) {
+	publicHeaders(c)
+	content, err := h.service.Open(c.Param("token"))
+	if err != nil {
+		status, code := errorStatus(err)
+		message, ok := pageMessages[code]
+		if !ok {
+			log.Printf("[share_handler SharedPage] Failed to open share: %v", err)
+			message = "页面暂时无法打开，请稍后再试。"
+		}
+		c.Data(status, "text/html; charset=utf-8", []byte(fmt.Sprintf(errorPage, message)))
+		return
+	}
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	if content.Story != nil {
+		err = render.StoryHTML(c.Writer, content.Story)
+	} else {
+		err = render.HTML(c.Writer, content.Conversation)
+	}
+	if err != nil {
+		log.Printf("[share_handler SharedPage] Failed to render %s: %v", content.Type, err)
+	}
+}
+
+// publicHeaders 公开访问的响应不缓存（撤销后立即失效）、不被搜索引擎收录，也不通过 Referer 泄露令牌
+func publicHeaders(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Robots-Tag", "noindex, nofollow")
+	c.Header("Referrer-Policy", "no-referrer")
+}
+
+// errorStatus 错误对应的状态码和错误码
+func errorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound, "share_not_found"
+	case err.Error() == "target_not_found", err.Error() == "content_not_found":
+		return http.StatusNotFound, err.Error()
+	case err.Error() == "share_expired", err.Error() == "share_revoked":
+		return http.StatusGone, err.Error()
+	case err.Error() == "invalid_target_type", err.Error() == "invalid_expiry":
+		return http.StatusBadRequest, err.Error()
+	default:
+		return http.StatusInternalServerError, err.Error()
+	}
+}
+
+func writeError(c *gin.Context, err error) {
+	status, code := errorStatus(err)
+	c.JSON(status, gin.H{"error": code})
+}
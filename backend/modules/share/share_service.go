@@ -0,0 +1,175 @@
+package share
+
+import (
+	"errors"
+	"fmt"
+	render "grandma/backend/export"
+	"grandma/backend/models"
+	"grandma/backend/modules/export"
+	"grandma/backend/repository"
+	"grandma/backend/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SharePath 分享页面的路径前缀，JSON 格式的内容在 /api 下的同名路径
+const SharePath = "/shared/"
+
+// SharedContent 分享链接指向的内容，Conversation 和 Story 只有一个不为空
+type SharedContent struct {
+	Type         string             `json:"type"`                 // conversation 或 story
+	ExpiresAt    *time.Time         `json:"expires_at,omitempty"` // 链接的过期时间
+	Conversation *render.Transcript `json:"conversation,omitempty"`
+	Story        *render.Story      `json:"story,omitempty"`
+}
+
+type ShareService struct {
+	shareRepo        *repository.ShareRepository
+	conversationRepo *repository.ConversationRepository
+	storyRepo        *repository.StoryRepository
+	exportSvc        *export.ExportService
+}
+
+func NewShareService(
+	shareRepo *repository.ShareRepository,
+	conversationRepo *repository.ConversationRepository,
+	storyRepo *repository.StoryRepository,
+	exportSvc *export.ExportService,
+) *ShareService {
+	return &ShareService{
+		shareRepo:        shareRepo,
+		conversationRepo: conversationRepo,
+		storyRepo:        storyRepo,
+		exportSvc:        exportSvc,
+	}
+}
+
+// CreateLink 为对话或故事创建分享链接，回收站中的内容不能分享
+func (s *ShareService) CreateLink(req *models.ShareRequest) (*models.ShareLinkItem, error) {
+	if !validTargetType(req.TargetType) {
+		return nil, errors.New("invalid_target_type")
+	}
+	now := time.Now()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return nil, errors.New("invalid_expiry")
+	}
+	if err := s.checkTarget(req.TargetType, req.TargetID); err != nil {
+		return nil, err
+	}
+
+	link := &models.ShareLink{
+		ID:         utils.GenerateShareID(),
+		Token:      utils.GenerateShareToken(),
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		ExpiresAt:  req.ExpiresAt,
+	}
+	if err := s.shareRepo.Create(link); err != nil {
+		return nil, err
+	}
+	return linkItem(link, now), nil
+}
+
+// ListLinks 获取分享链接，可以按内容类型和ID过滤
+func (s *ShareService) ListLinks(targetType, targetID string) (*models.ShareLinkListResponse, error) {
+	if targetType != "" && !validTargetType(targetType) {
+		return nil, errors.New("invalid_target_type")
+	}
+	links, err := s.shareRepo.List(targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	response := &models.ShareLinkListResponse{Links: make([]models.ShareLinkItem, 0, len(links))}
+	for i := range links {
+		response.Links = append(response.Links, *linkItem(&links[i], now))
+	}
+	return response, nil
+}
+
+// RevokeLink 撤销分享链接，撤销后链接不能再访问
+func (s *ShareService) RevokeLink(id string) (*models.ShareLinkItem, error) {
+	link, err := s.shareRepo.Revoke(id)
+	if err != nil {
+		return nil, err
+	}
+	return linkItem(link, time.Now()), nil
+}
+
+// Open 通过令牌查看分享的内容并记录一次访问
+// 令牌不存在时返回 gorm.ErrRecordNotFound，链接过期或撤销时返回 share_expired 或 share_revoked，内容已删除时返回 content_not_found
+func (s *ShareService) Open(token string) (*SharedContent, error) {
+	link, err := s.shareRepo.GetByToken(token)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	switch link.Status(now) {
+	case models.ShareStatusExpired:
+		return nil, errors.New("share_expired")
+	case models.ShareStatusRevoked:
+		return nil, errors.New("share_revoked")
+	}
+
+	content := &SharedContent{Type: link.TargetType, ExpiresAt: link.ExpiresAt}
+	switch link.TargetType {
+	case models.ShareTargetConversation:
+		content.Conversation, err = s.exportSvc.Transcript(link.TargetID, now)
+		if err == nil {
+			// 标签是自己整理用的，不对外展示
+			content.Conversation.Tags = nil
+		}
+	case models.ShareTargetStory:
+		var story *models.Story
+		story, err = s.storyRepo.GetByID(link.TargetID)
+		if err == nil {
+			content.Story = &render.Story{
+				ID:        story.ID,
+				Title:     story.Title,
+				Content:   story.Content,
+				CreatedAt: story.CreatedAt,
+				UpdatedAt: story.UpdatedAt,
+			}
+		}
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("content_not_found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.shareRepo.RecordAccess(link.ID); err != nil {
+		fmt.Printf("[share_service Open] Failed to record access for %s: %+v\n", link.ID, err)
+	}
+	return content, nil
+}
+
+// checkTarget 检查要分享的内容是否存在
+func (s *ShareService) checkTarget(targetType, targetID string) error {
+	var err error
+	switch targetType {
+	case models.ShareTargetConversation:
+		_, err = s.conversationRepo.GetMetaByID(targetID)
+	case models.ShareTargetStory:
+		_, err = s.storyRepo.GetByID(targetID)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New("target_not_found")
+	}
+	return err
+}
+
+func validTargetType(targetType string) bool {
+	return targetType == models.ShareTargetConversation || targetType == models.ShareTargetStory
+}
+
+// linkItem 分享链接及其当前状态和页面路径
+func linkItem(link *models.ShareLink, now time.Time) *models.ShareLinkItem {
+	return &models.ShareLinkItem{
+		ShareLink: *link,
+		Status:    link.Status(now),
+		Path:      SharePath + link.Token,
+	}
+}
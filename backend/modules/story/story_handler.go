@@ -1,11 +1,13 @@
 package story
 
 import (
+	"errors"
 	"fmt"
 	"grandma/backend/models"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type StoryHandler struct {
@@ -70,12 +72,40 @@ func (h *StoryHandler) CreateStory(c *gin.Context) {
 	c.JSON(http.StatusOK, story)
 }
 
+// UpdateStory 修改故事的标题和内容
+func (h *StoryHandler) UpdateStory(c *gin.Context) {
+	var req models.StoryUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	story, err := h.service.UpdateStory(c.Param("id"), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Story not found"})
+		case err.Error() == "invalid_author", err.Error() == "invalid_reason":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, story)
+}
+
 // DeleteStory 删除文档
 func (h *StoryHandler) DeleteStory(c *gin.Context) {
 	fmt.Println("[story_handler DeleteStory] Start")
 	id := c.Param("id")
 	err := h.service.DeleteStory(id)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Story not found"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
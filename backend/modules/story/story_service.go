@@ -3,18 +3,21 @@ package story
 import (
 	"errors"
 	"grandma/backend/models"
+	"grandma/backend/modules/revision"
 	"grandma/backend/repository"
 	"grandma/backend/utils"
 	"time"
 )
 
 type StoryService struct {
-	storyRepo *repository.StoryRepository
+	storyRepo   *repository.StoryRepository
+	revisionSvc *revision.RevisionService
 }
 
-func NewStoryService(storyRepo *repository.StoryRepository) *StoryService {
+func NewStoryService(storyRepo *repository.StoryRepository, revisionSvc *revision.RevisionService) *StoryService {
 	return &StoryService{
-		storyRepo: storyRepo,
+		storyRepo:   storyRepo,
+		revisionSvc: revisionSvc,
 	}
 }
 
@@ -40,6 +43,19 @@ func (s *StoryService) GetStoryList(guid string) (*models.StoryResponse, error)
 	}, nil
 }
 
+// GetStoryByContent 查找内容相同的故事，没有时返回 nil
+func (s *StoryService) GetStoryByContent(guid, content string) (*models.Story, error) {
+	if guid == "" {
+		guid = "default"
+	}
+	return s.storyRepo.GetByContentHash(guid, utils.CalculateContentHash(content))
+}
+
+// UpdateStory 修改故事的标题和内容，有变化时记录修订
+func (s *StoryService) UpdateStory(id string, req *models.StoryUpdateRequest) (*models.Story, error) {
+	return s.revisionSvc.UpdateStory(id, req)
+}
+
 // DeleteStory 删除文档
 func (s *StoryService) DeleteStory(id string) error {
 	return s.storyRepo.Delete(id)
@@ -0,0 +1,72 @@
+package trash
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TrashHandler struct {
+	service *TrashService
+}
+
+func NewTrashHandler(service *TrashService) *TrashHandler {
+	return &TrashHandler{
+		service: service,
+	}
+}
+
+// ListTrash 获取回收站中的内容
+func (h *TrashHandler) ListTrash(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	response, err := h.service.List(c.Query("type"), limit)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RestoreItem 从回收站恢复内容
+func (h *TrashHandler) RestoreItem(c *gin.Context) {
+	if err := h.service.Restore(c.Param("type"), c.Param("id")); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Item restored successfully"})
+}
+
+// PurgeItem 永久删除回收站中的一项内容
+func (h *TrashHandler) PurgeItem(c *gin.Context) {
+	if err := h.service.Purge(c.Param("type"), c.Param("id")); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Item purged successfully"})
+}
+
+// EmptyTrash 清空回收站
+func (h *TrashHandler) EmptyTrash(c *gin.Context) {
+	response, err := h.service.PurgeAll()
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func writeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found in trash"})
+	case err.Error() == "invalid_type":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err.Error() == "conversation_in_trash":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
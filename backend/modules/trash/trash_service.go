@@ -0,0 +1,235 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"grandma/backend/models"
+	"grandma/backend/repository"
+	"log"
+	"sort"
+	"time"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 200
+	previewRunes     = 100            // 列表中内容预览的最大字符数
+	purgeInterval    = time.Hour      // 自动清理回收站的间隔
+	retentionUnit    = 24 * time.Hour // 保留期限的单位：天
+)
+
+type TrashConfig struct {
+	RetentionDays int // 回收站内容保留的天数，为0时不自动删除
+}
+
+type TrashService struct {
+	conversationRepo *repository.ConversationRepository
+	documentRepo     *repository.DocumentRepository
+	storyRepo        *repository.StoryRepository
+	config           *TrashConfig
+}
+
+func NewTrashService(conversationRepo *repository.ConversationRepository, documentRepo *repository.DocumentRepository, storyRepo *repository.StoryRepository, config *TrashConfig) *TrashService {
+	return &TrashService{
+		conversationRepo: conversationRepo,
+		documentRepo:     documentRepo,
+		storyRepo:        storyRepo,
+		config:           config,
+	}
+}
+
+// Start 启动自动清理：启动时和之后每小时永久删除超过保留期限的内容，未设置保留期限时不启动
+func (s *TrashService) Start(ctx context.Context) {
+	if s.config.RetentionDays <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(purgeInterval)
+		defer ticker.Stop()
+		for {
+			result, err := s.PurgeExpired()
+			if err != nil {
+				log.Printf("[trash_service Start] Failed to purge expired items: %v", err)
+			} else if result.Conversations+result.Documents+result.Stories > 0 {
+				log.Printf("[trash_service Start] Purged %d conversations, %d documents, %d stories", result.Conversations, result.Documents, result.Stories)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// List 获取回收站中的内容，itemType 为空时获取所有类型
+func (s *TrashService) List(itemType string, limit int) (*models.TrashListResponse, error) {
+	if itemType != "" && !validType(itemType) {
+		return nil, errors.New("invalid_type")
+	}
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	items := []models.TrashItem{}
+	if itemType == "" || itemType == models.TrashTypeConversation {
+		conversations, err := s.conversationRepo.ListDeleted(limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, conversation := range conversations {
+			items = append(items, models.TrashItem{
+				Type:           models.TrashTypeConversation,
+				ID:             conversation.ID,
+				ConversationID: conversation.ID,
+				Title:          conversation.Title,
+				DeletedAt:      conversation.DeletedAt.Time,
+			})
+		}
+	}
+	if itemType == "" || itemType == models.TrashTypeDocument {
+		documents, err := s.documentRepo.ListDeleted(limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range documents {
+			items = append(items, models.TrashItem{
+				Type:           models.TrashTypeDocument,
+				ID:             doc.ID,
+				ConversationID: doc.ConversationID,
+				Preview:        preview(doc.Content),
+				DeletedAt:      doc.DeletedAt.Time,
+			})
+		}
+	}
+	if itemType == "" || itemType == models.TrashTypeStory {
+		stories, err := s.storyRepo.ListDeleted(limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, story := range stories {
+			items = append(items, models.TrashItem{
+				Type:      models.TrashTypeStory,
+				ID:        story.ID,
+				Title:     story.Title,
+				Preview:   preview(story.Content),
+				DeletedAt: story.DeletedAt.Time,
+			})
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	if s.config.RetentionDays > 0 {
+		for i := range items {
+			purgeAt := items[i].DeletedAt.Add(time.Duration(s.config.RetentionDays) * retentionUnit)
+			items[i].PurgeAt = &purgeAt
+		}
+	}
+
+	return &models.TrashListResponse{
+		Items:         items,
+		RetentionDays: s.config.RetentionDays,
+	}, nil
+}
+
+// Restore 从回收站恢复内容；对话随其文档一起恢复，所属对话仍在回收站中的文档需先恢复对话
+func (s *TrashService) Restore(itemType, id string) error {
+	switch itemType {
+	case models.TrashTypeConversation:
+		return s.conversationRepo.Restore(id)
+	case models.TrashTypeDocument:
+		doc, err := s.documentRepo.GetDeletedByID(id)
+		if err != nil {
+			return err
+		}
+		if _, err := s.conversationRepo.GetDeletedByID(doc.ConversationID); err == nil {
+			return errors.New("conversation_in_trash")
+		}
+		return s.documentRepo.Restore(id)
+	case models.TrashTypeStory:
+		return s.storyRepo.Restore(id)
+	}
+	return errors.New("invalid_type")
+}
+
+// Purge 永久删除回收站中的一项内容
+func (s *TrashService) Purge(itemType, id string) error {
+	switch itemType {
+	case models.TrashTypeConversation:
+		if _, err := s.conversationRepo.GetDeletedByID(id); err != nil {
+			return err
+		}
+		return s.conversationRepo.Purge(id)
+	case models.TrashTypeDocument:
+		if _, err := s.documentRepo.GetDeletedByID(id); err != nil {
+			return err
+		}
+		return s.documentRepo.Purge(id)
+	case models.TrashTypeStory:
+		if _, err := s.storyRepo.GetDeletedByID(id); err != nil {
+			return err
+		}
+		return s.storyRepo.Purge(id)
+	}
+	return errors.New("invalid_type")
+}
+
+// PurgeAll 清空回收站
+func (s *TrashService) PurgeAll() (*models.PurgeTrashResponse, error) {
+	return s.purgeDeletedBefore(time.Now().Add(time.Second))
+}
+
+// PurgeExpired 永久删除超过保留期限的内容
+func (s *TrashService) PurgeExpired() (*models.PurgeTrashResponse, error) {
+	if s.config.RetentionDays <= 0 {
+		return &models.PurgeTrashResponse{}, nil
+	}
+	return s.purgeDeletedBefore(time.Now().Add(-time.Duration(s.config.RetentionDays) * retentionUnit))
+}
+
+// purgeDeletedBefore 永久删除在指定时间之前移入回收站的内容
+func (s *TrashService) purgeDeletedBefore(cutoff time.Time) (*models.PurgeTrashResponse, error) {
+	result := &models.PurgeTrashResponse{}
+
+	conversationIDs, err := s.conversationRepo.ListDeletedBefore(cutoff)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range conversationIDs {
+		if err := s.conversationRepo.Purge(id); err != nil {
+			return nil, err
+		}
+		result.Conversations++
+	}
+
+	result.Documents, err = s.documentRepo.PurgeDeletedBefore(cutoff)
+	if err != nil {
+		return nil, err
+	}
+	result.Stories, err = s.storyRepo.PurgeDeletedBefore(cutoff)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func validType(itemType string) bool {
+	return itemType == models.TrashTypeConversation || itemType == models.TrashTypeDocument || itemType == models.TrashTypeStory
+}
+
+// preview 截取内容的开头
+func preview(content string) string {
+	runes := []rune(content)
+	if len(runes) <= previewRunes {
+		return content
+	}
+	return string(runes[:previewRunes]) + "…"
+}
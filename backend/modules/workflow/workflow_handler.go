@@ -0,0 +1,165 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"grandma/backend/models"
+	"grandma/backend/modules/chat"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WorkflowHandler struct {
+	service *WorkflowService
+}
+
+func NewWorkflowHandler(service *WorkflowService) *WorkflowHandler {
+	return &WorkflowHandler{
+		service: service,
+	}
+}
+
+// ListProjects 获取故事项目列表
+func (h *WorkflowHandler) ListProjects(c *gin.Context) {
+	response, err := h.service.ListProjects()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateProject 创建故事项目
+func (h *WorkflowHandler) CreateProject(c *gin.Context) {
+	var req models.CreateStoryProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	project, err := h.service.CreateProject(&req)
+	if err != nil {
+		if err.Error() == "too_many_chapters" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, project)
+}
+
+// GetProject 获取故事项目详情，包括已生成的章节
+func (h *WorkflowHandler) GetProject(c *gin.Context) {
+	project, err := h.service.GetProject(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, project)
+}
+
+// DeleteProject 删除故事项目
+func (h *WorkflowHandler) DeleteProject(c *gin.Context) {
+	if err := h.service.DeleteProject(c.Param("id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Project deleted successfully"})
+}
+
+// UpdateOutline 手动修改大纲
+func (h *WorkflowHandler) UpdateOutline(c *gin.Context) {
+	var req models.UpdateOutlineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	project, err := h.service.UpdateOutline(c.Param("id"), req.Outline)
+	if err != nil {
+		h.writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, project)
+}
+
+// GenerateOutline 流式生成大纲
+func (h *WorkflowHandler) GenerateOutline(c *gin.Context) {
+	h.stream(c, h.service.GenerateOutline)
+}
+
+// GenerateNextChapter 流式生成下一章
+func (h *WorkflowHandler) GenerateNextChapter(c *gin.Context) {
+	h.stream(c, h.service.GenerateNextChapter)
+}
+
+// Run 流式执行项目剩余的全部步骤
+func (h *WorkflowHandler) Run(c *gin.Context) {
+	h.stream(c, h.service.Run)
+}
+
+// stream 以SSE事件流式返回工作流步骤的进度；开始输出之前出错时返回普通的JSON错误
+func (h *WorkflowHandler) stream(c *gin.Context, step func(context.Context, string, func(models.WorkflowEvent)) (*models.StoryProject, error)) {
+	started := false
+	emit := func(event models.WorkflowEvent) {
+		if !started {
+			started = true
+			c.Header("Content-Type", "text/event-stream")
+			c.Header("Cache-Control", "no-cache")
+			c.Header("Connection", "keep-alive")
+		}
+		data, err := json.Marshal(event)
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(c.Writer, "data: %s\n\n", data)
+		c.Writer.Flush()
+	}
+
+	// 与聊天一致，使用独立的context：客户端断开连接后仍继续生成并保存
+	project, err := step(context.Background(), c.Param("id"), emit)
+	if err != nil {
+		fmt.Printf("[workflow_handler stream] Error: %v\n", err)
+		if started {
+			emit(models.WorkflowEvent{Type: EventError, Error: err.Error()})
+			return
+		}
+		h.writeError(c, err)
+		return
+	}
+	emit(models.WorkflowEvent{Type: EventCompleted, Project: project})
+}
+
+// writeError 根据错误类型返回不同的状态码
+func (h *WorkflowHandler) writeError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+	if errors.Is(err, chat.ErrConversationBusy) {
+		c.JSON(http.StatusConflict, gin.H{"error": "conversation_busy"})
+		return
+	}
+	if errors.Is(err, chat.ErrContentBlocked) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "content_blocked"})
+		return
+	}
+	if err.Error() == "duplicate_story" {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err.Error() == "invalid_stage" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,390 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"grandma/backend/events"
+	"grandma/backend/models"
+	"grandma/backend/modules/chat"
+	"grandma/backend/modules/story"
+	"grandma/backend/prompts"
+	"grandma/backend/repository"
+	"grandma/backend/services"
+	"grandma/backend/utils"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultChapterCount = 5
+	maxChapterCount     = 30
+)
+
+// 工作流事件类型
+const (
+	EventStep          = "step"
+	EventDelta         = "delta"
+	EventStepCompleted = "step_completed"
+	EventCompleted     = "completed"
+	EventError         = "error"
+)
+
+type WorkflowService struct {
+	projectRepo      *repository.StoryProjectRepository
+	conversationRepo *repository.ConversationRepository
+	documentRepo     *repository.DocumentRepository
+	chatService      *chat.ChatService
+	storyService     *story.StoryService
+	config           *WorkflowConfig
+	events           *events.Hub
+	prompts          *prompts.Store
+}
+
+type WorkflowConfig struct {
+	OpenAIAPIKey     string
+	OpenAIBaseURL    string
+	AnthropicAPIKey  string
+	AnthropicBaseURL string
+}
+
+func NewWorkflowService(projectRepo *repository.StoryProjectRepository, conversationRepo *repository.ConversationRepository, documentRepo *repository.DocumentRepository, chatService *chat.ChatService, storyService *story.StoryService, config *WorkflowConfig, hub *events.Hub, promptStore *prompts.Store) *WorkflowService {
+	return &WorkflowService{
+		projectRepo:      projectRepo,
+		conversationRepo: conversationRepo,
+		documentRepo:     documentRepo,
+		chatService:      chatService,
+		storyService:     storyService,
+		config:           config,
+		events:           hub,
+		prompts:          promptStore,
+	}
+}
+
+// CreateProject 创建故事项目，同时创建保存生成内容的对话
+func (s *WorkflowService) CreateProject(req *models.CreateStoryProjectRequest) (*models.StoryProject, error) {
+	chapterCount := req.ChapterCount
+	if chapterCount <= 0 {
+		chapterCount = defaultChapterCount
+	}
+	if chapterCount > maxChapterCount {
+		return nil, errors.New("too_many_chapters")
+	}
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = "新故事"
+	}
+
+	conversation := &models.Conversation{
+		ID:    utils.GenerateConversationID(),
+		Title: title,
+	}
+	err := s.conversationRepo.Create(conversation)
+	if err != nil {
+		return nil, err
+	}
+	s.events.Publish(events.Event{
+		Type:           events.TypeConversationCreated,
+		ConversationID: conversation.ID,
+		Data:           map[string]string{"title": title},
+	})
+
+	project := &models.StoryProject{
+		ID:             utils.GenerateProjectID(),
+		ConversationID: conversation.ID,
+		Title:          title,
+		Premise:        req.Premise,
+		Model:          req.Model,
+		Stage:          models.StageOutline,
+		ChapterCount:   chapterCount,
+		Chapters:       []models.StoryChapter{},
+	}
+	err = s.projectRepo.Create(project)
+	if err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// GetProject 获取故事项目
+func (s *WorkflowService) GetProject(id string) (*models.StoryProject, error) {
+	return s.projectRepo.GetByID(id)
+}
+
+// ListProjects 获取故事项目列表
+func (s *WorkflowService) ListProjects() (*models.StoryProjectListResponse, error) {
+	projects, err := s.projectRepo.List()
+	if err != nil {
+		return nil, err
+	}
+	return &models.StoryProjectListResponse{
+		Projects: projects,
+		Total:    len(projects),
+	}, nil
+}
+
+// DeleteProject 删除故事项目，项目的对话和已保存的故事保留
+func (s *WorkflowService) DeleteProject(id string) error {
+	return s.projectRepo.Delete(id)
+}
+
+// UpdateOutline 手动修改大纲，之后生成的章节以修改后的大纲为准
+func (s *WorkflowService) UpdateOutline(id, outline string) (*models.StoryProject, error) {
+	project, err := s.projectRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if project.Stage == models.StageCompleted {
+		return nil, errors.New("invalid_stage")
+	}
+	err = s.projectRepo.Updates(id, map[string]interface{}{
+		"outline": outline,
+		"stage":   models.StageWriting,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.projectRepo.GetByID(id)
+}
+
+// GenerateOutline 根据故事前提生成大纲，生成内容保存到项目的对话中；大纲阶段可重复生成
+func (s *WorkflowService) GenerateOutline(ctx context.Context, id string, emit func(models.WorkflowEvent)) (*models.StoryProject, error) {
+	project, err := s.projectRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if project.Stage != models.StageOutline {
+		return nil, errors.New("invalid_stage")
+	}
+
+	emit(models.WorkflowEvent{Type: EventStep, Stage: models.StageOutline})
+	prompt := s.prompts.Render(prompts.WorkflowOutline, map[string]string{
+		"premise":       project.Premise,
+		"chapter_count": strconv.Itoa(project.ChapterCount),
+	})
+	response, err := s.send(ctx, project, prompt, &workflowWriter{stage: models.StageOutline, emit: emit})
+	if err != nil {
+		return nil, err
+	}
+	if response.FinishReason == "cancelled" || response.FinishReason == "error" {
+		return nil, errors.New("generation_failed")
+	}
+
+	err = s.projectRepo.Updates(id, map[string]interface{}{
+		"outline":             strings.TrimSpace(response.Content),
+		"outline_document_id": response.DocumentID,
+		"stage":               models.StageWriting,
+	})
+	if err != nil {
+		return nil, err
+	}
+	project, err = s.projectRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	emit(models.WorkflowEvent{Type: EventStepCompleted, Stage: models.StageOutline, DocumentID: response.DocumentID, Project: project})
+	return project, nil
+}
+
+// GenerateNextChapter 以大纲和之前各章的摘要为上下文生成下一章，保存章节文档并生成本章摘要
+// 最后一章完成后将全部章节合并保存为故事
+func (s *WorkflowService) GenerateNextChapter(ctx context.Context, id string, emit func(models.WorkflowEvent)) (*models.StoryProject, error) {
+	project, err := s.projectRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if project.Stage != models.StageWriting || project.Outline == "" {
+		return nil, errors.New("invalid_stage")
+	}
+	number := len(project.Chapters) + 1
+	if number > project.ChapterCount {
+		// 全部章节已保存，上一次合并保存故事或更新项目时失败，直接重新合并
+		if err := s.complete(project.ID); err != nil {
+			return nil, err
+		}
+		return s.projectRepo.GetByID(id)
+	}
+
+	emit(models.WorkflowEvent{Type: EventStep, Stage: models.StageWriting, Chapter: number})
+	var summaries strings.Builder
+	for _, chapter := range project.Chapters {
+		fmt.Fprintf(&summaries, "第%d章：%s\n", chapter.Number, chapter.Summary)
+	}
+	if summaries.Len() == 0 {
+		summaries.WriteString("（这是第一章）")
+	}
+	prompt := s.prompts.Render(prompts.WorkflowChapter, map[string]string{
+		"premise":       project.Premise,
+		"outline":       project.Outline,
+		"summaries":     summaries.String(),
+		"chapter":       strconv.Itoa(number),
+		"chapter_count": strconv.Itoa(project.ChapterCount),
+	})
+	response, err := s.send(ctx, project, prompt, &workflowWriter{stage: models.StageWriting, chapter: number, emit: emit})
+	if err != nil {
+		return nil, err
+	}
+	if response.FinishReason == "cancelled" || response.FinishReason == "error" {
+		return nil, errors.New("generation_failed")
+	}
+
+	// 摘要或章节保存失败时删除本次生成的文档，重新生成本章时不会留下不属于任何章节的文档
+	summary, err := s.summarize(project.Model, response.Content)
+	if err != nil {
+		s.discard(project.ConversationID, response)
+		return nil, err
+	}
+	err = s.projectRepo.SaveChapter(&models.StoryChapter{
+		ProjectID:  project.ID,
+		Number:     number,
+		DocumentID: response.DocumentID,
+		Summary:    summary,
+	})
+	if err != nil {
+		s.discard(project.ConversationID, response)
+		return nil, err
+	}
+
+	if number >= project.ChapterCount {
+		err = s.complete(project.ID)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// 仅更新项目的修改时间
+		err = s.projectRepo.Updates(project.ID, map[string]interface{}{})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	project, err = s.projectRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	emit(models.WorkflowEvent{Type: EventStepCompleted, Stage: models.StageWriting, Chapter: number, DocumentID: response.DocumentID, Project: project})
+	return project, nil
+}
+
+// Run 依次执行项目剩余的步骤：尚无大纲时先生成大纲，然后按顺序生成全部剩余章节
+func (s *WorkflowService) Run(ctx context.Context, id string, emit func(models.WorkflowEvent)) (*models.StoryProject, error) {
+	project, err := s.projectRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if project.Stage == models.StageOutline {
+		project, err = s.GenerateOutline(ctx, id, emit)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for project.Stage == models.StageWriting {
+		project, err = s.GenerateNextChapter(ctx, id, emit)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return project, nil
+}
+
+// send 在项目的对话中发送提示词，以提示词本身作为完整上下文流式生成回复
+func (s *WorkflowService) send(ctx context.Context, project *models.StoryProject, prompt string, writer *workflowWriter) (*models.ChatResponse, error) {
+	req := &models.ChatRequest{
+		ConversationID: project.ConversationID,
+		Model:          project.Model,
+		Messages: []models.Message{
+			{Role: "user", Content: prompt},
+		},
+	}
+	return s.chatService.SendWithContext(ctx, req, nil, writer)
+}
+
+// discard 永久删除一次生成保存的提示词和回复文档，对话的活动分支回退到发送之前
+func (s *WorkflowService) discard(conversationID string, response *models.ChatResponse) {
+	headID := ""
+	if user, err := s.documentRepo.GetByID(response.UserDocumentID); err == nil {
+		headID = user.ParentID
+	}
+	if err := s.conversationRepo.UpdateHeadDocumentID(conversationID, headID); err != nil {
+		fmt.Printf("[workflow_service discard] Error resetting head of %s: %v\n", conversationID, err)
+	}
+	for _, id := range []string{response.DocumentID, response.UserDocumentID} {
+		if id == "" {
+			continue
+		}
+		if err := s.documentRepo.Purge(id); err != nil {
+			fmt.Printf("[workflow_service discard] Error purging document %s: %v\n", id, err)
+		}
+	}
+}
+
+// summarize 生成章节摘要，作为后续章节的上下文
+func (s *WorkflowService) summarize(model, content string) (string, error) {
+	provider, err := services.GetProvider(
+		model,
+		s.config.OpenAIAPIKey,
+		s.config.OpenAIBaseURL,
+		s.config.AnthropicAPIKey,
+		s.config.AnthropicBaseURL,
+	)
+	if err != nil {
+		return "", err
+	}
+	summary, err := provider.Chat([]models.Message{
+		{Role: "user", Content: s.prompts.Render(prompts.WorkflowChapterSummary, map[string]string{"content": content})},
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(summary), nil
+}
+
+// complete 将全部章节按顺序合并保存为故事，并将项目标记为已完成
+func (s *WorkflowService) complete(id string) error {
+	project, err := s.projectRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	var content strings.Builder
+	lastDocumentID := ""
+	for _, chapter := range project.Chapters {
+		doc, err := s.documentRepo.GetByID(chapter.DocumentID)
+		if err != nil {
+			return err
+		}
+		if content.Len() > 0 {
+			content.WriteString("\n\n")
+		}
+		fmt.Fprintf(&content, "第%d章\n\n%s", chapter.Number, strings.TrimSpace(doc.Content))
+		lastDocumentID = doc.ID
+	}
+
+	saved, err := s.storyService.CreateStory("", lastDocumentID, project.Title, content.String(), "")
+	if err != nil && err.Error() == "duplicate_story" {
+		// 上一次已保存故事但更新项目失败，或已有内容相同的故事：使用已有的故事完成项目
+		saved, err = s.storyService.GetStoryByContent("", content.String())
+		if err == nil && saved == nil {
+			err = errors.New("duplicate_story")
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return s.projectRepo.Updates(id, map[string]interface{}{
+		"stage":    models.StageCompleted,
+		"story_id": saved.ID,
+	})
+}
+
+// workflowWriter 将流式生成的内容包装为增量事件
+type workflowWriter struct {
+	stage   string
+	chapter int
+	emit    func(models.WorkflowEvent)
+}
+
+func (w *workflowWriter) Write(p []byte) (int, error) {
+	w.emit(models.WorkflowEvent{Type: EventDelta, Stage: w.stage, Chapter: w.chapter, Content: string(p)})
+	return len(p), nil
+}
@@ -0,0 +1,112 @@
+package prompts
+
+// 内置模板名称
+const (
+	ConversationTitle      = "conversation.title"
+	ChatContinuation       = "chat.continuation"
+	ChatSummaryContext     = "chat.summary_context"
+	ChatSummaryReply       = "chat.summary_context_reply"
+	ChatSummaryGeneration  = "chat.summary_generation"
+	ChatTruncatedContext   = "chat.truncated_context"
+	ChatTruncatedReply     = "chat.truncated_context_reply"
+	CommandOutline         = "command.outline"
+	CommandRewrite         = "command.rewrite"
+	WorkflowOutline        = "workflow.outline"
+	WorkflowChapter        = "workflow.chapter"
+	WorkflowChapterSummary = "workflow.chapter_summary"
+	SafetyRewrite          = "safety.rewrite"
+	SafetyBlockedNotice    = "safety.blocked_notice"
+	ReadabilityTargetAge   = "readability.target_age"
+)
+
+// defaultTemplate 内置默认模板
+type defaultTemplate struct {
+	Description string
+	Content     string
+}
+
+// defaults 内置默认模板，数据库中没有同名模板时使用
+var defaults = map[string]defaultTemplate{
+	ConversationTitle: {
+		Description: "根据用户输入生成对话标题",
+		Content:     "请根据以下用户输入，生成一个简洁的对话标题（不超过20个字，不要包含标点符号）：\n\n{{user_inputs}}",
+	},
+	ChatContinuation: {
+		Description: "OpenAI兼容接口续写时追加在已有内容之后的提示词；续写没有前文的用户消息时也作为用户消息",
+		Content:     "请从上文中断的地方继续写下去，直接输出后续内容，不要重复已经写过的内容，也不要添加任何说明。",
+	},
+	ChatSummaryContext: {
+		Description: "历史过长时注入上下文的摘要消息",
+		Content:     "【前情提要】以下是之前对话内容的摘要，请在此基础上继续：\n\n{{summary}}",
+	},
+	ChatSummaryReply: {
+		Description: "摘要消息之后的助手确认消息，保证消息角色交替",
+		Content:     "好的，我已了解之前的情节，会在此基础上继续。",
+	},
+	ChatTruncatedContext: {
+		Description: "历史过长且摘要尚未生成时，注入上下文说明更早的内容已省略",
+		Content:     "【说明】这段对话很长，更早的内容已省略，请根据下面保留的对话继续，保持人物和情节前后一致。",
+	},
+	ChatTruncatedReply: {
+		Description: "省略说明之后的助手确认消息，保证消息角色交替",
+		Content:     "好的，我会根据保留的内容继续。",
+	},
+	ChatSummaryGeneration: {
+		Description: "生成滚动摘要",
+		Content: `请将已有摘要与新增对话合并为一份新的情节摘要。要求：保留主要人物、设定、关键情节和尚未解决的伏笔，按时间顺序叙述，不超过800字，只输出摘要内容。
+
+已有摘要：
+{{summary}}
+
+新增对话：
+{{conversation}}`,
+	},
+	CommandOutline: {
+		Description: "/outline 命令",
+		Content:     "请根据目前的故事内容，整理出后续情节的大纲：分为若干章节，每章用一两句话概括主要事件，保持人物和设定前后一致。{{requirements}}",
+	},
+	CommandRewrite: {
+		Description: "/rewrite 命令",
+		Content:     "请按照以下要求改写你的上一条回复，只输出改写后的内容：{{instruction}}",
+	},
+	WorkflowOutline: {
+		Description: "故事创作工作流：根据故事前提生成大纲",
+		Content: `请根据以下故事前提，为一篇儿童故事写出分章节的大纲。
+
+故事前提：
+{{premise}}
+
+要求：共{{chapter_count}}章，每章一行，格式为“第N章：章节标题——本章主要情节”，保持人物和设定前后一致，只输出大纲。`,
+	},
+	WorkflowChapter: {
+		Description: "故事创作工作流：根据大纲和之前各章的摘要生成章节",
+		Content: `你正在按照大纲逐章创作一篇儿童故事。
+
+故事前提：
+{{premise}}
+
+大纲：
+{{outline}}
+
+之前各章的摘要：
+{{summaries}}
+
+请写出第{{chapter}}章（共{{chapter_count}}章）的正文：紧接上一章的情节，符合大纲中本章的安排，语言生动、适合儿童阅读，只输出本章正文。`,
+	},
+	WorkflowChapterSummary: {
+		Description: "故事创作工作流：生成章节摘要",
+		Content:     "请用不超过150字概括以下章节的主要情节，保留人物、关键事件和结尾时的状态，只输出摘要：\n\n{{content}}",
+	},
+	SafetyRewrite: {
+		Description: "内容安全：模型输出命中需要重写的规则时，追加在用户消息之后的要求",
+		Content:     "（请注意：读者是{{audience}}。请不要涉及{{category}}相关的内容，用温和、积极、适合这个年龄的方式继续。）",
+	},
+	SafetyBlockedNotice: {
+		Description: "内容安全：模型输出被拦截时追加的提示",
+		Content:     "\n\n（这部分内容不适合小朋友，已被过滤。）",
+	},
+	ReadabilityTargetAge: {
+		Description: "对话设置了目标年龄时，追加在用户消息之后的阅读难度要求",
+		Content:     "（请注意：读者是{{age}}岁的孩子。请使用这个年龄能读懂的语言：中文句子平均不超过{{sentence_length}}个字，英文句子平均不超过{{sentence_words}}个单词，多用常见的字词，少用生僻字词和复杂的句式。）",
+	},
+}
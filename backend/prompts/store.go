@@ -0,0 +1,120 @@
+package prompts
+
+import (
+	"errors"
+	"grandma/backend/models"
+	"grandma/backend/repository"
+	"log"
+	"regexp"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// variablePattern 模板变量，形如 {{name}}，变量名两侧允许空白
+var variablePattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+
+// ErrTemplateNotFound 数据库和内置默认模板中都没有该模板
+var ErrTemplateNotFound = errors.New("template_not_found")
+
+// Store 提示词模板库：按名称查找模板，数据库中的最新版本优先，没有时使用内置默认模板
+type Store struct {
+	repo *repository.PromptRepository
+}
+
+func NewStore(repo *repository.PromptRepository) *Store {
+	return &Store{repo: repo}
+}
+
+// Get 获取模板的当前版本
+func (s *Store) Get(name string) (*models.PromptTemplate, error) {
+	if s != nil {
+		template, err := s.repo.GetLatest(name)
+		if err == nil {
+			template.Variables = Variables(template.Content)
+			return template, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	return Default(name)
+}
+
+// Render 渲染模板的当前版本；自定义模板缺少变量或读取失败时回退到内置默认模板，保证服务端功能可用
+func (s *Store) Render(name string, vars map[string]string) string {
+	template, err := s.Get(name)
+	if err == nil {
+		rendered, missing := RenderContent(template.Content, vars)
+		if len(missing) == 0 {
+			return rendered
+		}
+		log.Printf("[prompts Render] Template %s v%d has unknown variables %v, falling back to default", name, template.Version, missing)
+	} else {
+		log.Printf("[prompts Render] Failed to load template %s: %v", name, err)
+	}
+
+	template, err = Default(name)
+	if err != nil {
+		log.Printf("[prompts Render] No default template %s", name)
+		return ""
+	}
+	rendered, _ := RenderContent(template.Content, vars)
+	return rendered
+}
+
+// Default 获取内置默认模板，版本号为0
+func Default(name string) (*models.PromptTemplate, error) {
+	template, ok := defaults[name]
+	if !ok {
+		return nil, ErrTemplateNotFound
+	}
+	return &models.PromptTemplate{
+		Name:        name,
+		Version:     0,
+		Content:     template.Content,
+		Description: template.Description,
+		Variables:   Variables(template.Content),
+	}, nil
+}
+
+// DefaultNames 获取所有内置模板的名称，按名称排序
+func DefaultNames() []string {
+	names := make([]string, 0, len(defaults))
+	for name := range defaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Variables 解析模板中引用的变量，按首次出现的顺序去重
+func Variables(content string) []string {
+	variables := []string{}
+	seen := make(map[string]bool)
+	for _, match := range variablePattern.FindAllStringSubmatch(content, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			variables = append(variables, match[1])
+		}
+	}
+	return variables
+}
+
+// RenderContent 将模板中的变量替换为对应的值，返回渲染结果和没有提供值的变量（保留原样）
+func RenderContent(content string, vars map[string]string) (string, []string) {
+	missing := []string{}
+	seen := make(map[string]bool)
+	rendered := variablePattern.ReplaceAllStringFunc(content, func(match string) string {
+		name := variablePattern.FindStringSubmatch(match)[1]
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		if !seen[name] {
+			seen[name] = true
+			missing = append(missing, name)
+		}
+		return match
+	})
+	return rendered, missing
+}
@@ -0,0 +1,282 @@
+package readability
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 字词的频率等级
+const (
+	TierBasic    = "basic"    // 高频字词
+	TierCommon   = "common"   // 常用字词
+	TierAdvanced = "advanced" // 生僻字词
+)
+
+// tierNames 字词表的等级，与 wordlists.go 中字词表的顺序对应
+var tierNames = []string{TierBasic, TierCommon}
+
+// 文本的主要语言
+const (
+	LanguageChinese = "zh"
+	LanguageEnglish = "en"
+)
+
+// 目标年龄的范围
+const (
+	MinTargetAge = 3
+	MaxTargetAge = 18
+)
+
+const maxUncommonWords = 30 // 报告中最多列出的生僻字词数
+
+// Tiers 各等级字词的数量
+type Tiers struct {
+	Basic    int `json:"basic"`
+	Common   int `json:"common"`
+	Advanced int `json:"advanced"`
+}
+
+// UncommonWord 生僻字词及出现次数
+type UncommonWord struct {
+	Text  string `json:"text"`
+	Count int    `json:"count"`
+}
+
+// Report 可读性分析结果，中文按汉字计数，英文按单词计数
+type Report struct {
+	Language          string         `json:"language"`            // 主要语言：zh 或 en
+	Characters        int            `json:"characters"`          // 汉字数
+	Words             int            `json:"words"`               // 英文单词数
+	Sentences         int            `json:"sentences"`           // 句子数
+	AvgSentenceLength float64        `json:"avg_sentence_length"` // 平均句长（汉字和单词数）
+	MaxSentenceLength int            `json:"max_sentence_length"` // 最长句子的长度
+	Tiers             Tiers          `json:"tiers"`
+	BasicRatio        float64        `json:"basic_ratio"`    // 高频字词的比例
+	AdvancedRatio     float64        `json:"advanced_ratio"` // 生僻字词的比例
+	UncommonWords     []UncommonWord `json:"uncommon_words"` // 生僻字词，按出现次数排列
+	EstimatedAge      int            `json:"estimated_age"`  // 估计适合阅读的最小年龄，空文本为0
+}
+
+// Level 各年龄的阅读难度上限
+type Level struct {
+	Age              int     `json:"age"`
+	MaxSentenceZh    float64 `json:"max_sentence_zh"`    // 中文平均句长上限（汉字）
+	MaxSentenceEn    float64 `json:"max_sentence_en"`    // 英文平均句长上限（单词）
+	MinBasicRatio    float64 `json:"min_basic_ratio"`    // 高频字词比例下限
+	MaxAdvancedRatio float64 `json:"max_advanced_ratio"` // 生僻字词比例上限
+}
+
+// levels 按年龄从小到大排列，超过最后一级时估计年龄为 MaxTargetAge
+var levels = []Level{
+	{Age: 5, MaxSentenceZh: 12, MaxSentenceEn: 8, MinBasicRatio: 0.70, MaxAdvancedRatio: 0.12},
+	{Age: 8, MaxSentenceZh: 18, MaxSentenceEn: 11, MinBasicRatio: 0.60, MaxAdvancedRatio: 0.18},
+	{Age: 10, MaxSentenceZh: 24, MaxSentenceEn: 14, MinBasicRatio: 0.50, MaxAdvancedRatio: 0.24},
+	{Age: 12, MaxSentenceZh: 30, MaxSentenceEn: 17, MinBasicRatio: 0.40, MaxAdvancedRatio: 0.30},
+	{Age: 15, MaxSentenceZh: 40, MaxSentenceEn: 22, MinBasicRatio: 0.30, MaxAdvancedRatio: 0.40},
+}
+
+// Issue 超过目标年龄难度上限的指标
+type Issue struct {
+	Metric string  `json:"metric"` // avg_sentence_length、basic_ratio 或 advanced_ratio
+	Value  float64 `json:"value"`
+	Limit  float64 `json:"limit"`
+}
+
+// ValidTargetAge 是否为支持的目标年龄
+func ValidTargetAge(age int) bool {
+	return age >= MinTargetAge && age <= MaxTargetAge
+}
+
+// LevelFor 获取目标年龄使用的难度上限：不超过目标年龄的最高一级，目标年龄低于最低一级时使用最低一级
+func LevelFor(age int) Level {
+	level := levels[0]
+	for _, l := range levels {
+		if l.Age <= age {
+			level = l
+		}
+	}
+	return level
+}
+
+// Analyze 分析文本的可读性
+func Analyze(text string) *Report {
+	report := &Report{UncommonWords: []UncommonWord{}}
+	uncommon := make(map[string]int)
+	var uncommonOrder []string
+
+	count := func(token, tier string) {
+		switch tier {
+		case TierBasic:
+			report.Tiers.Basic++
+		case TierCommon:
+			report.Tiers.Common++
+		default:
+			report.Tiers.Advanced++
+			if uncommon[token] == 0 {
+				uncommonOrder = append(uncommonOrder, token)
+			}
+			uncommon[token]++
+		}
+	}
+
+	sentenceLength := 0
+	totalLength := 0
+	endSentence := func() {
+		if sentenceLength == 0 {
+			return
+		}
+		report.Sentences++
+		totalLength += sentenceLength
+		if sentenceLength > report.MaxSentenceLength {
+			report.MaxSentenceLength = sentenceLength
+		}
+		sentenceLength = 0
+	}
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.Is(unicode.Han, r):
+			report.Characters++
+			sentenceLength++
+			tier, ok := zhRanks[r]
+			if !ok {
+				tier = TierAdvanced
+			}
+			count(string(r), tier)
+		case isLetter(r):
+			start := i
+			for i+1 < len(runes) && (isLetter(runes[i+1]) || runes[i+1] == '\'' && i+2 < len(runes) && isLetter(runes[i+2])) {
+				i++
+			}
+			word := string(runes[start : i+1])
+			report.Words++
+			// 大写开头的生词视为人名、地名等专有名词，不计为生僻词
+			tier := wordTier(word)
+			if tier == TierAdvanced && unicode.IsUpper(runes[start]) {
+				tier = TierBasic
+			}
+			sentenceLength++
+			count(strings.ToLower(word), tier)
+		case isSentenceEnd(runes, i):
+			endSentence()
+		}
+	}
+	endSentence()
+
+	units := report.Characters + report.Words
+	if units == 0 {
+		return report
+	}
+	if report.Characters >= report.Words {
+		report.Language = LanguageChinese
+	} else {
+		report.Language = LanguageEnglish
+	}
+	report.AvgSentenceLength = round(float64(totalLength) / float64(report.Sentences))
+	report.BasicRatio = round(float64(report.Tiers.Basic) / float64(units))
+	report.AdvancedRatio = round(float64(report.Tiers.Advanced) / float64(units))
+
+	sort.SliceStable(uncommonOrder, func(i, j int) bool {
+		return uncommon[uncommonOrder[i]] > uncommon[uncommonOrder[j]]
+	})
+	for _, token := range uncommonOrder {
+		if len(report.UncommonWords) >= maxUncommonWords {
+			break
+		}
+		report.UncommonWords = append(report.UncommonWords, UncommonWord{Text: token, Count: uncommon[token]})
+	}
+
+	report.EstimatedAge = MaxTargetAge
+	for _, level := range levels {
+		if len(Check(report, level)) == 0 {
+			report.EstimatedAge = level.Age
+			break
+		}
+	}
+	return report
+}
+
+// Check 检查分析结果是否超过难度上限，返回超出的指标
+func Check(report *Report, level Level) []Issue {
+	var issues []Issue
+	if report.Characters+report.Words == 0 {
+		return issues
+	}
+	maxSentence := level.MaxSentenceZh
+	if report.Language == LanguageEnglish {
+		maxSentence = level.MaxSentenceEn
+	}
+	if report.AvgSentenceLength > maxSentence {
+		issues = append(issues, Issue{Metric: "avg_sentence_length", Value: report.AvgSentenceLength, Limit: maxSentence})
+	}
+	if report.BasicRatio < level.MinBasicRatio {
+		issues = append(issues, Issue{Metric: "basic_ratio", Value: report.BasicRatio, Limit: level.MinBasicRatio})
+	}
+	if report.AdvancedRatio > level.MaxAdvancedRatio {
+		issues = append(issues, Issue{Metric: "advanced_ratio", Value: report.AdvancedRatio, Limit: level.MaxAdvancedRatio})
+	}
+	return issues
+}
+
+// wordTier 获取英文单词的等级，词表中找不到时去掉常见的词尾再查找
+func wordTier(word string) string {
+	word = strings.ToLower(word)
+	if i := strings.IndexByte(word, '\''); i >= 0 {
+		word = word[:i]
+	}
+	if tier, ok := enTiers[word]; ok {
+		return tier
+	}
+	for _, stem := range stems(word) {
+		if tier, ok := enTiers[stem]; ok {
+			return tier
+		}
+	}
+	return TierAdvanced
+}
+
+// stems 去掉复数、过去式、进行时等常见词尾后可能的词干
+func stems(word string) []string {
+	var result []string
+	suffixes := []struct{ suffix, replace string }{
+		{"ies", "y"}, {"ied", "y"}, {"es", ""}, {"s", ""},
+		{"ed", ""}, {"ed", "e"}, {"ing", ""}, {"ing", "e"},
+		{"er", ""}, {"est", ""}, {"ly", ""}, {"ily", "y"},
+	}
+	for _, s := range suffixes {
+		if !strings.HasSuffix(word, s.suffix) || len(word)-len(s.suffix) < 2 {
+			continue
+		}
+		stem := word[:len(word)-len(s.suffix)]
+		result = append(result, stem+s.replace)
+		// running -> run，stopped -> stop
+		if n := len(stem); s.replace == "" && n >= 3 && stem[n-1] == stem[n-2] {
+			result = append(result, stem[:n-1])
+		}
+	}
+	return result
+}
+
+// isLetter 是否为英文字母
+func isLetter(r rune) bool {
+	return r < unicode.MaxASCII && unicode.IsLetter(r)
+}
+
+// isSentenceEnd 是否为句子结束的标点，英文句点后面紧跟字母或数字时（如小数、缩写）不算
+func isSentenceEnd(runes []rune, i int) bool {
+	switch runes[i] {
+	case '。', '！', '？', '；', '…', '!', '?', ';', '\n':
+		return true
+	case '.':
+		return i+1 >= len(runes) || !(isLetter(runes[i+1]) || unicode.IsDigit(runes[i+1]))
+	}
+	return false
+}
+
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
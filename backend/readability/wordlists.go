@@ -0,0 +1,48 @@
+package readability
+
+import "strings"
+
+// 字词频率分级：basic 为学龄前儿童就能认读的高频字词，common 为小学阶段的常用字词，其余为生僻字词
+
+// zhBasicChars 最常用的汉字，按字频排列
+const zhBasicChars = `的一是了我不人在他有这个上们来到时大地为子中你说生国年着就那和要她出也得里后自以会家可下而过天去能对小多然于心学么之都好看起发当没成只如事把还用第样道想作种开美总从无情己面最女但现前些所同日手又行意动方期它头经长儿回位分爱老因很给名法间斯知世什两次使身者被高已亲其进此话常与活正感见明问力理尔点文几定本公特做外孩相西果走将月十实向声车全信重三机工物气每并别真打太新比才便夫再书部水像眼等体却加电主界门利海受听表德少克代员许先口由死安写性马光白或住难望教命花结乐色更拉东神记处让母父应直字场平报友关放至张认接告入笑内英军候民岁往何度山觉路带万男边风解叫任金快原吃妈变通师立象数四失满战远格士音轻目条呢病始达深完今提求清王化空业思切怎非找片罗钱紧吗语元喜曾离飞科言干流欢约各即指合反题必该论交终林请医晚制球决传画保读运及则房早院量苦火布品近坐产答星精视五连司巴奇管类未朋且婚台夜青北队久乎越观落尽形影红爸百令周吧识步希亚术留市半热送兴造谈容极随演收首根讲整式取照办强石古华拿计您装似足双妻尼转诉米称丽客南领节衣站黑刻统断福城故历惊脸选包紧争另建维绝树系伤示愿持千史谁准联妇纪基买志静阿诗独复痛消社算义竟确酒需单治卡幸兰念举仅钟怕共毛句息功官待究跟穿室易游程号居考突皮哪费倒价图具刚脑永歌响商礼细专黄块脚味灵改据般破引食仍存众注笔甚某沉血备习校默务土微娘须试怀料调广苏显赛查密议底列富梦错座参八除跑亮假印设线温虽掉京初养香停际致阳纸李纳验助激够严证帝饭忘趣支春集丈木研班普导顿睡展跳获艺六波察群皇段急庭创区奥器谢弟店否害草排背止组州朝封睛板角况曲馆育忙质河续哥呼若推境遇雨标姐充围案伦护冷警贝著雪索剧啊船险烟依斗值帮汉慢佛肯闻唱沙局伯族低玩资屋击速顾泪洲团圣旁堂兵七露园牛哭旅街劳型烈姑陈莫鱼异抱宝权鲁简态级票怪寻杀律胜份汽右洋范床舞秘午登楼贵吸责例追较职属渐左录丝牙党继托赶章智冲叶胡吉卖坚喝肉遗救修松临藏担戏善卫药悲敢靠伊村戴词森耳差短祖云规窗散迷油旧适乡架恩投弹铁博雷府压超负勒杂醒洗采毫嘴毕九冰既状乱景席珍童顶派素脱农疑练野按犯拍征坏骨余承置彩灯巨琴免环姆暗换技翻束增忍餐洛塞缺忆判欧层付阵玛批岛项狗休懂武革良恶恋委拥娜妙探呀营退摇弄桌熟诺宣银势奖宫忽套康供优课鸟喊降夏困刘罪亡鞋健模败伴守挥鲜财孤枪禁恐伙杰迹妹遍盖副坦牌江顺秋萨菜划授归浪听凡预奶雄升编典袋莱含盛济蒙棋端腿招释介烧误乾坤`
+
+// zhCommonChars 小学阶段的常用汉字，按字频排列
+const zhCommonChars = `萝卜奔柔帽弱锁坡埋滑欣稍滴劲鼓扬抓摸踢扫擦挤揉抢摔捡抬搬拔插握扔拾丢拖拉推捧扶搭披戴盖盼泼洒浇溪湖泉浮沿源池滚浓淡湿泥浅渡游泳冻冷冰凉暖烫烤炒煮蒸炸焦烂熊猫狐狸猴鹿兔鼠蛇龟蝴蝶蜂蚂蚁蜻蜓蝉鸡鸭鹅猪羊狼虎豹象鲸鹰燕雀鹊鸽鸦孔雀麻蛙虾蟹贝壳芽苗叶根茎枝杆瓜豆麦稻谷粮桃李杏梨橘枣莓葡萄苹蕉荷莲菊梅兰竹柳杨桂枫松柏草茶芬芳香甜酸辣咸苦涩饿饱渴馋饺馒粥汤糖盐醋酱奶蛋碗筷勺盘杯壶锅灶刀叉铲梳镜盆桶伞扇毯枕被褥袜裙裤衫袄帽靴钮扣针线绳布棉丝绸彩虹霞雾霜露冰雹雷闪晴阴暑寒昼晨昏夕宵钟秒岁周旬季节令朗诵吟唱歌舞蹈琴笛鼓锣铃哨纸笔墨砚橡皮尺胶粘剪贴画涂描绘蜡烛灯泡钉锤锯铲斧犁耙镰锄筐篮箱柜抽屉窗帘墙壁砖瓦柱梁顶棚厨卫厕浴桥梯栏杆篱笆院巷胡同郊野牧场庄园村镇街坊邻居朋伙伴姑姨舅婶叔伯爷奶孙侄甥婴宝贝淘乖傻呆笨聪灵巧勤懒馋骄傲谦虚诚实勇敢胆怯羞害慌乱急躁耐烦悄静闹吵嚷喊叫哭泣笑嘻哈嘿哇咦哎呦啦呐嘛吧呗哦噢嗯唉嘘咕噜嗡哗啪咚砰嘀嗒咔嚓扑通叮当轰隆汪喵咩哞嘎叽喳`
+
+// enBasicWords 最常用的英文单词（常见的启蒙阅读高频词）
+const enBasicWords = `a about after again all always am an and any are around as ask at ate away baby back bag bad ball be bear because bed been before best big bird black blue boat book both box boy bring brown but buy by cake call came can car cat children come could cow cut day did dinner do does dog doll done door down draw drink duck each eat egg eight every eye fall far fast father find fish five floor fly for found four friend from full funny game gave get girl give go goes going good got green grow had happy has have he head help her here him his hold home hot house how hurt i if in into is it its jump just keep kind know laugh let like little live long look love made make man many mat may me milk mom money more morning mother much must my myself name never new nice night no not now of off old on once one only open or our out over own pick play please pretty pull put ran read red ride right room round run said sat saw say school see seven shall she show sing sit six sleep small so some soon start stop sun table take tell ten thank that the their them then there these they thing think this those three to today together too toy tree try two under up upon us use very walk want warm was wash water way we well went were what when where which white who why will wish with work would write yellow yes you your`
+
+// enCommonWords 小学阶段的常用英文单词
+const enCommonWords = `able above across act add afraid against age ago agree air almost alone along already also although among angry animal another answer anything apple area arm army art begin behind believe bell below beside better between bicycle bit blow body bone bottom brave bread break breakfast bridge bright broke brother build burn busy butter button cage candy cap care carry catch cause center chair chance change cheese chicken child choose circle city class clean clear climb clock close cloth cloud coat cold color cook cool corn corner count country course cover crowd cry cup dance dark dear deep desk different dinosaur dirty dish doctor dollar dragon dream dress drive drop dry during early earth easy edge else end enough even evening ever example face fact fair family farm farmer fat fear feel feet few field fight fill final fine finger finish fire first flower follow food foot forest forget fox free fresh front fruit garden gate gentle gift glad glass gold grass great ground group guess hair half hand hang hard hat hear heart heavy high hill hit hole hope horse hour hundred hungry idea important inside island job join juice kept key kick kill king kitchen kitten knee knew lady lake land large last late later lay lead learn leave left leg less letter life lift light line lion list listen lost loud low lunch magic mail map mark matter maybe mean meet middle might mind minute miss monkey moon most mountain mouse mouth move music near neck need nest next noise nose note nothing number ocean often orange order other outside page paint pair paper parent park part party pass pay pen people perhaps person picture piece pig place plan plant plate point pond pony poor present princess prince problem puppy push queen question quick quiet rabbit rain reach ready real remember rest river road rock roof rope rose sad safe sail same sand save scared sea season seat second seed seem sell send set shape share sheep ship shoe shop short should shout side sign silly simple sister size sky slow smell smile snow soft song sorry sound space speak special spell spring square stand star stay step stick still stone store story street strong such sudden summer supper sure surprise swim tail talk tall taste teach teacher team teeth than thick thin though thought through throw tiny tired top touch town train travel trip truck true turn turtle uncle until visit voice wait wake wall watch wave wear weather week whole wide wild wind window winter wolf woman wonder wood word world worry year yet young zoo`
+
+var (
+	zhRanks = rankChars(zhBasicChars, zhCommonChars)
+	enTiers = tierWords(enBasicWords, enCommonWords)
+)
+
+// rankChars 为各级汉字标记等级，重复出现的字以较高的等级为准
+func rankChars(levels ...string) map[rune]string {
+	ranks := make(map[rune]string)
+	for i, chars := range levels {
+		for _, r := range chars {
+			if _, ok := ranks[r]; !ok {
+				ranks[r] = tierNames[i]
+			}
+		}
+	}
+	return ranks
+}
+
+// tierWords 为各级英文单词标记等级
+func tierWords(levels ...string) map[string]string {
+	tiers := make(map[string]string)
+	for i, words := range levels {
+		for _, word := range strings.Fields(words) {
+			if _, ok := tiers[word]; !ok {
+				tiers[word] = tierNames[i]
+			}
+		}
+	}
+	return tiers
+}
@@ -2,9 +2,12 @@ package repository
 
 import (
 	"grandma/backend/models"
+	"grandma/backend/search"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ConversationRepository struct {
@@ -19,7 +22,11 @@ func NewConversationRepository(db *gorm.DB) *ConversationRepository {
 func (r *ConversationRepository) Create(conversation *models.Conversation) error {
 	conversation.CreatedAt = time.Now()
 	conversation.UpdatedAt = time.Now()
-	return r.db.Create(conversation).Error
+	if err := r.db.Create(conversation).Error; err != nil {
+		return err
+	}
+	indexConversation(r.db, conversation)
+	return nil
 }
 
 // GetByID 根据ID获取对话
@@ -29,68 +36,522 @@ func (r *ConversationRepository) GetByID(id string) (*models.Conversation, error
 	if err != nil {
 		return nil, err
 	}
+	if err := r.fillDocumentIDs(&conversation); err != nil {
+		return nil, err
+	}
+	if err := r.fillTags(&conversation); err != nil {
+		return nil, err
+	}
 	return &conversation, nil
 }
 
-// List 获取对话列表
-func (r *ConversationRepository) List(page, pageSize int) ([]models.Conversation, int64, error) {
-	var conversations []models.Conversation
-	var total int64
+// GetMetaByID 根据ID获取对话，不加载关联的文档
+func (r *ConversationRepository) GetMetaByID(id string) (*models.Conversation, error) {
+	var conversation models.Conversation
+	err := r.db.Where("id = ?", id).First(&conversation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+// 对话列表的排序字段
+const (
+	ConversationSortUpdated = "updated_at"
+	ConversationSortCreated = "created_at"
+	ConversationSortTitle   = "title"
+)
+
+// ConversationFilter 对话列表的筛选和排序条件
+type ConversationFilter struct {
+	FolderID    *string  // 不为nil时只返回该文件夹中的对话，空字符串表示未分类的对话
+	Tags        []string // 只返回同时带有这些标签的对话
+	Archived    *bool    // 不为nil时按是否归档筛选
+	Pinned      *bool    // 不为nil时按是否置顶筛选
+	Sort        string   // 排序字段，为空时按更新时间
+	Ascending   bool     // 是否正序
+	PinnedFirst bool     // 置顶的对话是否排在最前
+}
 
-	offset := (page - 1) * pageSize
-	if offset < 0 {
-		offset = 0
+// apply 将筛选条件加到查询上
+func (f *ConversationFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.FolderID != nil {
+		query = query.Where("folder_id = ?", *f.FolderID)
 	}
+	for _, tag := range f.Tags {
+		query = query.Where("id IN (SELECT conversation_id FROM conversation_tags WHERE tag = ?)", tag)
+	}
+	if f.Archived != nil {
+		query = query.Where("archived = ?", *f.Archived)
+	}
+	if f.Pinned != nil {
+		query = query.Where("pinned = ?", *f.Pinned)
+	}
+	return query
+}
+
+// keys 排序条件对应的翻页排序列
+func (f *ConversationFilter) keys() []sortKey[models.Conversation] {
+	var keys []sortKey[models.Conversation]
+	if f.PinnedFirst {
+		keys = append(keys, sortKey[models.Conversation]{
+			column: "pinned", kind: keyBool, desc: true,
+			value: func(c *models.Conversation) interface{} { return c.Pinned },
+		})
+	}
+	switch f.Sort {
+	case ConversationSortCreated:
+		keys = append(keys, sortKey[models.Conversation]{
+			column: "created_at", kind: keyTime, desc: !f.Ascending,
+			value: func(c *models.Conversation) interface{} { return c.CreatedAt },
+		})
+	case ConversationSortTitle:
+		keys = append(keys, sortKey[models.Conversation]{
+			column: "title", kind: keyText, desc: !f.Ascending,
+			value: func(c *models.Conversation) interface{} { return c.Title },
+		})
+	default:
+		keys = append(keys, sortKey[models.Conversation]{
+			column: "updated_at", kind: keyTime, desc: !f.Ascending,
+			value: func(c *models.Conversation) interface{} { return c.UpdatedAt },
+		})
+	}
+	return keys
+}
+
+func conversationID(c *models.Conversation) string { return c.ID }
+
+// List 按游标获取一页符合条件的对话，total 为符合条件的对话总数
+// 游标为空时从列表开头返回；next_cursor 指向列表中靠后的对话，prev_cursor 指向靠前的对话
+// 游标只能用于创建它时的排序方式，排序方式改变后需要从头翻页
+func (r *ConversationRepository) List(filter ConversationFilter, cursor string, pageSize int) ([]models.Conversation, int64, *models.PageInfo, error) {
+	var total int64
 	if pageSize <= 0 {
 		pageSize = 20
 	}
 
-	err := r.db.Model(&models.Conversation{}).Count(&total).Error
+	err := filter.apply(r.db.Model(&models.Conversation{})).Count(&total).Error
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, nil, err
 	}
 
-	err = r.db.Order("updated_at DESC").Offset(offset).Limit(pageSize).Find(&conversations).Error
+	conversations, page, err := keysetPage(filter.apply(r.db), filter.keys(), conversationID, cursor, pageSize, false)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, nil, err
 	}
 
-	return conversations, total, nil
+	pointers := make([]*models.Conversation, len(conversations))
+	for i := range conversations {
+		pointers[i] = &conversations[i]
+	}
+	if err := r.fillDocumentIDs(pointers...); err != nil {
+		return nil, 0, nil, err
+	}
+	if err := r.fillTags(pointers...); err != nil {
+		return nil, 0, nil, err
+	}
+	return conversations, total, page, nil
 }
 
-// Update 更新对话
-func (r *ConversationRepository) Update(conversation *models.Conversation) error {
-	conversation.UpdatedAt = time.Now()
-	return r.db.Save(conversation).Error
+// Update 更新对话的标题和修改时间，其他字段保持不变
+func (r *ConversationRepository) Update(id, title string) error {
+	result := r.db.Model(&models.Conversation{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"title":      title,
+		"updated_at": time.Now(),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	indexConversation(r.db, &models.Conversation{ID: id, Title: title})
+	return nil
 }
 
-// Delete 删除对话
+// Delete 将对话及其文档移入回收站，使用同一个删除时间，恢复时一起恢复
 func (r *ConversationRepository) Delete(id string) error {
-	return r.db.Delete(&models.Conversation{}, "id = ?", id).Error
+	now := time.Now()
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Conversation{}).Where("id = ?", id).Update("deleted_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		// 已单独删除的文档保持原来的删除时间，恢复对话时不会一起恢复
+		return tx.Model(&models.Document{}).Where("conversation_id = ?", id).Update("deleted_at", now).Error
+	})
+	if err != nil {
+		return err
+	}
+	removeEntries(r.db, search.KindConversation, "ref_id = ?", id)
+	removeEntries(r.db, search.KindDocument, "conversation_id = ?", id)
+	return nil
+}
+
+// GetDeletedByID 获取回收站中的对话
+func (r *ConversationRepository) GetDeletedByID(id string) (*models.Conversation, error) {
+	var conversation models.Conversation
+	err := r.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&conversation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+// ListDeleted 获取回收站中的对话，最近删除的在前
+func (r *ConversationRepository) ListDeleted(limit int) ([]models.Conversation, error) {
+	var conversations []models.Conversation
+	err := r.db.Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at DESC").Limit(limit).Find(&conversations).Error
+	if err != nil {
+		return nil, err
+	}
+	return conversations, nil
+}
+
+// Restore 从回收站恢复对话，以及随对话一起删除的文档
+func (r *ConversationRepository) Restore(id string) error {
+	conversation, err := r.GetDeletedByID(id)
+	if err != nil {
+		return err
+	}
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Model(&models.Document{}).
+			Where("conversation_id = ? AND deleted_at = ?", id, conversation.DeletedAt.Time).
+			Update("deleted_at", nil).Error
+		if err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&models.Conversation{}).Where("id = ?", id).Update("deleted_at", nil).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	conversation.DeletedAt = gorm.DeletedAt{}
+	indexConversation(r.db, conversation)
+	var documents []models.Document
+	if err := r.db.Where("conversation_id = ?", id).Find(&documents).Error; err != nil {
+		return err
+	}
+	for i := range documents {
+		indexDocument(r.db, &documents[i])
+	}
+	return nil
+}
+
+// Purge 永久删除对话及其所有文档和摘要，包括已在回收站中的
+func (r *ConversationRepository) Purge(id string) error {
+	removeEntries(r.db, search.KindConversation, "ref_id = ?", id)
+	removeEntries(r.db, search.KindDocument, "conversation_id = ?", id)
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		documents := tx.Unscoped().Model(&models.Document{}).Select("id").Where("conversation_id = ?", id)
+		if err := deleteRevisions(tx, models.RevisionTargetDocument, documents); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("conversation_id = ?", id).Delete(&models.Document{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("conversation_id = ?", id).Delete(&models.ConversationSummary{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("conversation_id = ?", id).Delete(&models.ConversationTag{}).Error; err != nil {
+			return err
+		}
+		if err := deleteShareLinks(tx, models.ShareTargetConversation, id); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.Conversation{}, "id = ?", id).Error
+	})
+}
+
+// ListDeletedBefore 获取在指定时间之前移入回收站的对话ID
+func (r *ConversationRepository) ListDeletedBefore(cutoff time.Time) ([]string, error) {
+	var ids []string
+	err := r.db.Unscoped().Model(&models.Conversation{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
 }
 
 // UpdateTitle 更新对话标题
 func (r *ConversationRepository) UpdateTitle(id, title string) error {
-	return r.db.Model(&models.Conversation{}).Where("id = ?", id).Update("title", title).Error
+	result := r.db.Model(&models.Conversation{}).Where("id = ?", id).Update("title", title)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		indexConversation(r.db, &models.Conversation{ID: id, Title: title})
+	}
+	return nil
 }
 
-// AppendDocumentID 添加文档ID到对话的文档ID列表
+// UpdateAgeBand 更新对话内容安全过滤使用的年龄段
+func (r *ConversationRepository) UpdateAgeBand(id, ageBand string) error {
+	return r.db.Model(&models.Conversation{}).Where("id = ?", id).Update("age_band", ageBand).Error
+}
+
+// UpdateTargetAge 更新对话的目标读者年龄
+func (r *ConversationRepository) UpdateTargetAge(id string, targetAge int) error {
+	return r.db.Model(&models.Conversation{}).Where("id = ?", id).Update("target_age", targetAge).Error
+}
+
+// AppendDocumentID 将文档加入对话的文档列表：为文档分配对话内的下一个序号
+// 序号在一条UPDATE语句中计算，并由 (conversation_id, seq) 唯一索引保证不重复；已在列表中的文档保持原序号
 func (r *ConversationRepository) AppendDocumentID(id, documentID string) error {
-	conversation, err := r.GetByID(id)
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Conversation{}).
+			Where("id = ?", id).
+			Update("updated_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Model(&models.Document{}).
+			Where("id = ? AND conversation_id = ? AND seq IS NULL", documentID, id).
+			Update("seq", gorm.Expr("(SELECT COALESCE(MAX(seq), 0) + 1 FROM documents WHERE conversation_id = ?)", id)).
+			Error
+	})
+}
+
+// fillDocumentIDs 按文档序号生成对话的文档ID列表
+func (r *ConversationRepository) fillDocumentIDs(conversations ...*models.Conversation) error {
+	if len(conversations) == 0 {
+		return nil
+	}
+	ids := make([]string, len(conversations))
+	for i, conversation := range conversations {
+		ids[i] = conversation.ID
+	}
+
+	var rows []struct {
+		ID             string
+		ConversationID string
+	}
+	err := r.db.Model(&models.Document{}).
+		Select("id, conversation_id").
+		Where("conversation_id IN ? AND seq IS NOT NULL", ids).
+		Order("conversation_id, seq").
+		Find(&rows).Error
 	if err != nil {
 		return err
 	}
 
-	var newDocumentIDs string
-	if conversation.DocumentIDs == "" {
-		newDocumentIDs = documentID
-	} else {
-		newDocumentIDs = conversation.DocumentIDs + "," + documentID
+	documentIDs := make(map[string][]string)
+	for _, row := range rows {
+		documentIDs[row.ConversationID] = append(documentIDs[row.ConversationID], row.ID)
 	}
+	for _, conversation := range conversations {
+		conversation.DocumentIDs = strings.Join(documentIDs[conversation.ID], ",")
+	}
+	return nil
+}
+
+// fillTags 加载对话的标签，按标签名排序
+func (r *ConversationRepository) fillTags(conversations ...*models.Conversation) error {
+	if len(conversations) == 0 {
+		return nil
+	}
+	ids := make([]string, len(conversations))
+	for i, conversation := range conversations {
+		ids[i] = conversation.ID
+	}
+
+	var rows []models.ConversationTag
+	err := r.db.Where("conversation_id IN ?", ids).Order("conversation_id, tag").Find(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	tags := make(map[string][]string)
+	for _, row := range rows {
+		tags[row.ConversationID] = append(tags[row.ConversationID], row.Tag)
+	}
+	for _, conversation := range conversations {
+		conversation.Tags = tags[conversation.ID]
+		if conversation.Tags == nil {
+			conversation.Tags = []string{}
+		}
+	}
+	return nil
+}
+
+// GetImportedIDs 获取已从来源导入的对话（包括回收站中的），返回来源中的ID到对话ID的映射
+func (r *ConversationRepository) GetImportedIDs(source string, sourceIDs []string) (map[string]string, error) {
+	imported := make(map[string]string)
+	for start := 0; start < len(sourceIDs); start += 500 {
+		end := min(start+500, len(sourceIDs))
+		var rows []models.Conversation
+		err := r.db.Unscoped().Select("id", "source_id").
+			Where("source = ? AND source_id IN ?", source, sourceIDs[start:end]).
+			Find(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			imported[row.SourceID] = row.ID
+		}
+	}
+	return imported, nil
+}
+
+// Import 在一个事务中保存导入的对话及其文档，保留原来的创建和更新时间，并建立搜索索引
+func (r *ConversationRepository) Import(conversation *models.Conversation, documents []models.Document) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(conversation).Error; err != nil {
+			return err
+		}
+		if len(documents) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(documents, 100).Error
+	})
+	if err != nil {
+		return err
+	}
+	indexConversation(r.db, conversation)
+	for i := range documents {
+		indexDocument(r.db, &documents[i])
+	}
+	return nil
+}
+
+// GetTags 获取对话的标签，按标签名排序
+func (r *ConversationRepository) GetTags(id string) ([]string, error) {
+	conversation := &models.Conversation{ID: id}
+	if err := r.fillTags(conversation); err != nil {
+		return nil, err
+	}
+	return conversation.Tags, nil
+}
+
+// ForEach 按ID顺序分批遍历所有对话（不含回收站中的），每批加载标签后交给 fn 处理
+func (r *ConversationRepository) ForEach(batchSize int, fn func(conversations []models.Conversation) error) error {
+	var conversations []models.Conversation
+	return r.db.FindInBatches(&conversations, batchSize, func(tx *gorm.DB, batch int) error {
+		pointers := make([]*models.Conversation, len(conversations))
+		for i := range conversations {
+			pointers[i] = &conversations[i]
+		}
+		if err := r.fillTags(pointers...); err != nil {
+			return err
+		}
+		return fn(conversations)
+	}).Error
+}
+
+// ExistingIDs 返回ID列表中存在（不在回收站中）的对话ID
+func (r *ConversationRepository) ExistingIDs(ids []string) ([]string, error) {
+	var existing []string
+	if len(ids) == 0 {
+		return existing, nil
+	}
+	err := r.db.Model(&models.Conversation{}).Where("id IN ?", ids).Pluck("id", &existing).Error
+	if err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// 整理对话（置顶、归档、移动、标签）不改变对话的更新时间，避免打乱按更新时间排列的列表
+
+// SetPinned 批量置顶或取消置顶对话，返回修改的对话数
+func (r *ConversationRepository) SetPinned(ids []string, pinned bool) (int64, error) {
+	result := r.db.Model(&models.Conversation{}).Where("id IN ? AND pinned = ?", ids, !pinned).UpdateColumn("pinned", pinned)
+	return result.RowsAffected, result.Error
+}
+
+// SetArchived 批量归档或取消归档对话，返回修改的对话数
+func (r *ConversationRepository) SetArchived(ids []string, archived bool) (int64, error) {
+	var archivedAt *time.Time
+	if archived {
+		now := time.Now()
+		archivedAt = &now
+	}
+	result := r.db.Model(&models.Conversation{}).
+		Where("id IN ? AND archived = ?", ids, !archived).
+		UpdateColumns(map[string]interface{}{"archived": archived, "archived_at": archivedAt})
+	return result.RowsAffected, result.Error
+}
+
+// SetFolder 批量将对话移动到文件夹，folderID 为空表示移出文件夹，返回修改的对话数
+func (r *ConversationRepository) SetFolder(ids []string, folderID string) (int64, error) {
+	result := r.db.Model(&models.Conversation{}).Where("id IN ? AND folder_id <> ?", ids, folderID).UpdateColumn("folder_id", folderID)
+	return result.RowsAffected, result.Error
+}
+
+// AddTags 为对话批量添加标签，已有的标签保持不变，返回新添加的标签数
+func (r *ConversationRepository) AddTags(ids, tags []string) (int64, error) {
+	if len(ids) == 0 || len(tags) == 0 {
+		return 0, nil
+	}
+	now := time.Now()
+	rows := make([]models.ConversationTag, 0, len(ids)*len(tags))
+	for _, id := range ids {
+		for _, tag := range tags {
+			rows = append(rows, models.ConversationTag{ConversationID: id, Tag: tag, CreatedAt: now})
+		}
+	}
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(rows, 200)
+	return result.RowsAffected, result.Error
+}
+
+// RemoveTags 批量移除对话的标签，返回移除的标签数
+func (r *ConversationRepository) RemoveTags(ids, tags []string) (int64, error) {
+	if len(ids) == 0 || len(tags) == 0 {
+		return 0, nil
+	}
+	result := r.db.Where("conversation_id IN ? AND tag IN ?", ids, tags).Delete(&models.ConversationTag{})
+	return result.RowsAffected, result.Error
+}
+
+// SetTags 将对话的标签替换为指定的标签
+func (r *ConversationRepository) SetTags(id string, tags []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("conversation_id = ?", id).Delete(&models.ConversationTag{}).Error; err != nil {
+			return err
+		}
+		if len(tags) == 0 {
+			return nil
+		}
+		now := time.Now()
+		rows := make([]models.ConversationTag, len(tags))
+		for i, tag := range tags {
+			rows[i] = models.ConversationTag{ConversationID: id, Tag: tag, CreatedAt: now}
+		}
+		return tx.Create(&rows).Error
+	})
+}
+
+// ListTags 获取所有标签及使用该标签的对话数，不统计回收站中的对话
+func (r *ConversationRepository) ListTags() ([]models.TagCount, error) {
+	var tags []models.TagCount
+	err := r.db.Model(&models.ConversationTag{}).
+		Select("conversation_tags.tag AS tag, COUNT(*) AS count").
+		Joins("JOIN conversations ON conversations.id = conversation_tags.conversation_id AND conversations.deleted_at IS NULL").
+		Group("conversation_tags.tag").
+		Order("count DESC, tag").
+		Scan(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
 
+// UpdateHeadDocumentID 更新对话当前活动分支的最后一条文档
+func (r *ConversationRepository) UpdateHeadDocumentID(id, documentID string) error {
 	return r.db.Model(&models.Conversation{}).
 		Where("id = ?", id).
-		Update("document_ids", newDocumentIDs).
-		Update("updated_at", time.Now()).
+		Updates(map[string]interface{}{
+			"head_document_id": documentID,
+			"updated_at":       time.Now(),
+		}).
 		Error
 }
@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"grandma/backend/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 翻页方向，相对于列表的展示顺序
+const (
+	CursorBefore = "before" // 游标之前的记录
+	CursorAfter  = "after"  // 游标之后的记录
+)
+
+// ErrInvalidCursor 游标无法解析，或与本次查询的排序方式不一致
+var ErrInvalidCursor = errors.New("invalid_cursor")
+
+// 排序列值的类型，用于还原游标中的值
+const (
+	keyTime = iota
+	keyText
+	keyBool
+)
+
+// sortKey 键集翻页的一个排序列，value 从记录中取出该列的值
+type sortKey[T any] struct {
+	column string
+	kind   int
+	desc   bool
+	value  func(*T) interface{}
+}
+
+// Cursor 键集翻页的位置：边界记录各排序列的值和ID，以及翻页方向
+// 以 (排序列..., ID) 作为排序键，排序列相同的记录按ID区分，翻页时不会跳过或重复
+type Cursor struct {
+	Direction string        `json:"d"`
+	Sort      string        `json:"s"` // 排序方式，与本次查询不一致时游标无效
+	Values    []interface{} `json:"v"`
+	ID        string        `json:"id"`
+}
+
+// Encode 将游标编码为返回给客户端的不透明字符串
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// sortSignature 排序方式的标识，写入游标用于校验
+func sortSignature[T any](keys []sortKey[T]) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		order := "asc"
+		if key.desc {
+			order = "desc"
+		}
+		parts[i] = key.column + ":" + order
+	}
+	return strings.Join(parts, ",")
+}
+
+// newCursor 以记录为边界创建游标
+func newCursor[T any](keys []sortKey[T], item *T, id, direction string) Cursor {
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		values[i] = key.value(item)
+	}
+	return Cursor{Direction: direction, Sort: sortSignature(keys), Values: values, ID: id}
+}
+
+// decodeCursor 解析客户端传入的游标，并按排序列的类型还原各列的值；空字符串返回nil
+func decodeCursor[T any](value string, keys []sortKey[T]) (*Cursor, error) {
+	if value == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.ID == "" || (cursor.Direction != CursorBefore && cursor.Direction != CursorAfter) ||
+		cursor.Sort != sortSignature(keys) || len(cursor.Values) != len(keys) {
+		return nil, ErrInvalidCursor
+	}
+	for i, key := range keys {
+		switch key.kind {
+		case keyTime:
+			text, ok := cursor.Values[i].(string)
+			if !ok {
+				return nil, ErrInvalidCursor
+			}
+			t, err := time.Parse(time.RFC3339Nano, text)
+			if err != nil {
+				return nil, ErrInvalidCursor
+			}
+			cursor.Values[i] = t
+		case keyText:
+			if _, ok := cursor.Values[i].(string); !ok {
+				return nil, ErrInvalidCursor
+			}
+		case keyBool:
+			if _, ok := cursor.Values[i].(bool); !ok {
+				return nil, ErrInvalidCursor
+			}
+		}
+	}
+	return &cursor, nil
+}
+
+// keysetPage 按排序列从游标位置取一页记录，返回的记录按展示顺序排列
+// 游标为空时 fromEnd 为false从第一条开始，为true时取最后一页；hasMore 表示请求的方向上还有更多记录
+// prev_cursor 指向本页之前的记录，next_cursor 指向本页之后的记录；本页为空时两个游标都指向传入游标的位置
+// has_prev、has_next 分别表示本页之前、之后是否还有记录
+func keysetPage[T any](query *gorm.DB, keys []sortKey[T], idOf func(*T) string, cursor string, limit int, fromEnd bool) ([]T, *models.PageInfo, error) {
+	pageCursor, err := decodeCursor(cursor, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	direction := CursorAfter
+	if fromEnd {
+		direction = CursorBefore
+	}
+	if pageCursor != nil {
+		direction = pageCursor.Direction
+	}
+	// 向前翻页时按展示顺序的逆序查询，取到后再反转
+	reversed := direction == CursorBefore
+
+	// id 作为最后一个排序列，方向与前一列相同
+	columns := make([]string, 0, len(keys)+1)
+	descs := make([]bool, 0, len(keys)+1)
+	for _, key := range keys {
+		columns = append(columns, key.column)
+		descs = append(descs, key.desc)
+	}
+	columns = append(columns, "id")
+	descs = append(descs, descs[len(descs)-1])
+
+	// 同一个查询还用于检查另一个方向上是否有记录
+	base := query.Session(&gorm.Session{})
+	find := base
+	if pageCursor != nil {
+		condition, args := keysetCondition(columns, descs, pageCursor, reversed)
+		find = find.Where(condition, args...)
+	}
+	for i, column := range columns {
+		order := "ASC"
+		if descs[i] != reversed {
+			order = "DESC"
+		}
+		find = find.Order(column + " " + order)
+	}
+
+	var items []T
+	if err := find.Limit(limit + 1).Find(&items).Error; err != nil {
+		return nil, nil, err
+	}
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+	if reversed {
+		reverse(items)
+	}
+
+	page := &models.PageInfo{HasMore: hasMore}
+	var prev, next Cursor
+	switch {
+	case len(items) > 0:
+		first, last := &items[0], &items[len(items)-1]
+		prev = newCursor(keys, first, idOf(first), CursorBefore)
+		next = newCursor(keys, last, idOf(last), CursorAfter)
+	case pageCursor != nil:
+		// 本页为空（如已翻到末尾），仍可从传入游标的位置向两个方向翻页
+		prev, next = *pageCursor, *pageCursor
+		prev.Direction, next.Direction = CursorBefore, CursorAfter
+	default:
+		// 没有游标且本页为空，列表中没有记录
+		return items, page, nil
+	}
+	page.PrevCursor = prev.Encode()
+	page.NextCursor = next.Encode()
+
+	// 请求的方向由多取的一条记录确定；另一个方向在没有游标时从列表的一端开始，没有更多记录，否则查询一条确认
+	var opposite bool
+	if pageCursor != nil {
+		boundary := prev
+		if reversed {
+			boundary = next
+		}
+		condition, args := keysetCondition(columns, descs, &boundary, !reversed)
+		var probe []T
+		if err := base.Where(condition, args...).Limit(1).Find(&probe).Error; err != nil {
+			return nil, nil, err
+		}
+		opposite = len(probe) > 0
+	}
+	if reversed {
+		page.HasPrev, page.HasNext = hasMore, opposite
+	} else {
+		page.HasPrev, page.HasNext = opposite, hasMore
+	}
+	return items, page, nil
+}
+
+// keysetCondition 排序键在游标位置之后的记录的查询条件，reversed 为true时为之前的记录
+func keysetCondition(columns []string, descs []bool, cursor *Cursor, reversed bool) (string, []interface{}) {
+	values := append(append([]interface{}{}, cursor.Values...), cursor.ID)
+	var clauses []string
+	var args []interface{}
+	for i := range columns {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, columns[j]+" = ?")
+			args = append(args, values[j])
+		}
+		op := ">"
+		if descs[i] != reversed {
+			op = "<"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s ?", columns[i], op))
+		args = append(args, values[i])
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+	}
+	return "(" + strings.Join(clauses, " OR ") + ")", args
+}
+
+// reverse 反转记录的顺序
+func reverse[T any](items []T) {
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+}
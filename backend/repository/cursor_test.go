@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"fmt"
+	"grandma/backend/database"
+	"grandma/backend/models"
+	"path/filepath"
+	"testing"
+)
+
+// newPagingRepo 使用临时数据库创建一个包含 n 条文档的对话
+func newPagingRepo(t *testing.T, n int) *DocumentRepository {
+	t.Helper()
+	if err := database.InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	if err := NewConversationRepository(database.DB).Create(&models.Conversation{ID: "conv_1", Title: "测试"}); err != nil {
+		t.Fatal(err)
+	}
+	repo := NewDocumentRepository(database.DB)
+	for i := 1; i <= n; i++ {
+		doc := &models.Document{ID: fmt.Sprintf("doc_%d", i), ConversationID: "conv_1", Role: "user", Content: "内容"}
+		if err := repo.Create(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo
+}
+
+func pageIDs(docs []models.Document) string {
+	var ids string
+	for _, doc := range docs {
+		ids += doc.ID[len("doc_"):]
+	}
+	return ids
+}
+
+func checkPage(t *testing.T, name string, docs []models.Document, page *models.PageInfo, ids string, hasPrev, hasNext bool) {
+	t.Helper()
+	if got := pageIDs(docs); got != ids {
+		t.Fatalf("%s: documents = %s, want %s", name, got, ids)
+	}
+	if page.HasPrev != hasPrev || page.HasNext != hasNext {
+		t.Fatalf("%s: has_prev %v has_next %v, want %v %v", name, page.HasPrev, page.HasNext, hasPrev, hasNext)
+	}
+	if page.PrevCursor == "" || page.NextCursor == "" {
+		t.Fatalf("%s: missing cursors %+v", name, page)
+	}
+}
+
+func TestKeysetPageBothDirections(t *testing.T) {
+	repo := newPagingRepo(t, 5)
+
+	// 未指定游标时返回最新的文档
+	docs, page, err := repo.ListPage("conv_1", "", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPage(t, "latest", docs, page, "45", true, false)
+
+	docs, page, err = repo.ListPage("conv_1", page.PrevCursor, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPage(t, "middle", docs, page, "23", true, true)
+	if !page.HasMore {
+		t.Fatal("middle: has_more = false")
+	}
+
+	docs, page, err = repo.ListPage("conv_1", page.PrevCursor, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPage(t, "first", docs, page, "1", false, true)
+
+	// 向后翻回到最新的文档
+	docs, page, err = repo.ListPage("conv_1", page.NextCursor, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPage(t, "forward", docs, page, "23", true, true)
+}
+
+func TestKeysetPageEmpty(t *testing.T) {
+	repo := newPagingRepo(t, 3)
+	_, page, err := repo.ListPage("conv_1", "", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 已是最新的文档，之后没有记录：两个游标仍然返回
+	docs, empty, err := repo.ListPage("conv_1", page.NextCursor, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPage(t, "after end", docs, empty, "", true, false)
+
+	// 游标位置的文档在上一次返回的页中，向前翻页从它之前开始
+	docs, _, err = repo.ListPage("conv_1", empty.PrevCursor, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pageIDs(docs); got != "12" {
+		t.Fatalf("prev of empty page = %s, want 12", got)
+	}
+
+	// 没有文档的对话
+	docs, page, err = repo.ListPage("conv_2", "", 5)
+	if err != nil || len(docs) != 0 || page.PrevCursor != "" || page.NextCursor != "" || page.HasPrev || page.HasNext {
+		t.Fatalf("empty list: %d documents, page %+v, err %v", len(docs), page, err)
+	}
+}
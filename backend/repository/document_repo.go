@@ -3,11 +3,15 @@ package repository
 import (
 	"fmt"
 	"grandma/backend/models"
+	"grandma/backend/search"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// primaryDocumentCondition 过滤掉重新生成的候选版本，只保留对话中的原始文档
+const primaryDocumentCondition = "(alternative_of IS NULL OR alternative_of = '')"
+
 type DocumentRepository struct {
 	db *gorm.DB
 }
@@ -20,7 +24,11 @@ func NewDocumentRepository(db *gorm.DB) *DocumentRepository {
 func (r *DocumentRepository) Create(document *models.Document) error {
 	document.CreatedAt = time.Now()
 	document.UpdatedAt = time.Now()
-	return r.db.Create(document).Error
+	if err := r.db.Create(document).Error; err != nil {
+		return err
+	}
+	indexDocument(r.db, document)
+	return nil
 }
 
 // GetByID 根据ID获取文档
@@ -33,10 +41,23 @@ func (r *DocumentRepository) GetByID(id string) (*models.Document, error) {
 	return &document, nil
 }
 
+// GetByIDs 根据ID列表批量获取文档，使用一次IN查询，不保证返回顺序，不存在的ID不返回
+func (r *DocumentRepository) GetByIDs(ids []string) ([]models.Document, error) {
+	var documents []models.Document
+	if len(ids) == 0 {
+		return documents, nil
+	}
+	err := r.db.Where("id IN ?", ids).Find(&documents).Error
+	if err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
 // GetByConversationID 根据对话ID获取文档列表
 func (r *DocumentRepository) GetByConversationID(conversationID string) ([]models.Document, error) {
 	var documents []models.Document
-	err := r.db.Where("conversation_id = ?", conversationID).Order("created_at ASC").Find(&documents).Error
+	err := r.db.Where("conversation_id = ?", conversationID).Where(primaryDocumentCondition).Order("created_at ASC").Find(&documents).Error
 	if err != nil {
 		fmt.Printf("[document_repo GetByConversationID] Error: %+v\n", err)
 		return nil, err
@@ -45,102 +66,266 @@ func (r *DocumentRepository) GetByConversationID(conversationID string) ([]model
 	return documents, nil
 }
 
-// GetDocumentIDsByConversationID 根据对话ID获取文档ID列表（用于翻页）
-// beforeDocumentID: 如果提供，返回比该文档更早的文档ID
-// limit: 返回的最大数量
-func (r *DocumentRepository) GetDocumentIDsByConversationID(conversationID string, beforeDocumentID string, limit int) ([]string, error) {
-	var documentIDs []string
+// documentKeys 文档按创建时间正序排列
+var documentKeys = []sortKey[models.Document]{
+	{column: "created_at", kind: keyTime, value: func(d *models.Document) interface{} { return d.CreatedAt }},
+}
+
+func documentID(d *models.Document) string { return d.ID }
+
+// DocumentCursor 以文档为边界创建翻页游标，用于兼容按文档ID翻页的旧接口
+func DocumentCursor(doc *models.Document, direction string) string {
+	return newCursor(documentKeys, doc, doc.ID, direction).Encode()
+}
+
+// ListPage 按游标获取对话的一页文档（不含候选版本），返回的文档按时间正序排列
+// 游标为空时返回最新的文档；prev_cursor 指向更早的文档，next_cursor 指向更晚的文档
+func (r *DocumentRepository) ListPage(conversationID, cursor string, limit int) ([]models.Document, *models.PageInfo, error) {
+	query := r.db.Where("conversation_id = ?", conversationID).Where(primaryDocumentCondition)
+	return documentPage(query, cursor, limit)
+}
+
+// ListIDPage 与 ListPage 相同，只查询文档ID和排序需要的字段
+func (r *DocumentRepository) ListIDPage(conversationID, cursor string, limit int) ([]models.Document, *models.PageInfo, error) {
+	query := r.db.Select("id", "created_at").Where("conversation_id = ?", conversationID).Where(primaryDocumentCondition)
+	return documentPage(query, cursor, limit)
+}
 
+func documentPage(query *gorm.DB, cursor string, limit int) ([]models.Document, *models.PageInfo, error) {
 	if limit <= 0 {
 		limit = 10
 	}
+	return keysetPage(query, documentKeys, documentID, cursor, limit, true)
+}
 
-	if beforeDocumentID == "" {
-		// 如果没有提供beforeDocumentID，返回最新的文档ID（按created_at倒序，取前limit个，然后反转顺序）
-		query := r.db.Model(&models.Document{}).
-			Where("conversation_id = ?", conversationID).
-			Order("created_at DESC").
-			Limit(limit)
-
-		err := query.Pluck("id", &documentIDs).Error
-		if err != nil {
-			return nil, err
-		}
+// GetBranch 从指定文档沿父文档链向上获取分支（按分支顺序倒序，即指定文档在最前）
+// limit: 返回的最大数量，<=0 表示不限制
+func (r *DocumentRepository) GetBranch(documentID string, limit int) ([]models.Document, error) {
+	return r.branch(documentID, "", limit)
+}
 
-		// 反转顺序，使其按时间正序排列（最早的在前）
-		for i, j := 0, len(documentIDs)-1; i < j; i, j = i+1, j-1 {
-			documentIDs[i], documentIDs[j] = documentIDs[j], documentIDs[i]
-		}
+// GetBranchSince 从指定文档沿父文档链向上获取分支，到祖先文档 ancestorID 为止（不含），顺序与 GetBranch 相同
+// ancestorID 为空时获取到分支起点
+func (r *DocumentRepository) GetBranchSince(documentID, ancestorID string) ([]models.Document, error) {
+	return r.branch(documentID, ancestorID, 0)
+}
 
-		return documentIDs, nil
+// branch 沿父文档链向上获取分支，遇到 stopID 时停止
+func (r *DocumentRepository) branch(documentID, stopID string, limit int) ([]models.Document, error) {
+	var documents []models.Document
+	if documentID == "" || documentID == stopID {
+		return documents, nil
 	}
 
-	// 如果提供了beforeDocumentID，返回比该ID更早的文档ID（按created_at正序）
-	query := r.db.Model(&models.Document{}).
-		Where("conversation_id = ?", conversationID).
-		Order("created_at ASC")
-
-	// 找到 beforeDocumentID 对应的文档的 created_at
-	var beforeDoc models.Document
-	if err := r.db.Where("id = ?", beforeDocumentID).First(&beforeDoc).Error; err == nil {
-		query = query.Where("created_at < ?", beforeDoc.CreatedAt)
+	maxDepth := limit
+	if maxDepth <= 0 {
+		maxDepth = -1
 	}
 
-	err := query.Limit(limit).Pluck("id", &documentIDs).Error
+	// 使用递归查询沿parent_id向上遍历，避免逐条查询；回收站中的文档视为不存在，分支在此截断
+	err := r.db.Raw(`
+		WITH RECURSIVE branch(id, depth) AS (
+			SELECT id, 0 FROM documents WHERE id = ? AND deleted_at IS NULL
+			UNION ALL
+			SELECT d.parent_id, b.depth + 1
+			FROM documents d JOIN branch b ON d.id = b.id
+			WHERE d.parent_id IS NOT NULL AND d.parent_id <> '' AND d.parent_id <> ? AND d.deleted_at IS NULL AND (? < 0 OR b.depth + 1 < ?)
+		)
+		SELECT documents.* FROM documents JOIN branch ON documents.id = branch.id
+		WHERE documents.deleted_at IS NULL
+		ORDER BY branch.depth ASC`, documentID, stopID, maxDepth, maxDepth).
+		Scan(&documents).Error
 	if err != nil {
 		return nil, err
 	}
-
-	return documentIDs, nil
+	return documents, nil
 }
 
-// GetLatestDocumentsByConversationID 获取对话的最新文档（按created_at倒序）
-func (r *DocumentRepository) GetLatestDocumentsByConversationID(conversationID string, limit int) ([]models.Document, error) {
+// GetChildren 获取文档的直接子文档（按created_at正序，不包括候选版本）
+func (r *DocumentRepository) GetChildren(documentID string) ([]models.Document, error) {
 	var documents []models.Document
-	query := r.db.Where("conversation_id = ?", conversationID).Order("created_at DESC")
-	if limit > 0 {
-		query = query.Limit(limit)
+	err := r.db.Where("parent_id = ?", documentID).Where(primaryDocumentCondition).Order("created_at ASC").Find(&documents).Error
+	if err != nil {
+		return nil, err
 	}
-	err := query.Find(&documents).Error
+	return documents, nil
+}
+
+// GetAlternatives 获取原始助手文档的所有候选版本（按created_at正序）
+func (r *DocumentRepository) GetAlternatives(originalID string) ([]models.Document, error) {
+	var documents []models.Document
+	err := r.db.Where("alternative_of = ?", originalID).Order("created_at ASC").Find(&documents).Error
 	if err != nil {
 		return nil, err
 	}
 	return documents, nil
 }
 
-// Update 更新文档
-func (r *DocumentRepository) Update(document *models.Document) error {
+// SetActiveAlternative 设置原始助手文档当前选用的候选版本，alternativeID为空表示恢复使用原始内容
+func (r *DocumentRepository) SetActiveAlternative(originalID, alternativeID string) error {
+	return r.db.Model(&models.Document{}).
+		Where("id = ?", originalID).
+		Update("active_alternative_id", alternativeID).
+		Error
+}
+
+// UpdateWithRevision 更新文档内容并追加修订，current 为修改前的内容，与最新的修订不同时先保存
+// 只更新内容和更新时间，对话、分支和序号等字段保持不变
+func (r *DocumentRepository) UpdateWithRevision(document *models.Document, current, revision *models.Revision) error {
 	document.UpdatedAt = time.Now()
-	return r.db.Save(document).Error
+	revision.CreatedAt = document.UpdatedAt
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(document).
+			Select("content", "updated_at").
+			Updates(document).Error
+		if err != nil {
+			return err
+		}
+		return appendRevision(tx, current, revision)
+	})
+	if err != nil {
+		return err
+	}
+	indexDocument(r.db, document)
+	return nil
 }
 
-// Delete 删除文档
+// Delete 将文档及其候选版本移入回收站，使用同一个删除时间，恢复时一起恢复
 func (r *DocumentRepository) Delete(id string) error {
-	return r.db.Delete(&models.Document{}, "id = ?", id).Error
+	result := r.db.Model(&models.Document{}).
+		Where("id = ? OR alternative_of = ?", id, id).
+		Update("deleted_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	removeEntries(r.db, search.KindDocument, "ref_id IN (SELECT id FROM documents WHERE id = ? OR alternative_of = ?)", id, id)
+	return nil
 }
 
-// DeleteByConversationID 删除对话的所有文档
-func (r *DocumentRepository) DeleteByConversationID(conversationID string) error {
-	return r.db.Where("conversation_id = ?", conversationID).Delete(&models.Document{}).Error
+// Purge 永久删除文档及其候选版本，包括已在回收站中的
+func (r *DocumentRepository) Purge(id string) error {
+	removeEntries(r.db, search.KindDocument, "ref_id IN (SELECT id FROM documents WHERE id = ? OR alternative_of = ?)", id, id)
+	purged := r.db.Unscoped().Model(&models.Document{}).Select("id").Where("id = ? OR alternative_of = ?", id, id)
+	if err := deleteRevisions(r.db, models.RevisionTargetDocument, purged); err != nil {
+		return err
+	}
+	return r.db.Unscoped().Delete(&models.Document{}, "id = ? OR alternative_of = ?", id, id).Error
+}
+
+// GetDeletedByID 获取回收站中的文档
+func (r *DocumentRepository) GetDeletedByID(id string) (*models.Document, error) {
+	var document models.Document
+	err := r.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&document).Error
+	if err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+// ListDeleted 获取回收站中单独删除的文档，最近删除的在前
+// 随对话一起删除的文档和随原始文档一起删除的候选版本不单独列出
+func (r *DocumentRepository) ListDeleted(limit int) ([]models.Document, error) {
+	var documents []models.Document
+	err := r.db.Unscoped().
+		Where("documents.deleted_at IS NOT NULL").
+		Where("documents.conversation_id NOT IN (SELECT id FROM conversations WHERE deleted_at IS NOT NULL)").
+		Where("NOT EXISTS (SELECT 1 FROM documents o WHERE o.id = documents.alternative_of AND o.deleted_at = documents.deleted_at)").
+		Order("documents.deleted_at DESC").
+		Limit(limit).
+		Find(&documents).Error
+	if err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+// Restore 从回收站恢复文档，以及与它同时删除的候选版本
+func (r *DocumentRepository) Restore(id string) error {
+	doc, err := r.GetDeletedByID(id)
+	if err != nil {
+		return err
+	}
+	err = r.db.Unscoped().Model(&models.Document{}).
+		Where("(id = ? OR alternative_of = ?) AND deleted_at = ?", id, id, doc.DeletedAt.Time).
+		Update("deleted_at", nil).Error
+	if err != nil {
+		return err
+	}
+
+	var restored []models.Document
+	if err := r.db.Where("id = ? OR alternative_of = ?", id, id).Find(&restored).Error; err != nil {
+		return err
+	}
+	for i := range restored {
+		indexDocument(r.db, &restored[i])
+	}
+	return nil
+}
+
+// PurgeDeletedBefore 永久删除在指定时间之前移入回收站的文档，返回删除的数量
+func (r *DocumentRepository) PurgeDeletedBefore(cutoff time.Time) (int64, error) {
+	expired := r.db.Unscoped().Model(&models.Document{}).Select("id").Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff)
+	if err := deleteRevisions(r.db, models.RevisionTargetDocument, expired); err != nil {
+		return 0, err
+	}
+	result := r.db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Delete(&models.Document{})
+	return result.RowsAffected, result.Error
 }
 
 // AppendContent 追加内容到文档（用于流式更新）
+// 流式生成时每次追加的内容很少，不读取整个文档，也不更新搜索索引；生成结束后调用 Reindex 更新索引
 func (r *DocumentRepository) AppendContent(id string, content string) error {
+	result := r.db.Model(&models.Document{}).
+		Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"content":    gorm.Expr("content || ?", content),
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Reindex 按文档当前的内容更新搜索索引
+func (r *DocumentRepository) Reindex(id string) error {
 	var doc models.Document
-	err := r.db.Where("id = ?", id).First(&doc).Error
-	if err != nil {
+	if err := r.db.Where("id = ?", id).First(&doc).Error; err != nil {
 		return err
 	}
-	doc.Content = doc.Content + content
-	doc.UpdatedAt = time.Now()
-	return r.db.Save(&doc).Error
+	indexDocument(r.db, &doc)
+	return nil
+}
+
+// UpdateReadingLevel 更新助手回复的阅读难度分析结果
+func (r *DocumentRepository) UpdateReadingLevel(id string, readingAge int, exceedsTargetAge bool) error {
+	return r.db.Model(&models.Document{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"reading_age":        readingAge,
+			"exceeds_target_age": exceedsTargetAge,
+		}).
+		Error
 }
 
 // UpdateContent 更新文档内容（用于流式更新的初始设置）
 func (r *DocumentRepository) UpdateContent(id string, content string) error {
-	return r.db.Model(&models.Document{}).
+	err := r.db.Model(&models.Document{}).
 		Where("id = ?", id).
 		Update("content", content).
 		Update("updated_at", time.Now()).
 		Error
+	if err != nil {
+		return err
+	}
+	if doc, err := r.GetByID(id); err == nil {
+		indexDocument(r.db, doc)
+	}
+	return nil
 }
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"grandma/backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type FolderRepository struct {
+	db *gorm.DB
+}
+
+func NewFolderRepository(db *gorm.DB) *FolderRepository {
+	return &FolderRepository{db: db}
+}
+
+// Create 创建文件夹
+func (r *FolderRepository) Create(folder *models.Folder) error {
+	folder.CreatedAt = time.Now()
+	folder.UpdatedAt = time.Now()
+	return r.db.Create(folder).Error
+}
+
+// GetByID 根据ID获取文件夹
+func (r *FolderRepository) GetByID(id string) (*models.Folder, error) {
+	var folder models.Folder
+	err := r.db.Where("id = ?", id).First(&folder).Error
+	if err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// GetByName 根据名称获取文件夹
+func (r *FolderRepository) GetByName(name string) (*models.Folder, error) {
+	var folder models.Folder
+	err := r.db.Where("name = ?", name).First(&folder).Error
+	if err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// List 获取所有文件夹及其中的对话数，按名称排序
+func (r *FolderRepository) List() ([]models.FolderItem, error) {
+	var folders []models.FolderItem
+	err := r.db.Model(&models.Folder{}).
+		Select("folders.*, COUNT(conversations.id) AS conversation_count").
+		Joins("LEFT JOIN conversations ON conversations.folder_id = folders.id AND conversations.deleted_at IS NULL").
+		Group("folders.id").
+		Order("folders.name").
+		Scan(&folders).Error
+	if err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
+
+// Rename 重命名文件夹
+func (r *FolderRepository) Rename(id, name string) error {
+	result := r.db.Model(&models.Folder{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"name": name, "updated_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Delete 删除文件夹，其中的对话（包括回收站中的）移出文件夹，对话本身不删除
+func (r *FolderRepository) Delete(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.Folder{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Unscoped().Model(&models.Conversation{}).
+			Where("folder_id = ?", id).
+			UpdateColumn("folder_id", "").Error
+	})
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"grandma/backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type JobRepository struct {
+	db *gorm.DB
+}
+
+func NewJobRepository(db *gorm.DB) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+// Create 创建任务
+func (r *JobRepository) Create(job *models.Job) error {
+	now := time.Now()
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	if job.RunAfter.IsZero() {
+		job.RunAfter = now
+	}
+	return r.db.Create(job).Error
+}
+
+// GetByID 根据ID获取任务
+func (r *JobRepository) GetByID(id string) (*models.Job, error) {
+	var job models.Job
+	err := r.db.Where("id = ?", id).First(&job).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// List 获取任务列表，新任务在前；status 为空时获取全部任务
+func (r *JobRepository) List(status string, limit int) ([]models.Job, error) {
+	var jobs []models.Job
+	query := r.db.Order("created_at DESC").Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// ClaimNext 领取最早的可执行任务并标记为执行中，没有可执行的任务时返回 gorm.ErrRecordNotFound
+func (r *JobRepository) ClaimNext() (*models.Job, error) {
+	var job models.Job
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		// 空闲时每次轮询都查不到任务，使用 Find 避免 First 在日志中记录 record not found
+		result := tx.Where("status = ? AND run_after <= ?", models.JobStatusQueued, now).
+			Order("run_after ASC, created_at ASC").
+			Limit(1).
+			Find(&job)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		// 带上状态条件，避免同一任务被重复领取
+		result = tx.Model(&models.Job{}).
+			Where("id = ? AND status = ?", job.ID, models.JobStatusQueued).
+			Updates(map[string]interface{}{
+				"status":          models.JobStatusRunning,
+				"attempts":        gorm.Expr("attempts + 1"),
+				"started_at":      now,
+				"generated_chars": 0,
+				"updated_at":      now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("id = ?", job.ID).First(&job).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Updates 更新任务的指定字段
+func (r *JobRepository) Updates(id string, fields map[string]interface{}) error {
+	fields["updated_at"] = time.Now()
+	return r.db.Model(&models.Job{}).Where("id = ?", id).Updates(fields).Error
+}
+
+// UpdateStatus 在任务处于 from 状态时更新状态和其他字段，返回是否更新成功
+func (r *JobRepository) UpdateStatus(id, from string, fields map[string]interface{}) (bool, error) {
+	fields["updated_at"] = time.Now()
+	result := r.db.Model(&models.Job{}).Where("id = ? AND status = ?", id, from).Updates(fields)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RequeueRunning 将执行中的任务重新排队，用于服务启动时恢复上次运行中断的任务
+func (r *JobRepository) RequeueRunning() (int64, error) {
+	now := time.Now()
+	result := r.db.Model(&models.Job{}).
+		Where("status = ?", models.JobStatusRunning).
+		Updates(map[string]interface{}{
+			"status":     models.JobStatusQueued,
+			"run_after":  now,
+			"error":      "interrupted",
+			"updated_at": now,
+		})
+	return result.RowsAffected, result.Error
+}
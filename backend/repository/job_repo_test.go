@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"errors"
+	"grandma/backend/database"
+	"grandma/backend/models"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func newJobTestRepo(t *testing.T) *JobRepository {
+	t.Helper()
+	if err := database.InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	return NewJobRepository(database.DB)
+}
+
+func createTestJob(t *testing.T, repo *JobRepository, id string, runAfter time.Time) {
+	t.Helper()
+	job := &models.Job{ID: id, Type: models.JobTypeChat, Status: models.JobStatusQueued, MaxAttempts: 3, RunAfter: runAfter}
+	if err := repo.Create(job); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClaimNext(t *testing.T) {
+	repo := newJobTestRepo(t)
+	now := time.Now()
+	createTestJob(t, repo, "job_later", now.Add(time.Hour))
+	createTestJob(t, repo, "job_2", now.Add(-time.Second))
+	createTestJob(t, repo, "job_1", now.Add(-time.Minute))
+
+	for _, want := range []string{"job_1", "job_2"} {
+		job, err := repo.ClaimNext()
+		if err != nil {
+			t.Fatalf("ClaimNext: %v", err)
+		}
+		if job.ID != want || job.Status != models.JobStatusRunning || job.Attempts != 1 || job.StartedAt == nil {
+			t.Fatalf("claimed %+v, want %s running", job, want)
+		}
+	}
+	// 延后执行的任务还不能领取
+	if _, err := repo.ClaimNext(); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("idle ClaimNext err = %v, want ErrRecordNotFound", err)
+	}
+}
+
+func TestUpdateStatusIsConditional(t *testing.T) {
+	repo := newJobTestRepo(t)
+	createTestJob(t, repo, "job_1", time.Now())
+	if _, err := repo.ClaimNext(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 按排队状态取消时任务已开始执行，不更新
+	updated, err := repo.UpdateStatus("job_1", models.JobStatusQueued, map[string]interface{}{"status": models.JobStatusCancelled})
+	if err != nil || updated {
+		t.Fatalf("cancel as queued: updated %v, err %v", updated, err)
+	}
+	updated, err = repo.UpdateStatus("job_1", models.JobStatusRunning, map[string]interface{}{"status": models.JobStatusCancelled, "finished_at": time.Now()})
+	if err != nil || !updated {
+		t.Fatalf("cancel as running: updated %v, err %v", updated, err)
+	}
+	// 执行线程随后完成时不能覆盖取消的状态
+	updated, err = repo.UpdateStatus("job_1", models.JobStatusRunning, map[string]interface{}{"status": models.JobStatusSucceeded})
+	if err != nil || updated {
+		t.Fatalf("finish after cancel: updated %v, err %v", updated, err)
+	}
+
+	// 重试只成功一次
+	retry := func() (bool, error) {
+		return repo.UpdateStatus("job_1", models.JobStatusCancelled, map[string]interface{}{
+			"status":      models.JobStatusQueued,
+			"run_after":   time.Now(),
+			"finished_at": nil,
+		})
+	}
+	if updated, err := retry(); err != nil || !updated {
+		t.Fatalf("retry: updated %v, err %v", updated, err)
+	}
+	if updated, err := retry(); err != nil || updated {
+		t.Fatalf("second retry: updated %v, err %v", updated, err)
+	}
+	job, err := repo.ClaimNext()
+	if err != nil || job.ID != "job_1" || job.Attempts != 2 || job.FinishedAt != nil {
+		t.Fatalf("claim after retry: %+v, err %v", job, err)
+	}
+}
+
+func TestRequeueRunningAfterRestart(t *testing.T) {
+	repo := newJobTestRepo(t)
+	createTestJob(t, repo, "job_1", time.Now())
+	createTestJob(t, repo, "job_2", time.Now())
+	if _, err := repo.ClaimNext(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 服务重启：执行中的任务重新排队，排队中的任务不变
+	requeued, err := repo.RequeueRunning()
+	if err != nil || requeued != 1 {
+		t.Fatalf("RequeueRunning = %d, err %v", requeued, err)
+	}
+	job, err := repo.GetByID("job_1")
+	if err != nil || job.Status != models.JobStatusQueued || job.Error != "interrupted" {
+		t.Fatalf("requeued job = %+v, err %v", job, err)
+	}
+
+	claimed := map[string]int{}
+	for i := 0; i < 2; i++ {
+		job, err := repo.ClaimNext()
+		if err != nil {
+			t.Fatal(err)
+		}
+		claimed[job.ID] = job.Attempts
+	}
+	if claimed["job_1"] != 2 || claimed["job_2"] != 1 {
+		t.Fatalf("attempts after restart = %v", claimed)
+	}
+}
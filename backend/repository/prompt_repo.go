@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"grandma/backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PromptRepository struct {
+	db *gorm.DB
+}
+
+func NewPromptRepository(db *gorm.DB) *PromptRepository {
+	return &PromptRepository{db: db}
+}
+
+// CreateVersion 保存模板的新版本，版本号为该模板当前最大版本号加1
+func (r *PromptRepository) CreateVersion(template *models.PromptTemplate) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var maxVersion int
+		err := tx.Model(&models.PromptTemplate{}).
+			Where("name = ?", template.Name).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&maxVersion).Error
+		if err != nil {
+			return err
+		}
+		template.ID = 0
+		template.Version = maxVersion + 1
+		template.CreatedAt = time.Now()
+		return tx.Create(template).Error
+	})
+}
+
+// GetLatest 获取模板的最新版本，不存在时返回 gorm.ErrRecordNotFound
+func (r *PromptRepository) GetLatest(name string) (*models.PromptTemplate, error) {
+	var template models.PromptTemplate
+	err := r.db.Where("name = ?", name).Order("version DESC").First(&template).Error
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// GetVersion 获取模板的指定版本
+func (r *PromptRepository) GetVersion(name string, version int) (*models.PromptTemplate, error) {
+	var template models.PromptTemplate
+	err := r.db.Where("name = ? AND version = ?", name, version).First(&template).Error
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// ListVersions 获取模板的所有版本，新版本在前
+func (r *PromptRepository) ListVersions(name string) ([]models.PromptTemplate, error) {
+	var templates []models.PromptTemplate
+	err := r.db.Where("name = ?", name).Order("version DESC").Find(&templates).Error
+	if err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// ListLatest 获取每个模板的最新版本
+func (r *PromptRepository) ListLatest() ([]models.PromptTemplate, error) {
+	var templates []models.PromptTemplate
+	err := r.db.Where("version = (SELECT MAX(p.version) FROM prompt_templates p WHERE p.name = prompt_templates.name)").
+		Order("name ASC").
+		Find(&templates).Error
+	if err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
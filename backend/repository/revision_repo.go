@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"grandma/backend/models"
+
+	"gorm.io/gorm"
+)
+
+type RevisionRepository struct {
+	db *gorm.DB
+}
+
+func NewRevisionRepository(db *gorm.DB) *RevisionRepository {
+	return &RevisionRepository{db: db}
+}
+
+// List 获取文档或故事的修订，最新的在前；不加载内容
+func (r *RevisionRepository) List(targetType, targetID string) ([]models.Revision, error) {
+	var revisions []models.Revision
+	err := r.db.Omit("content").
+		Where("target_type = ? AND target_id = ?", targetType, targetID).
+		Order("number DESC").
+		Find(&revisions).Error
+	if err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// Get 获取指定修订号的修订
+func (r *RevisionRepository) Get(targetType, targetID string, number int) (*models.Revision, error) {
+	var revision models.Revision
+	err := r.db.Where("target_type = ? AND target_id = ? AND number = ?", targetType, targetID, number).
+		First(&revision).Error
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// Latest 获取最新的修订
+func (r *RevisionRepository) Latest(targetType, targetID string) (*models.Revision, error) {
+	var revision models.Revision
+	err := r.db.Where("target_type = ? AND target_id = ?", targetType, targetID).
+		Order("number DESC").
+		First(&revision).Error
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// appendRevision 在事务中追加修订并分配修订号
+// current 为修改前的内容：还没有修订，或者内容在修订之外被更改过（例如续写）时，先把它保存为一个修订
+func appendRevision(tx *gorm.DB, current, revision *models.Revision) error {
+	var latest models.Revision
+	err := tx.Select("number", "title", "content_hash").
+		Where("target_type = ? AND target_id = ?", revision.TargetType, revision.TargetID).
+		Order("number DESC").
+		Limit(1).
+		Find(&latest).Error
+	if err != nil {
+		return err
+	}
+	if latest.Number == 0 || latest.ContentHash != current.ContentHash || latest.Title != current.Title {
+		current.Number = latest.Number + 1
+		if err := tx.Create(current).Error; err != nil {
+			return err
+		}
+		latest.Number = current.Number
+	}
+	revision.Number = latest.Number + 1
+	return tx.Create(revision).Error
+}
+
+// deleteRevisions 删除文档或故事的修订，在永久删除时调用；targetIDs 可以是ID或子查询
+func deleteRevisions(db *gorm.DB, targetType string, targetIDs interface{}) error {
+	return db.Where("target_type = ? AND target_id IN (?)", targetType, targetIDs).Delete(&models.Revision{}).Error
+}
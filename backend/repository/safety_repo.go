@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"grandma/backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SafetyRepository struct {
+	db *gorm.DB
+}
+
+func NewSafetyRepository(db *gorm.DB) *SafetyRepository {
+	return &SafetyRepository{db: db}
+}
+
+// CreateInterventions 批量保存内容安全处理记录
+func (r *SafetyRepository) CreateInterventions(interventions []models.SafetyIntervention) error {
+	if len(interventions) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for i := range interventions {
+		interventions[i].CreatedAt = now
+	}
+	return r.db.Create(&interventions).Error
+}
+
+// GetByDocumentID 获取文档的处理记录
+func (r *SafetyRepository) GetByDocumentID(documentID string) ([]models.SafetyIntervention, error) {
+	var interventions []models.SafetyIntervention
+	err := r.db.Where("document_id = ?", documentID).Order("created_at ASC").Find(&interventions).Error
+	if err != nil {
+		return nil, err
+	}
+	return interventions, nil
+}
+
+// List 获取处理记录，新记录在前；conversationID 为空时获取所有对话的记录
+func (r *SafetyRepository) List(conversationID string, limit int) ([]models.SafetyIntervention, error) {
+	var interventions []models.SafetyIntervention
+	query := r.db.Order("created_at DESC").Limit(limit)
+	if conversationID != "" {
+		query = query.Where("conversation_id = ?", conversationID)
+	}
+	err := query.Find(&interventions).Error
+	if err != nil {
+		return nil, err
+	}
+	return interventions, nil
+}
@@ -0,0 +1,312 @@
+package repository
+
+import (
+	"fmt"
+	"grandma/backend/models"
+	"grandma/backend/search"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ftsEnabled 数据库是否支持 FTS5 全文索引，由 InitIndex 检测
+// 不支持时（编译 go-sqlite3 时未加 sqlite_fts5 标签）只维护 search_entries 表，搜索退化为 LIKE 查询
+var ftsEnabled bool
+
+// SearchHit 一条搜索结果，Score 为 bm25 相关度（越小越相关），LIKE 查询时为0
+type SearchHit struct {
+	models.SearchEntry `gorm:"embedded"`
+	Score              float64
+}
+
+// SearchQuery 搜索条件
+type SearchQuery struct {
+	Query          string
+	Kinds          []string // 为空时搜索所有类型
+	ConversationID string   // 不为空时只搜索该对话
+	Offset         int
+	Limit          int
+}
+
+type SearchRepository struct {
+	db *gorm.DB
+}
+
+func NewSearchRepository(db *gorm.DB) *SearchRepository {
+	return &SearchRepository{db: db}
+}
+
+// FTSEnabled 是否使用 FTS5 全文索引
+func (r *SearchRepository) FTSEnabled() bool {
+	return ftsEnabled
+}
+
+// InitIndex 检测 FTS5 并创建全文索引表；索引为空时从现有数据建立索引
+// 全文索引与 search_entries 的条目数或最后更新时间不一致时（如曾用不支持 FTS5 的版本运行过）重建全文索引
+func (r *SearchRepository) InitIndex() error {
+	var fts5 bool
+	if err := r.db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5).Error; err != nil {
+		return err
+	}
+	ftsEnabled = fts5
+	if ftsEnabled {
+		err := r.db.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS search_fts USING fts5(title, body, version UNINDEXED, tokenize = 'unicode61')").Error
+		if err != nil {
+			return err
+		}
+	} else {
+		// 不带 sqlite_fts5 标签编译时搜索仍可用，但每次都要扫描全部内容，数据多时很慢
+		fmt.Println("[search_repo InitIndex] WARNING: SQLite was built without FTS5, full-text search falls back to slow LIKE queries. " +
+			"Build with `make build` or `go build -tags sqlite_fts5` to enable the index")
+	}
+
+	var entries int64
+	if err := r.db.Model(&models.SearchEntry{}).Count(&entries).Error; err != nil {
+		return err
+	}
+	if entries == 0 {
+		return r.Rebuild()
+	}
+	if !ftsEnabled {
+		return nil
+	}
+
+	var indexed struct {
+		Count   int64
+		Version int64
+	}
+	if err := r.db.Raw("SELECT COUNT(*) AS count, COALESCE(MAX(version), 0) AS version FROM search_fts").Scan(&indexed).Error; err != nil {
+		return err
+	}
+	var latest models.SearchEntry
+	if err := r.db.Order("updated_at DESC").First(&latest).Error; err != nil {
+		return err
+	}
+	if indexed.Count != entries || indexed.Version != latest.UpdatedAt.UnixNano() {
+		return r.rebuildFTS()
+	}
+	return nil
+}
+
+// Rebuild 从对话、文档和故事重建搜索索引
+func (r *SearchRepository) Rebuild() error {
+	if ftsEnabled {
+		if err := r.db.Exec("DELETE FROM search_fts").Error; err != nil {
+			return err
+		}
+	}
+	if err := r.db.Where("1 = 1").Delete(&models.SearchEntry{}).Error; err != nil {
+		return err
+	}
+
+	count := 0
+	var conversations []models.Conversation
+	err := r.db.FindInBatches(&conversations, 200, func(tx *gorm.DB, batch int) error {
+		count += len(conversations)
+		for _, conversation := range conversations {
+			indexConversation(r.db, &conversation)
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	var documents []models.Document
+	err = r.db.FindInBatches(&documents, 200, func(tx *gorm.DB, batch int) error {
+		count += len(documents)
+		for _, doc := range documents {
+			indexDocument(r.db, &doc)
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	var stories []models.Story
+	err = r.db.FindInBatches(&stories, 200, func(tx *gorm.DB, batch int) error {
+		count += len(stories)
+		for _, story := range stories {
+			indexStory(r.db, &story)
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[search_repo Rebuild] Indexed %d conversations, documents and stories\n", count)
+	return nil
+}
+
+// rebuildFTS 根据 search_entries 重建全文索引
+func (r *SearchRepository) rebuildFTS() error {
+	if !ftsEnabled {
+		return nil
+	}
+	if err := r.db.Exec("DELETE FROM search_fts").Error; err != nil {
+		return err
+	}
+	var entries []models.SearchEntry
+	count := 0
+	err := r.db.FindInBatches(&entries, 200, func(tx *gorm.DB, batch int) error {
+		count += len(entries)
+		for _, entry := range entries {
+			if err := insertFTS(r.db, &entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[search_repo rebuildFTS] Indexed %d entries\n", count)
+	return nil
+}
+
+// Search 搜索索引，返回当前页的结果和结果总数
+// 使用全文索引时按相关度排序，标题的权重高于正文；LIKE 查询时按更新时间倒序
+func (r *SearchRepository) Search(q SearchQuery) ([]SearchHit, int64, error) {
+	var query *gorm.DB
+	order := "search_entries.updated_at DESC"
+	if ftsEnabled {
+		query = r.db.Table("search_fts").
+			Select("search_entries.*, bm25(search_fts, 5.0, 1.0) AS score").
+			Joins("JOIN search_entries ON search_entries.id = search_fts.rowid").
+			Where("search_fts MATCH ?", search.MatchExpression(q.Query))
+		order = "score"
+	} else {
+		query = r.db.Table("search_entries").Select("search_entries.*, 0 AS score")
+		for _, term := range search.Terms(q.Query) {
+			like := "%" + escapeLike(term) + "%"
+			query = query.Where(`(search_entries.title LIKE ? ESCAPE '\' OR search_entries.body LIKE ? ESCAPE '\')`, like, like)
+		}
+	}
+	if len(q.Kinds) > 0 {
+		query = query.Where("search_entries.kind IN ?", q.Kinds)
+	}
+	if q.ConversationID != "" {
+		query = query.Where("search_entries.conversation_id = ?", q.ConversationID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var hits []SearchHit
+	err := query.Order(order).Order("search_entries.id").Offset(q.Offset).Limit(q.Limit).Scan(&hits).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return hits, total, nil
+}
+
+// GetConversationTitles 从索引中批量获取对话标题
+func (r *SearchRepository) GetConversationTitles(conversationIDs []string) (map[string]string, error) {
+	titles := make(map[string]string)
+	if len(conversationIDs) == 0 {
+		return titles, nil
+	}
+	var entries []models.SearchEntry
+	err := r.db.Select("ref_id", "title").
+		Where("kind = ? AND ref_id IN ?", search.KindConversation, conversationIDs).
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		titles[entry.RefID] = entry.Title
+	}
+	return titles, nil
+}
+
+// escapeLike 转义 LIKE 查询中的通配符
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// indexConversation 更新对话标题的索引
+func indexConversation(db *gorm.DB, conversation *models.Conversation) {
+	indexEntry(db, models.SearchEntry{
+		Kind:           search.KindConversation,
+		RefID:          conversation.ID,
+		ConversationID: conversation.ID,
+		Title:          conversation.Title,
+	})
+}
+
+// indexDocument 更新文档的索引
+func indexDocument(db *gorm.DB, doc *models.Document) {
+	indexEntry(db, models.SearchEntry{
+		Kind:           search.KindDocument,
+		RefID:          doc.ID,
+		ConversationID: doc.ConversationID,
+		Body:           doc.Content,
+	})
+}
+
+// indexStory 更新故事的索引
+func indexStory(db *gorm.DB, story *models.Story) {
+	indexEntry(db, models.SearchEntry{
+		Kind:  search.KindStory,
+		RefID: story.ID,
+		Title: story.Title,
+		Body:  story.Content,
+	})
+}
+
+// indexEntry 写入或更新索引条目，并同步全文索引
+// 索引是辅助数据，失败时只记录日志，不影响对原数据的修改
+func indexEntry(db *gorm.DB, entry models.SearchEntry) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var existing models.SearchEntry
+		err := tx.Where("kind = ? AND ref_id = ?", entry.Kind, entry.RefID).Limit(1).Find(&existing).Error
+		if err != nil {
+			return err
+		}
+		entry.ID = existing.ID
+		entry.UpdatedAt = time.Now()
+		if err := tx.Save(&entry).Error; err != nil {
+			return err
+		}
+		if !ftsEnabled {
+			return nil
+		}
+		if err := tx.Exec("DELETE FROM search_fts WHERE rowid = ?", entry.ID).Error; err != nil {
+			return err
+		}
+		return insertFTS(tx, &entry)
+	})
+	if err != nil {
+		fmt.Printf("[search_repo indexEntry] Error indexing %s %s: %+v\n", entry.Kind, entry.RefID, err)
+	}
+}
+
+// insertFTS 将索引条目分词后写入全文索引，version 记录条目的更新时间，用于启动时检查全文索引是否过期
+func insertFTS(db *gorm.DB, entry *models.SearchEntry) error {
+	return db.Exec("INSERT INTO search_fts(rowid, title, body, version) VALUES (?, ?, ?, ?)",
+		entry.ID, search.Tokenize(entry.Title), search.Tokenize(entry.Body), entry.UpdatedAt.UnixNano()).Error
+}
+
+// removeEntries 删除符合条件的索引条目，并同步全文索引
+func removeEntries(db *gorm.DB, kind, condition string, args ...interface{}) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		err := tx.Model(&models.SearchEntry{}).Where("kind = ?", kind).Where(condition, args...).Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		if ftsEnabled {
+			if err := tx.Exec("DELETE FROM search_fts WHERE rowid IN ?", ids).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&models.SearchEntry{}, ids).Error
+	})
+	if err != nil {
+		fmt.Printf("[search_repo removeEntries] Error removing %s entries: %+v\n", kind, err)
+	}
+}
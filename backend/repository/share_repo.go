@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"grandma/backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ShareRepository struct {
+	db *gorm.DB
+}
+
+func NewShareRepository(db *gorm.DB) *ShareRepository {
+	return &ShareRepository{db: db}
+}
+
+// Create 创建分享链接
+func (r *ShareRepository) Create(link *models.ShareLink) error {
+	link.CreatedAt = time.Now()
+	link.UpdatedAt = time.Now()
+	return r.db.Create(link).Error
+}
+
+// GetByID 根据ID获取分享链接
+func (r *ShareRepository) GetByID(id string) (*models.ShareLink, error) {
+	var link models.ShareLink
+	err := r.db.Where("id = ?", id).First(&link).Error
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// GetByToken 根据令牌获取分享链接
+func (r *ShareRepository) GetByToken(token string) (*models.ShareLink, error) {
+	var link models.ShareLink
+	err := r.db.Where("token = ?", token).First(&link).Error
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// List 获取分享链接，最近创建的在前；targetType 和 targetID 为空时不按其过滤
+func (r *ShareRepository) List(targetType, targetID string) ([]models.ShareLink, error) {
+	query := r.db.Model(&models.ShareLink{})
+	if targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if targetID != "" {
+		query = query.Where("target_id = ?", targetID)
+	}
+	var links []models.ShareLink
+	if err := query.Order("created_at DESC").Find(&links).Error; err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// Revoke 撤销分享链接，已撤销的保持原来的撤销时间
+func (r *ShareRepository) Revoke(id string) (*models.ShareLink, error) {
+	now := time.Now()
+	err := r.db.Model(&models.ShareLink{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{"revoked_at": now, "updated_at": now}).Error
+	if err != nil {
+		return nil, err
+	}
+	return r.GetByID(id)
+}
+
+// RecordAccess 记录一次访问：访问次数加一并更新最后访问时间
+func (r *ShareRepository) RecordAccess(id string) error {
+	return r.db.Model(&models.ShareLink{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"access_count":     gorm.Expr("access_count + 1"),
+		"last_accessed_at": time.Now(),
+	}).Error
+}
+
+// deleteShareLinks 删除指向指定内容的分享链接，在永久删除对话或故事时调用；targetIDs 可以是ID或子查询
+func deleteShareLinks(db *gorm.DB, targetType string, targetIDs interface{}) error {
+	return db.Where("target_type = ? AND target_id IN (?)", targetType, targetIDs).Delete(&models.ShareLink{}).Error
+}
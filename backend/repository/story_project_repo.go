@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"grandma/backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type StoryProjectRepository struct {
+	db *gorm.DB
+}
+
+func NewStoryProjectRepository(db *gorm.DB) *StoryProjectRepository {
+	return &StoryProjectRepository{db: db}
+}
+
+// Create 创建故事项目
+func (r *StoryProjectRepository) Create(project *models.StoryProject) error {
+	project.CreatedAt = time.Now()
+	project.UpdatedAt = time.Now()
+	return r.db.Create(project).Error
+}
+
+// GetByID 根据ID获取故事项目，包括按顺序排列的章节
+func (r *StoryProjectRepository) GetByID(id string) (*models.StoryProject, error) {
+	var project models.StoryProject
+	err := r.db.Preload("Chapters", func(db *gorm.DB) *gorm.DB {
+		return db.Order("number ASC")
+	}).Where("id = ?", id).First(&project).Error
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// List 获取故事项目列表（不包括章节）
+func (r *StoryProjectRepository) List() ([]models.StoryProject, error) {
+	var projects []models.StoryProject
+	err := r.db.Order("updated_at DESC").Find(&projects).Error
+	if err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// Updates 更新故事项目的指定字段
+func (r *StoryProjectRepository) Updates(id string, fields map[string]interface{}) error {
+	fields["updated_at"] = time.Now()
+	return r.db.Model(&models.StoryProject{}).Where("id = ?", id).Updates(fields).Error
+}
+
+// SaveChapter 保存章节，同一章节重新生成时覆盖
+func (r *StoryProjectRepository) SaveChapter(chapter *models.StoryChapter) error {
+	now := time.Now()
+	if chapter.CreatedAt.IsZero() {
+		chapter.CreatedAt = now
+	}
+	chapter.UpdatedAt = now
+	return r.db.Where("project_id = ? AND number = ?", chapter.ProjectID, chapter.Number).
+		Assign(map[string]interface{}{
+			"document_id": chapter.DocumentID,
+			"summary":     chapter.Summary,
+			"updated_at":  now,
+		}).
+		FirstOrCreate(chapter).Error
+}
+
+// Delete 删除故事项目及其章节
+func (r *StoryProjectRepository) Delete(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("project_id = ?", id).Delete(&models.StoryChapter{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.StoryProject{}, "id = ?", id).Error
+	})
+}
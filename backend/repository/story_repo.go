@@ -3,6 +3,7 @@ package repository
 import (
 	"fmt"
 	"grandma/backend/models"
+	"grandma/backend/search"
 	"time"
 
 	"gorm.io/gorm"
@@ -20,7 +21,21 @@ func NewStoryRepository(db *gorm.DB) *StoryRepository {
 func (r *StoryRepository) Create(story *models.Story) error {
 	story.CreatedAt = time.Now()
 	story.UpdatedAt = time.Now()
-	return r.db.Create(story).Error
+	if err := r.db.Create(story).Error; err != nil {
+		return err
+	}
+	indexStory(r.db, story)
+	return nil
+}
+
+// GetByID 根据ID获取故事
+func (r *StoryRepository) GetByID(id string) (*models.Story, error) {
+	var story models.Story
+	err := r.db.Where("id = ?", id).First(&story).Error
+	if err != nil {
+		return nil, err
+	}
+	return &story, nil
 }
 
 // GetByGuid 根据Guid获取故事
@@ -46,9 +61,95 @@ func (r *StoryRepository) GetAll() ([]models.Story, error) {
 	return stories, nil
 }
 
-// Delete 删除文档
+// UpdateWithRevision 更新故事的标题和内容并追加修订，current 为修改前的内容，与最新的修订不同时先保存
+func (r *StoryRepository) UpdateWithRevision(story *models.Story, current, revision *models.Revision) error {
+	story.UpdatedAt = time.Now()
+	revision.CreatedAt = story.UpdatedAt
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(story).
+			Select("title", "content", "content_hash", "updated_at").
+			Updates(story).Error
+		if err != nil {
+			return err
+		}
+		return appendRevision(tx, current, revision)
+	})
+	if err != nil {
+		return err
+	}
+	indexStory(r.db, story)
+	return nil
+}
+
+// Delete 将故事移入回收站
 func (r *StoryRepository) Delete(id string) error {
-	return r.db.Delete(&models.Story{}, "id = ?", id).Error
+	result := r.db.Delete(&models.Story{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	removeEntries(r.db, search.KindStory, "ref_id = ?", id)
+	return nil
+}
+
+// GetDeletedByID 获取回收站中的故事
+func (r *StoryRepository) GetDeletedByID(id string) (*models.Story, error) {
+	var story models.Story
+	err := r.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&story).Error
+	if err != nil {
+		return nil, err
+	}
+	return &story, nil
+}
+
+// ListDeleted 获取回收站中的故事，最近删除的在前
+func (r *StoryRepository) ListDeleted(limit int) ([]models.Story, error) {
+	var stories []models.Story
+	err := r.db.Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at DESC").Limit(limit).Find(&stories).Error
+	if err != nil {
+		return nil, err
+	}
+	return stories, nil
+}
+
+// Restore 从回收站恢复故事
+func (r *StoryRepository) Restore(id string) error {
+	story, err := r.GetDeletedByID(id)
+	if err != nil {
+		return err
+	}
+	if err := r.db.Unscoped().Model(&models.Story{}).Where("id = ?", id).Update("deleted_at", nil).Error; err != nil {
+		return err
+	}
+	indexStory(r.db, story)
+	return nil
+}
+
+// Purge 永久删除故事，包括已在回收站中的
+func (r *StoryRepository) Purge(id string) error {
+	removeEntries(r.db, search.KindStory, "ref_id = ?", id)
+	if err := deleteShareLinks(r.db, models.ShareTargetStory, id); err != nil {
+		return err
+	}
+	if err := deleteRevisions(r.db, models.RevisionTargetStory, id); err != nil {
+		return err
+	}
+	return r.db.Unscoped().Delete(&models.Story{}, "id = ?", id).Error
+}
+
+// PurgeDeletedBefore 永久删除在指定时间之前移入回收站的故事，返回删除的数量
+func (r *StoryRepository) PurgeDeletedBefore(cutoff time.Time) (int64, error) {
+	expired := r.db.Unscoped().Model(&models.Story{}).Select("id").Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff)
+	if err := deleteShareLinks(r.db, models.ShareTargetStory, expired); err != nil {
+		return 0, err
+	}
+	if err := deleteRevisions(r.db, models.RevisionTargetStory, expired); err != nil {
+		return 0, err
+	}
+	result := r.db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Delete(&models.Story{})
+	return result.RowsAffected, result.Error
 }
 
 // GetByContentHash 根据内容特征值查找故事
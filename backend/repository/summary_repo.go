@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"grandma/backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SummaryRepository struct {
+	db *gorm.DB
+}
+
+func NewSummaryRepository(db *gorm.DB) *SummaryRepository {
+	return &SummaryRepository{db: db}
+}
+
+// Save 保存摘要（同一文档的摘要会被覆盖）
+func (r *SummaryRepository) Save(summary *models.ConversationSummary) error {
+	now := time.Now()
+	if summary.CreatedAt.IsZero() {
+		summary.CreatedAt = now
+	}
+	summary.UpdatedAt = now
+	return r.db.Save(summary).Error
+}
+
+// GetLatestOnBranch 获取指定文档及其祖先文档中最靠后的摘要，即分支上覆盖范围最大的摘要；没有摘要时返回 nil
+func (r *SummaryRepository) GetLatestOnBranch(documentID string) (*models.ConversationSummary, error) {
+	var summaries []models.ConversationSummary
+	err := r.db.Raw(`
+		WITH RECURSIVE branch(id, depth) AS (
+			SELECT id, 0 FROM documents WHERE id = ? AND deleted_at IS NULL
+			UNION ALL
+			SELECT d.parent_id, b.depth + 1
+			FROM documents d JOIN branch b ON d.id = b.id
+			WHERE d.parent_id IS NOT NULL AND d.parent_id <> '' AND d.deleted_at IS NULL
+		)
+		SELECT conversation_summaries.* FROM conversation_summaries
+		JOIN branch ON conversation_summaries.document_id = branch.id
+		ORDER BY branch.depth ASC
+		LIMIT 1`, documentID).
+		Scan(&summaries).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(summaries) == 0 {
+		return nil, nil
+	}
+	return &summaries[0], nil
+}
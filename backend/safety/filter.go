@@ -0,0 +1,307 @@
+package safety
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minHoldbackRunes 流式检查时至少保留的未输出字符数，保证跨越多个分片的关键词也能被命中
+const minHoldbackRunes = 16
+
+// actionSeverity 处理方式的严重程度
+var actionSeverity = map[string]int{
+	ActionMask:    1,
+	ActionRewrite: 2,
+	ActionBlock:   3,
+}
+
+// Match 一次规则命中
+type Match struct {
+	RuleID   string `json:"rule_id"`
+	Category string `json:"category"`
+	Action   string `json:"action"`
+	Text     string `json:"text"`
+	Start    int    `json:"-"` // 在被检查文本中的字节位置
+	End      int    `json:"-"`
+}
+
+type compiledRule struct {
+	Rule
+	re *regexp.Regexp
+}
+
+// Filter 本地的内容安全过滤器，按年龄段使用不同的规则处理用户输入和模型输出
+type Filter struct {
+	enabled     bool
+	defaultBand string
+	rules       []compiledRule
+	holdback    int
+}
+
+// NewFilter 创建过滤器，rulesFile 不为空时从该JSON文件加载规则代替内置规则
+func NewFilter(enabled bool, defaultBand, rulesFile string) (*Filter, error) {
+	if !ValidBand(defaultBand) {
+		return nil, fmt.Errorf("invalid safety age band: %s", defaultBand)
+	}
+
+	rules := defaultRules
+	if rulesFile != "" {
+		data, err := os.ReadFile(rulesFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &rules); err != nil {
+			return nil, fmt.Errorf("invalid safety rules file: %w", err)
+		}
+	}
+
+	filter := &Filter{
+		enabled:     enabled,
+		defaultBand: defaultBand,
+		holdback:    minHoldbackRunes,
+	}
+	for _, rule := range rules {
+		var parts []string
+		for _, keyword := range rule.Keywords {
+			if keyword == "" {
+				continue
+			}
+			part := regexp.QuoteMeta(keyword)
+			// 英文关键词按整词匹配，避免 "skill" 命中 "kill"
+			if isWord(keyword) {
+				part = `\b` + part + `\b`
+			}
+			parts = append(parts, part)
+			if n := utf8.RuneCountInString(keyword) + minHoldbackRunes/2; n > filter.holdback {
+				filter.holdback = n
+			}
+		}
+		for _, pattern := range rule.Patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("invalid pattern in safety rule %s: %w", rule.ID, err)
+			}
+			parts = append(parts, "(?:"+pattern+")")
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		for band, action := range rule.Actions {
+			if !ValidBand(band) || actionSeverity[action] == 0 {
+				return nil, fmt.Errorf("invalid action %s for band %s in safety rule %s", action, band, rule.ID)
+			}
+		}
+		filter.rules = append(filter.rules, compiledRule{
+			Rule: rule,
+			re:   regexp.MustCompile("(?i)" + strings.Join(parts, "|")),
+		})
+	}
+	return filter, nil
+}
+
+// Enabled 是否启用过滤
+func (f *Filter) Enabled() bool {
+	return f != nil && f.enabled
+}
+
+// Band 获取实际使用的年龄段，未设置或无效时使用默认年龄段
+func (f *Filter) Band(band string) string {
+	if ValidBand(band) {
+		return band
+	}
+	return f.defaultBand
+}
+
+// Rules 获取当前使用的规则
+func (f *Filter) Rules() []Rule {
+	rules := make([]Rule, len(f.rules))
+	for i, rule := range f.rules {
+		rules[i] = rule.Rule
+	}
+	return rules
+}
+
+// Check 按年龄段检查文本，返回按位置排序的命中列表
+func (f *Filter) Check(text, band string) []Match {
+	if !f.Enabled() || text == "" {
+		return nil
+	}
+	var matches []Match
+	for _, rule := range f.rules {
+		action, ok := rule.Actions[band]
+		if !ok {
+			continue
+		}
+		for _, loc := range rule.re.FindAllStringIndex(text, -1) {
+			matches = append(matches, Match{
+				RuleID:   rule.ID,
+				Category: rule.Category,
+				Action:   action,
+				Text:     text[loc[0]:loc[1]],
+				Start:    loc[0],
+				End:      loc[1],
+			})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Start < matches[j].Start
+	})
+	return matches
+}
+
+// MostSevere 获取命中列表中最严重的处理方式，没有命中时返回空字符串
+func MostSevere(matches []Match) string {
+	action := ""
+	for _, match := range matches {
+		if actionSeverity[match.Action] > actionSeverity[action] {
+			action = match.Action
+		}
+	}
+	return action
+}
+
+// Mask 用星号遮盖文本中的命中内容，每个字符替换为一个星号
+func Mask(text string, matches []Match) string {
+	if len(matches) == 0 {
+		return text
+	}
+	var b strings.Builder
+	last := 0
+	for _, match := range matches {
+		if match.End <= last {
+			continue
+		}
+		start := match.Start
+		if start < last {
+			start = last
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(strings.Repeat("*", utf8.RuneCountInString(text[start:match.End])))
+		last = match.End
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// isWord 是否为只包含英文字母、数字和连字符的关键词
+func isWord(keyword string) bool {
+	for _, r := range keyword {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == ' ') {
+			return false
+		}
+	}
+	return true
+}
+
+// isWordByte 是否为正则表达式 \b 使用的单词字符
+func isWordByte(b byte) bool {
+	return b == '_' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+// Guard 流式输出的检查器：保留末尾一小段尚未输出的内容，使跨越分片的关键词也能被命中
+// 需要遮盖的内容在输出前遮盖；命中需要重写或拦截的规则时停止输出
+type Guard struct {
+	filter  *Filter
+	band    string
+	pending string
+	matches []Match
+	halted  *Match
+}
+
+// NewGuard 创建流式检查器，过滤未启用时返回nil
+func (f *Filter) NewGuard(band string) *Guard {
+	if !f.Enabled() {
+		return nil
+	}
+	return &Guard{filter: f, band: band}
+}
+
+// Write 接收新的内容，返回现在可以安全输出的部分
+func (g *Guard) Write(chunk string) string {
+	if g.halted != nil {
+		return ""
+	}
+	g.pending += chunk
+	return g.process(false)
+}
+
+// Flush 检查并输出剩余的内容
+func (g *Guard) Flush() string {
+	if g.halted != nil {
+		return ""
+	}
+	return g.process(true)
+}
+
+// process 检查未输出的内容，返回可以输出的部分；final 为true时内容已经结束，全部输出
+func (g *Guard) process(final bool) string {
+	matches := g.filter.Check(g.pending, g.band)
+
+	// 到达末尾的命中可能随后续分片改变：英文的整词匹配在末尾总是成立（"kill" 后面可能接着 "er"），
+	// 模式也可能匹配更长的内容。这样的命中以及与它重叠的命中等到下一个分片或结束时再处理
+	limit := len(g.pending)
+	if !final {
+		for changed := true; changed; {
+			changed = false
+			for _, match := range matches {
+				if match.Start < limit && (match.End > limit || match.End == len(g.pending)) {
+					limit = match.Start
+					changed = true
+				}
+			}
+		}
+	}
+
+	var masks []Match
+	for i := range matches {
+		if matches[i].End > limit {
+			continue
+		}
+		if matches[i].Action != ActionMask {
+			// 输出命中位置之前的内容，之后的内容全部丢弃
+			g.halted = &matches[i]
+			g.matches = append(g.matches, matches[i])
+			out := Mask(g.pending[:matches[i].Start], masks)
+			g.pending = ""
+			return out
+		}
+		masks = append(masks, matches[i])
+	}
+	g.matches = append(g.matches, masks...)
+	checked := Mask(g.pending[:limit], masks)
+	rest := g.pending[limit:]
+	if final {
+		g.pending = ""
+		return checked + rest
+	}
+
+	// 保留末尾的内容等待后续分片，等待处理的命中一起保留
+	cut := len(checked)
+	for n := utf8.RuneCountInString(rest); n < g.filter.holdback && cut > 0; n++ {
+		_, size := utf8.DecodeLastRuneInString(checked[:cut])
+		cut -= size
+	}
+	// 不在英文单词中间断开，否则保留部分的开头会被当作词首（"overkill" 断开为 "over" 和 "kill"）；
+	// 单词长于 holdback 时保留部分不可能是完整的关键词，最多后退 holdback 个字节
+	full := checked + rest
+	for n := 0; n < g.filter.holdback && cut > 0 && isWordByte(full[cut-1]) && isWordByte(full[cut]); n++ {
+		cut--
+	}
+	g.pending = full[cut:]
+	return full[:cut]
+}
+
+// Halted 返回导致停止输出的命中，未停止时返回nil
+func (g *Guard) Halted() *Match {
+	return g.halted
+}
+
+// Matches 返回全部命中
+func (g *Guard) Matches() []Match {
+	return g.matches
+}
@@ -0,0 +1,147 @@
+package safety
+
+import (
+	"strings"
+	"testing"
+)
+
+func newTestFilter(t *testing.T) *Filter {
+	t.Helper()
+	filter, err := NewFilter(true, BandChild, "")
+	if err != nil {
+		t.Fatalf("NewFilter: %v", err)
+	}
+	return filter
+}
+
+// stream 将文本按给定的分片写入检查器，返回输出的全部内容
+func stream(guard *Guard, chunks ...string) string {
+	var out strings.Builder
+	for _, chunk := range chunks {
+		out.WriteString(guard.Write(chunk))
+	}
+	out.WriteString(guard.Flush())
+	return out.String()
+}
+
+func TestCheckWholeWords(t *testing.T) {
+	filter := newTestFilter(t)
+	cases := []struct {
+		text  string
+		rules []string
+	}{
+		{"He has a great skill.", nil},
+		{"The killer whale swims.", nil},
+		{"A good method.", nil},
+		{"Do not kill the bird.", []string{"violence"}},
+		{"奶奶讲了妈妈的故事", nil},
+		{"小明说：妈妈的手很暖和", nil},
+		{"他妈的，真讨厌", []string{"profanity"}},
+		{"他们在讨论毒品和杀人。", []string{"drugs", "violence"}},
+	}
+	for _, c := range cases {
+		matches := filter.Check(c.text, BandChild)
+		var rules []string
+		for _, match := range matches {
+			rules = append(rules, match.RuleID)
+		}
+		if strings.Join(rules, ",") != strings.Join(c.rules, ",") {
+			t.Errorf("Check(%q) = %v, want %v", c.text, rules, c.rules)
+		}
+	}
+}
+
+func TestMask(t *testing.T) {
+	filter := newTestFilter(t)
+	text := "他想杀死恶龙，kill it!"
+	got := Mask(text, filter.Check(text, BandChild))
+	if want := "他想**恶龙，**** it!"; got != want {
+		t.Fatalf("Mask = %q, want %q", got, want)
+	}
+}
+
+func TestDisabledFilter(t *testing.T) {
+	filter, err := NewFilter(false, BandYoung, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if matches := filter.Check("kill", BandYoung); matches != nil {
+		t.Fatalf("disabled filter matched %v", matches)
+	}
+	if guard := filter.NewGuard(BandYoung); guard != nil {
+		t.Fatal("disabled filter returned a guard")
+	}
+}
+
+func TestGuardSplitWords(t *testing.T) {
+	filter := newTestFilter(t)
+	cases := []struct {
+		name   string
+		chunks []string
+	}{
+		{"killer", []string{"The whale is a kill", "er of the sea."}},
+		{"method", []string{"Try a new meth", "od today."}},
+		{"skill", []string{"She practised the s", "kill every day."}},
+		{"overkill", []string{"That was a lot of over", "kill, honestly."}},
+		{"妈妈的", []string{"奶奶讲了妈妈", "的故事。"}},
+		{"long prefix", []string{strings.Repeat("a", 40) + " overoverover", "kill done."}},
+	}
+	for _, c := range cases {
+		guard := filter.NewGuard(BandChild)
+		want := strings.Join(c.chunks, "")
+		if got := stream(guard, c.chunks...); got != want {
+			t.Errorf("%s: output = %q, want %q", c.name, got, want)
+		}
+		if guard.Halted() != nil || len(guard.Matches()) != 0 {
+			t.Errorf("%s: unexpected matches %v", c.name, guard.Matches())
+		}
+	}
+}
+
+func TestGuardMasksAcrossChunks(t *testing.T) {
+	filter := newTestFilter(t)
+	guard := filter.NewGuard(BandChild)
+	got := stream(guard, "The knight did not want to ki", "ll", " the dragon.")
+	if want := "The knight did not want to **** the dragon."; got != want {
+		t.Fatalf("output = %q, want %q", got, want)
+	}
+	if len(guard.Matches()) != 1 || guard.Matches()[0].RuleID != "violence" {
+		t.Fatalf("matches = %v", guard.Matches())
+	}
+}
+
+func TestGuardMatchAtEnd(t *testing.T) {
+	filter := newTestFilter(t)
+	guard := filter.NewGuard(BandChild)
+	// 命中在最后一个分片的末尾，结束时才能确定
+	if got := stream(guard, "Please do not ", "kill"); got != "Please do not ****" {
+		t.Fatalf("output = %q", got)
+	}
+}
+
+func TestGuardHalts(t *testing.T) {
+	filter := newTestFilter(t)
+	guard := filter.NewGuard(BandChild)
+	got := stream(guard, "小兔子在森林里散步，听说有人在卖毒", "品，它赶紧跑回了家。")
+	if got != "小兔子在森林里散步，听说有人在卖" {
+		t.Fatalf("output = %q", got)
+	}
+	halted := guard.Halted()
+	if halted == nil || halted.RuleID != "drugs" || halted.Action != ActionRewrite {
+		t.Fatalf("halted = %+v", halted)
+	}
+	if out := guard.Write("后面的内容"); out != "" {
+		t.Fatalf("output after halt = %q", out)
+	}
+}
+
+func TestGuardHaltsOnFlush(t *testing.T) {
+	filter := newTestFilter(t)
+	guard := filter.NewGuard(BandYoung)
+	if got := stream(guard, "Story about a ", "zombie"); got != "Story about a " {
+		t.Fatalf("output = %q", got)
+	}
+	if halted := guard.Halted(); halted == nil || halted.RuleID != "horror" {
+		t.Fatalf("halted = %+v", halted)
+	}
+}
@@ -0,0 +1,89 @@
+package safety
+
+// 年龄段
+const (
+	BandYoung = "young" // 3~6岁
+	BandChild = "child" // 7~12岁
+	BandTeen  = "teen"  // 13~17岁
+)
+
+// 处理方式，按严重程度从低到高
+const (
+	ActionMask    = "mask"    // 用星号遮盖命中的内容
+	ActionRewrite = "rewrite" // 停止输出，要求模型以适合儿童的方式重写（输入中命中时按遮盖处理）
+	ActionBlock   = "block"   // 拒绝输入，或停止输出并提示内容已被过滤
+)
+
+// bandAudiences 各年龄段对应的读者描述，用于要求模型重写时的提示词
+var bandAudiences = map[string]string{
+	BandYoung: "3到6岁的幼儿",
+	BandChild: "7到12岁的小学生",
+	BandTeen:  "13到17岁的青少年",
+}
+
+// ValidBand 是否为支持的年龄段
+func ValidBand(band string) bool {
+	_, ok := bandAudiences[band]
+	return ok
+}
+
+// Audience 获取年龄段对应的读者描述
+func Audience(band string) string {
+	return bandAudiences[band]
+}
+
+// Rule 过滤规则：关键词和正则表达式任一命中即视为命中，Actions 为各年龄段的处理方式，未列出的年龄段不检查该规则
+type Rule struct {
+	ID       string            `json:"id"`
+	Category string            `json:"category"`
+	Keywords []string          `json:"keywords,omitempty"`
+	Patterns []string          `json:"patterns,omitempty"`
+	Actions  map[string]string `json:"actions"`
+}
+
+// defaultRules 内置规则，可通过 SAFETY_RULES_FILE 指定的JSON文件替换
+var defaultRules = []Rule{
+	{
+		ID:       "adult",
+		Category: "成人内容",
+		Keywords: []string{"色情", "性交", "做爱", "裸体", "黄片", "porn", "sex", "nude", "naked"},
+		Actions:  map[string]string{BandYoung: ActionBlock, BandChild: ActionBlock, BandTeen: ActionBlock},
+	},
+	{
+		ID:       "self_harm",
+		Category: "自我伤害",
+		Keywords: []string{"自杀", "自残", "割腕", "跳楼", "suicide", "self-harm", "kill myself"},
+		Actions:  map[string]string{BandYoung: ActionBlock, BandChild: ActionBlock, BandTeen: ActionRewrite},
+	},
+	{
+		ID:       "drugs",
+		Category: "毒品",
+		Keywords: []string{"毒品", "吸毒", "冰毒", "海洛因", "大麻", "cocaine", "heroin", "meth"},
+		Actions:  map[string]string{BandYoung: ActionBlock, BandChild: ActionRewrite, BandTeen: ActionRewrite},
+	},
+	{
+		ID:       "gore",
+		Category: "血腥暴力",
+		Keywords: []string{"血腥", "砍头", "肢解", "分尸", "血肉模糊", "开膛", "gore", "behead", "dismember"},
+		Actions:  map[string]string{BandYoung: ActionRewrite, BandChild: ActionRewrite, BandTeen: ActionMask},
+	},
+	{
+		ID:       "violence",
+		Category: "暴力",
+		Keywords: []string{"杀死", "杀人", "谋杀", "枪杀", "捅死", "murder", "kill"},
+		Actions:  map[string]string{BandYoung: ActionRewrite, BandChild: ActionMask},
+	},
+	{
+		ID:       "horror",
+		Category: "恐怖",
+		Keywords: []string{"厉鬼", "恶鬼", "僵尸", "尸体", "zombie", "corpse"},
+		Actions:  map[string]string{BandYoung: ActionRewrite},
+	},
+	{
+		ID:       "profanity",
+		Category: "脏话",
+		Keywords: []string{"他妈的", "傻逼", "操你", "混蛋", "王八蛋", "fuck", "shit", "bitch", "damn"},
+		Patterns: []string{`(?i)f+u+c+k+`},
+		Actions:  map[string]string{BandYoung: ActionMask, BandChild: ActionMask, BandTeen: ActionMask},
+	},
+}
@@ -0,0 +1,104 @@
+package search
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 高亮检索词使用的标签
+const (
+	highlightStart = "<mark>"
+	highlightEnd   = "</mark>"
+)
+
+const snippetContext = 30 // 片段中第一个检索词之前保留的字符数，片段较短时最多保留四分之一
+
+// span 检索词在文本中的位置（按字符计）
+type span struct {
+	start, end int
+}
+
+// findSpans 查找检索词在文本中出现的位置，不区分大小写，重叠的位置合并
+func findSpans(runes []rune, terms []string) []span {
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	var spans []span
+	for _, term := range terms {
+		needle := []rune(term)
+		if len(needle) == 0 {
+			continue
+		}
+		for i := 0; i+len(needle) <= len(lower); i++ {
+			if string(lower[i:i+len(needle)]) == term {
+				spans = append(spans, span{start: i, end: i + len(needle)})
+			}
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var merged []span
+	for _, s := range spans {
+		if n := len(merged); n > 0 && s.start <= merged[n-1].end {
+			if s.end > merged[n-1].end {
+				merged[n-1].end = s.end
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+// render 对文本做HTML转义，并用 <mark> 标记检索词
+func render(runes []rune, spans []span, from, to int) string {
+	var b strings.Builder
+	pos := from
+	for _, s := range spans {
+		if s.end <= from || s.start >= to {
+			continue
+		}
+		start, end := max(s.start, from), min(s.end, to)
+		b.WriteString(html.EscapeString(string(runes[pos:start])))
+		b.WriteString(highlightStart)
+		b.WriteString(html.EscapeString(string(runes[start:end])))
+		b.WriteString(highlightEnd)
+		pos = end
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:to])))
+	return b.String()
+}
+
+// Highlight 对整段文本做HTML转义并高亮检索词，用于标题
+func Highlight(text string, terms []string) string {
+	runes := []rune(text)
+	return render(runes, findSpans(runes, terms), 0, len(runes))
+}
+
+// Snippet 截取第一个检索词附近不超过 width 个字符的片段，做HTML转义并高亮检索词
+// 文本中没有检索词时截取开头，片段前后被截断时加上省略号
+func Snippet(text string, terms []string, width int) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	spans := findSpans(runes, terms)
+
+	from := 0
+	if len(spans) > 0 {
+		from = max(spans[0].start-min(snippetContext, width/4), 0)
+	}
+	to := min(from+width, len(runes))
+	// 片段到达文本末尾时向前补足长度
+	from = max(min(from, to-width), 0)
+
+	snippet := render(runes, spans, from, to)
+	if from > 0 {
+		snippet = "…" + snippet
+	}
+	if to < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
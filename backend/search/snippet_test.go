@@ -0,0 +1,58 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	cases := []struct {
+		text  string
+		terms []string
+		want  string
+	}{
+		{"小龙怕黑", []string{"怕黑"}, "小龙<mark>怕黑</mark>"},
+		{"The Dragon and the dragonfly", []string{"dragon"}, "The <mark>Dragon</mark> and the <mark>dragon</mark>fly"},
+		// 重叠的检索词合并为一个标记
+		{"小龙怕黑", []string{"小龙", "龙怕"}, "<mark>小龙怕</mark>黑"},
+		// 原文中的HTML被转义
+		{"<b>龙</b> & 虎", []string{"龙"}, "&lt;b&gt;<mark>龙</mark>&lt;/b&gt; &amp; 虎"},
+		{"没有命中", []string{"龙"}, "没有命中"},
+		{"空检索词", []string{""}, "空检索词"},
+	}
+	for _, c := range cases {
+		if got := Highlight(c.text, c.terms); got != c.want {
+			t.Errorf("Highlight(%q, %q) = %q, want %q", c.text, c.terms, got, c.want)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	long := strings.Repeat("甲", 50) + "小龙" + strings.Repeat("乙", 50)
+	longer := strings.Repeat("甲", 100) + "小龙" + strings.Repeat("乙", 200)
+
+	cases := []struct {
+		name  string
+		text  string
+		terms []string
+		width int
+		want  string
+	}{
+		{"short text", "小龙怕黑", []string{"小龙"}, 20, "<mark>小龙</mark>怕黑"},
+		{"whitespace collapsed", "小龙\n\n  怕黑", []string{"怕黑"}, 20, "小龙 <mark>怕黑</mark>"},
+		{"no match takes the beginning", long, []string{"老虎"}, 10, strings.Repeat("甲", 10) + "…"},
+		{"context before the match", longer, []string{"小龙"}, 120,
+			"…" + strings.Repeat("甲", snippetContext) + "<mark>小龙</mark>" + strings.Repeat("乙", 88) + "…"},
+		{"whole text fits", long, []string{"小龙"}, 120,
+			strings.Repeat("甲", 50) + "<mark>小龙</mark>" + strings.Repeat("乙", 50)},
+		// 片段较短时检索词之前最多保留四分之一
+		{"short snippet keeps the match", long, []string{"小龙"}, 40,
+			"…" + strings.Repeat("甲", 10) + "<mark>小龙</mark>" + strings.Repeat("乙", 28) + "…"},
+		{"match near the end", "甲乙丙丁戊己庚辛小龙", []string{"小龙"}, 4, "…庚辛<mark>小龙</mark>"},
+	}
+	for _, c := range cases {
+		if got := Snippet(c.text, c.terms, c.width); got != c.want {
+			t.Errorf("%s: Snippet = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// 搜索结果的类型
+const (
+	KindConversation = "conversation" // 对话标题
+	KindDocument     = "document"     // 对话中的文档
+	KindStory        = "story"        // 保存的故事
+)
+
+// Kinds 支持的搜索结果类型
+var Kinds = []string{KindConversation, KindDocument, KindStory}
+
+// ValidKind 是否为支持的搜索结果类型
+func ValidKind(kind string) bool {
+	for _, k := range Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// segment 文本中连续的一段：中日韩文字或字母数字组成的单词
+type segment struct {
+	text string
+	cjk  bool
+}
+
+// segments 将文本切分为中日韩文字段和单词，标点和空白作为分隔
+func segments(text string) []segment {
+	var result []segment
+	var current []rune
+	currentCJK := false
+	flush := func() {
+		if len(current) > 0 {
+			result = append(result, segment{text: string(current), cjk: currentCJK})
+			current = current[:0]
+		}
+	}
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			if !currentCJK {
+				flush()
+			}
+			currentCJK = true
+			current = append(current, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if currentCJK {
+				flush()
+			}
+			currentCJK = false
+			current = append(current, unicode.ToLower(r))
+		default:
+			flush()
+		}
+	}
+	flush()
+	return result
+}
+
+// Tokenize 将文本转换为写入全文索引的词序列，以空格分隔
+// 中文没有空格分词，连续的汉字按相邻两字切分（小龙怕黑 -> 小龙 龙怕 怕黑），
+// 每段末尾再补一个单字，保证单字查询也能命中；其它文字按单词切分并转为小写
+func Tokenize(text string) string {
+	var tokens []string
+	for _, seg := range segments(text) {
+		if !seg.cjk {
+			tokens = append(tokens, seg.text)
+			continue
+		}
+		runes := []rune(seg.text)
+		for i := 0; i+1 < len(runes); i++ {
+			tokens = append(tokens, string(runes[i:i+2]))
+		}
+		tokens = append(tokens, string(runes[len(runes)-1]))
+	}
+	return strings.Join(tokens, " ")
+}
+
+// Terms 从查询中提取的检索词：小写的单词和连续的汉字段，用于高亮和不支持全文索引时的模糊查询
+func Terms(query string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, seg := range segments(query) {
+		if !seen[seg.text] {
+			seen[seg.text] = true
+			terms = append(terms, seg.text)
+		}
+	}
+	return terms
+}
+
+// MatchExpression 将查询转换为 FTS5 的 MATCH 表达式，各检索词之间为 AND 关系
+// 单词按前缀匹配（drag 可以命中 dragon）；两个字以上的汉字段转换为相邻两字组成的短语，单个汉字按前缀匹配
+func MatchExpression(query string) string {
+	var parts []string
+	for _, seg := range segments(query) {
+		runes := []rune(seg.text)
+		if !seg.cjk || len(runes) == 1 {
+			parts = append(parts, quote(seg.text)+"*")
+			continue
+		}
+		bigrams := make([]string, 0, len(runes)-1)
+		for i := 0; i+1 < len(runes); i++ {
+			bigrams = append(bigrams, string(runes[i:i+2]))
+		}
+		parts = append(parts, quote(strings.Join(bigrams, " ")))
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// quote 将检索词转换为 FTS5 的字符串
+func quote(text string) string {
+	return `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+}
+
+// isCJK 是否为中日韩文字
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
@@ -0,0 +1,72 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	cases := []struct {
+		text, want string
+	}{
+		{"小龙怕黑", "小龙 龙怕 怕黑 黑"},
+		{"龙", "龙"},
+		{"The Dragon's cave", "the dragon s cave"},
+		{"小龙Dragon3号", "小龙 龙 dragon3 号"},
+		{"你好，世界！", "你好 好 世界 界"},
+		{"ひらがな", "ひら らが がな な"},
+		{"", ""},
+		{"  ,.!  ", ""},
+	}
+	for _, c := range cases {
+		if got := Tokenize(c.text); got != c.want {
+			t.Errorf("Tokenize(%q) = %q, want %q", c.text, got, c.want)
+		}
+	}
+}
+
+func TestTerms(t *testing.T) {
+	got := Terms("小龙 Dragon 小龙, dragon! 怕黑")
+	want := []string{"小龙", "dragon", "怕黑"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Terms = %q, want %q", got, want)
+	}
+	if terms := Terms("，。!?"); terms != nil {
+		t.Fatalf("Terms of punctuation = %q", terms)
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	cases := []struct {
+		query, want string
+	}{
+		{"drag", `"drag"*`},
+		{"小龙怕黑", `"小龙 龙怕 怕黑"`},
+		{"龙", `"龙"*`},
+		{"小龙 Dragon", `"小龙" AND "dragon"*`},
+		{`say "hi"`, `"say"* AND "hi"*`},
+		{"", ""},
+	}
+	for _, c := range cases {
+		if got := MatchExpression(c.query); got != c.want {
+			t.Errorf("MatchExpression(%q) = %q, want %q", c.query, got, c.want)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	if got := quote(`a"b`); got != `"a""b"` {
+		t.Fatalf("quote = %s", got)
+	}
+}
+
+func TestValidKind(t *testing.T) {
+	for _, kind := range Kinds {
+		if !ValidKind(kind) {
+			t.Errorf("ValidKind(%q) = false", kind)
+		}
+	}
+	if ValidKind("folder") {
+		t.Error("ValidKind(folder) = true")
+	}
+}
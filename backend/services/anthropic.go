@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"grandma/backend/models"
@@ -21,7 +22,7 @@ func NewAnthropicProvider(apiKey, baseURL string) *AnthropicProvider {
 	}
 }
 
-func (p *AnthropicProvider) ChatStream(messages []models.Message, writer io.Writer) error {
+func (p *AnthropicProvider) ChatStream(ctx context.Context, messages []models.Message, writer io.Writer) (*StreamResult, error) {
 	url := fmt.Sprintf("%s/v1/messages", p.BaseURL)
 
 	// 将消息数组转换为API格式
@@ -34,7 +35,7 @@ func (p *AnthropicProvider) ChatStream(messages []models.Message, writer io.Writ
 	}
 
 	payload := map[string]interface{}{
-		"model":      "claude-3-5-sonnet-20241022",
+		"model":      anthropicModel,
 		"max_tokens": 4096,
 		"messages":   apiMessages,
 		"stream":     true,
@@ -42,12 +43,12 @@ func (p *AnthropicProvider) ChatStream(messages []models.Message, writer io.Writ
 
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -57,33 +58,53 @@ func (p *AnthropicProvider) ChatStream(messages []models.Message, writer io.Writ
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("anthropic api error: %s", string(body))
+		return nil, fmt.Errorf("anthropic api error: %s", string(body))
 	}
 
+	result := &StreamResult{}
 	decoder := json.NewDecoder(resp.Body)
 	for {
 		var event struct {
-			Type  string `json:"type"`
+			Type    string `json:"type"`
+			Message struct {
+				Model string `json:"model"`
+				Usage struct {
+					InputTokens int `json:"input_tokens"`
+				} `json:"usage"`
+			} `json:"message,omitempty"`
 			Delta struct {
-				Text string `json:"text"`
+				Text       string `json:"text"`
+				StopReason string `json:"stop_reason"`
 			} `json:"delta,omitempty"`
+			Usage struct {
+				OutputTokens int `json:"output_tokens"`
+			} `json:"usage,omitempty"`
 		}
 
 		if err := decoder.Decode(&event); err != nil {
 			if err == io.EOF {
 				break
 			}
-			return err
+			return result, err
 		}
 
-		if event.Type == "content_block_delta" && event.Delta.Text != "" {
-			_, _ = writer.Write([]byte(event.Delta.Text))
+		switch event.Type {
+		case "message_start":
+			result.Model = event.Message.Model
+			result.Usage.PromptTokens = event.Message.Usage.InputTokens
+		case "content_block_delta":
+			if event.Delta.Text != "" {
+				_, _ = writer.Write([]byte(event.Delta.Text))
+			}
+		case "message_delta":
+			result.FinishReason = event.Delta.StopReason
+			result.Usage.CompletionTokens = event.Usage.OutputTokens
 		}
 
 		if event.Type == "message_stop" {
@@ -91,7 +112,8 @@ func (p *AnthropicProvider) ChatStream(messages []models.Message, writer io.Writ
 		}
 	}
 
-	return nil
+	result.Usage.TotalTokens = result.Usage.PromptTokens + result.Usage.CompletionTokens
+	return result, nil
 }
 
 // Chat 非流式聊天，用于生成标题等场景
@@ -108,7 +130,7 @@ func (p *AnthropicProvider) Chat(messages []models.Message) (string, error) {
 	}
 
 	payload := map[string]interface{}{
-		"model":      "claude-3-5-sonnet-20241022",
+		"model":      anthropicModel,
 		"max_tokens": 4096,
 		"messages":   apiMessages,
 		"stream":     false,
@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"grandma/backend/models"
@@ -21,7 +22,7 @@ func NewOpenAIProvider(apiKey, baseURL string) *OpenAIProvider {
 	}
 }
 
-func (p *OpenAIProvider) ChatStream(messages []models.Message, writer io.Writer) error {
+func (p *OpenAIProvider) ChatStream(ctx context.Context, messages []models.Message, writer io.Writer) (*StreamResult, error) {
 	url := fmt.Sprintf("%s/chat/completions", p.BaseURL)
 
 	// 将消息数组转换为API格式
@@ -34,19 +35,23 @@ func (p *OpenAIProvider) ChatStream(messages []models.Message, writer io.Writer)
 	}
 
 	payload := map[string]interface{}{
-		"model":    "deepseek-chat",
+		"model":    openAIModel,
 		"messages": apiMessages,
 		"stream":   true,
+		// 在最后一个数据块中返回token用量
+		"stream_options": map[string]interface{}{
+			"include_usage": true,
+		},
 	}
 
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -55,15 +60,16 @@ func (p *OpenAIProvider) ChatStream(messages []models.Message, writer io.Writer)
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("openai api error: %s", string(body))
+		return nil, fmt.Errorf("openai api error: %s", string(body))
 	}
 
+	result := &StreamResult{}
 	buffer := make([]byte, 4096)
 	for {
 		n, err := resp.Body.Read(buffer)
@@ -76,17 +82,30 @@ func (p *OpenAIProvider) ChatStream(messages []models.Message, writer io.Writer)
 				}
 
 				var streamResp struct {
+					Model   string `json:"model"`
 					Choices []struct {
 						Delta struct {
 							Content string `json:"content"`
 						} `json:"delta"`
+						FinishReason string `json:"finish_reason"`
 					} `json:"choices"`
+					Usage *models.Usage `json:"usage"`
 				}
 
 				if err := json.Unmarshal([]byte(line), &streamResp); err != nil {
 					continue
 				}
 
+				if streamResp.Model != "" {
+					result.Model = streamResp.Model
+				}
+				if streamResp.Usage != nil {
+					result.Usage = *streamResp.Usage
+				}
+				if len(streamResp.Choices) > 0 && streamResp.Choices[0].FinishReason != "" {
+					result.FinishReason = streamResp.Choices[0].FinishReason
+				}
+
 				if len(streamResp.Choices) > 0 && streamResp.Choices[0].Delta.Content != "" {
 					_, _ = writer.Write([]byte(streamResp.Choices[0].Delta.Content))
 				}
@@ -96,11 +115,11 @@ func (p *OpenAIProvider) ChatStream(messages []models.Message, writer io.Writer)
 			break
 		}
 		if err != nil {
-			return err
+			return result, err
 		}
 	}
 
-	return nil
+	return result, nil
 }
 
 func parseSSE(data string) []string {
@@ -140,7 +159,7 @@ func (p *OpenAIProvider) Chat(messages []models.Message) (string, error) {
 	}
 
 	payload := map[string]interface{}{
-		"model":    "deepseek-chat",
+		"model":    openAIModel,
 		"messages": apiMessages,
 		"stream":   false,
 	}
@@ -1,24 +1,52 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"grandma/backend/models"
 	"io"
+	"strings"
 )
 
+// StreamResult 流式响应结束后的统计信息
+type StreamResult struct {
+	Model        string       // 服务端实际使用的模型名
+	Usage        models.Usage // token用量
+	FinishReason string       // 结束原因，如 stop、length、end_turn
+}
+
 type ChatProvider interface {
-	ChatStream(messages []models.Message, writer io.Writer) error
-	Chat(messages []models.Message) (string, error) // 非流式，用于生成标题等场景
+	ChatStream(ctx context.Context, messages []models.Message, writer io.Writer) (*StreamResult, error) // ctx 取消时中止流式请求
+	Chat(messages []models.Message) (string, error)                                                     // 非流式，用于生成标题等场景
 }
 
-func GetProvider(providerName, openaiKey, openaiURL, anthropicKey, anthropicURL string) (ChatProvider, error) {
-	switch providerName {
+// 各服务商实际使用的模型
+const (
+	openAIModel    = "deepseek-chat"
+	anthropicModel = "claude-3-5-sonnet-20241022"
+)
+
+// ResolveModel 将请求中的模型名称（包括别名）解析为服务商和实际使用的模型，不支持的名称返回空字符串
+func ResolveModel(name string) (provider, model string) {
+	switch name {
 	case "openai", "gpt-3.5-turbo", "gpt-4":
+		return "openai", openAIModel
+	case "anthropic", "claude":
+		return "anthropic", anthropicModel
+	default:
+		return "", ""
+	}
+}
+
+func GetProvider(providerName, openaiKey, openaiURL, anthropicKey, anthropicURL string) (ChatProvider, error) {
+	provider, _ := ResolveModel(providerName)
+	switch provider {
+	case "openai":
 		if openaiKey == "" {
 			return nil, fmt.Errorf("OpenAI API key is not configured")
 		}
 		return NewOpenAIProvider(openaiKey, openaiURL), nil
-	case "anthropic", "claude":
+	case "anthropic":
 		if anthropicKey == "" {
 			return nil, fmt.Errorf("Anthropic API key is not configured")
 		}
@@ -27,3 +55,34 @@ func GetProvider(providerName, openaiKey, openaiURL, anthropicKey, anthropicURL
 		return nil, fmt.Errorf("unsupported provider: %s", providerName)
 	}
 }
+
+// BuildContinuationMessages 构建续写请求的消息
+// Anthropic 使用助手消息预填充，模型会紧接着已有内容继续生成；
+// OpenAI 兼容接口不支持预填充，改为在已有内容之后追加续写提示词 continuationPrompt
+// Anthropic 要求第一条消息为用户消息且用户和助手交替，历史为空（如对话的第一条文档）或不以用户消息结尾时，
+// 在预填充之前补一条续写提示词作为用户消息；已有内容为空时没有可续写的内容，按历史直接生成回复
+func BuildContinuationMessages(providerName string, history []models.Message, partial, continuationPrompt string) []models.Message {
+	messages := append([]models.Message{}, history...)
+	if strings.TrimSpace(partial) == "" {
+		return appendUserTurn(messages, continuationPrompt)
+	}
+	switch providerName {
+	case "anthropic", "claude":
+		messages = appendUserTurn(messages, continuationPrompt)
+		// 预填充内容不能以空白结尾
+		return append(messages, models.Message{Role: "assistant", Content: strings.TrimRight(partial, " \t\r\n")})
+	default:
+		return append(messages,
+			models.Message{Role: "assistant", Content: partial},
+			models.Message{Role: "user", Content: continuationPrompt},
+		)
+	}
+}
+
+// appendUserTurn 消息为空或不以用户消息结尾时追加一条用户消息
+func appendUserTurn(messages []models.Message, content string) []models.Message {
+	if len(messages) > 0 && messages[len(messages)-1].Role == "user" {
+		return messages
+	}
+	return append(messages, models.Message{Role: "user", Content: content})
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"grandma/backend/models"
+	"testing"
+)
+
+const testPrompt = "请继续"
+
+func roles(messages []models.Message) string {
+	var s string
+	for _, msg := range messages {
+		s += msg.Role[:1]
+	}
+	return s
+}
+
+func TestBuildContinuationMessages(t *testing.T) {
+	history := []models.Message{{Role: "user", Content: "讲个故事"}}
+	cases := []struct {
+		name     string
+		provider string
+		history  []models.Message
+		partial  string
+		want     string // 各消息角色的首字母
+	}{
+		{"anthropic prefill", "anthropic", history, "从前有座山 ", "ua"},
+		{"anthropic without history", "claude", nil, "从前有座山", "ua"},
+		{"anthropic after assistant", "anthropic", []models.Message{{Role: "user", Content: "你好"}, {Role: "assistant", Content: "你好！"}}, "从前", "uaua"},
+		{"anthropic empty partial", "anthropic", history, " \n", "u"},
+		{"openai", "openai", history, "从前有座山", "uau"},
+		{"openai without history", "openai", nil, "从前有座山", "au"},
+		{"openai empty partial", "openai", nil, "", "u"},
+	}
+	for _, c := range cases {
+		messages := BuildContinuationMessages(c.provider, c.history, c.partial, testPrompt)
+		if got := roles(messages); got != c.want {
+			t.Errorf("%s: roles = %s, want %s", c.name, got, c.want)
+			continue
+		}
+		last := messages[len(messages)-1]
+		if last.Role == "assistant" && last.Content != "从前有座山" && last.Content != "从前" {
+			t.Errorf("%s: prefill = %q", c.name, last.Content)
+		}
+	}
+	if len(history) != 1 {
+		t.Fatal("history was modified")
+	}
+}
@@ -0,0 +1,31 @@
+package services
+
+import "unicode"
+
+// EstimateTokens 粗略估算文本的token数：中日韩字符按每字1个token计算，其余字符按每4个字符1个token计算
+func EstimateTokens(text string) int {
+	cjk := 0
+	other := 0
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r) {
+			cjk++
+		} else {
+			other++
+		}
+	}
+	return cjk + (other+3)/4
+}
+
+// GetContextBudget 获取模型可用于历史上下文的token预算（已为回复预留空间）
+func GetContextBudget(providerName string) int {
+	switch providerName {
+	case "openai", "gpt-3.5-turbo", "gpt-4":
+		// deepseek-chat 上下文长度为64K
+		return 48000
+	case "anthropic", "claude":
+		// Claude 3.5 Sonnet 上下文长度为200K
+		return 150000
+	default:
+		return 16000
+	}
+}
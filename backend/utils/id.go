@@ -2,6 +2,7 @@ package utils
 
 import (
 	"crypto/rand"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"time"
@@ -21,6 +22,33 @@ func GenerateStoryId() string {
 	return generateID("story")
 }
 
+// GenerateProjectID 生成故事项目ID
+func GenerateProjectID() string {
+	return generateID("proj")
+}
+
+// GenerateJobID 生成后台任务ID
+func GenerateJobID() string {
+	return generateID("job")
+}
+
+// GenerateFolderID 生成文件夹ID
+func GenerateFolderID() string {
+	return generateID("folder")
+}
+
+// GenerateShareID 生成分享链接ID
+func GenerateShareID() string {
+	return generateID("share")
+}
+
+// GenerateShareToken 生成分享链接的令牌，24个随机字节的 URL 安全编码
+func GenerateShareToken() string {
+	randomBytes := make([]byte, 24)
+	rand.Read(randomBytes)
+	return base64.RawURLEncoding.EncodeToString(randomBytes)
+}
+
 // generateID 生成唯一ID
 func generateID(prefix string) string {
 	timestamp := time.Now().UnixNano()